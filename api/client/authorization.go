@@ -85,6 +85,41 @@ func (c *Client) getAuthorization(authorizationURL string,
 	return resp.Body, nil
 }
 
+// getAuthorizationIfExists is like getAuthorization, but returns nil if no
+// authorization is stored for the key.
+func (c *Client) getAuthorizationIfExists(authorizationURL string,
+	authPrivKey [PrivateKeySize]byte) ([]byte, error) {
+	req, err := c.getAuthorizationRequest(authorizationURL, authPrivKey)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(req, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// deleteAuthorization removes the authorization from the server once it
+// has been imported. Authenticates itself against the server with the
+// passed authorization key.
+func (c *Client) deleteAuthorization(authorizationURL string,
+	authPrivKey [PrivateKeySize]byte) error {
+	req, err := http.NewRequest("DELETE", authorizationURL, nil)
+	if err != nil {
+		return err
+	}
+	common.SignWithKey(req, authPrivKey)
+
+	_, err = c.doRequest(req, http.StatusOK)
+	return err
+}
+
 // ImportAuthorization generates a new repository "repoName" and imports the
 // authorization information from the given URL.
 func ImportAuthorization(repoName string, urlString string) (*Client, *repository.ClientRepository, error) {
@@ -121,7 +156,28 @@ func ImportAuthorization(repoName string, urlString string) (*Client, *repositor
 		return nil, nil, fmt.Errorf("key storage failure (%s)", err)
 	}
 
-	privKey, err := repo.GetSigningPrivateKey()
+	if auth.Chunker != nil {
+		err = repo.SetChunkerConfig(auth.Chunker)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chunker configuration storage failure (%s)", err)
+		}
+	}
+
+	err = repo.RegisterDevice()
+	if err != nil {
+		return nil, nil, fmt.Errorf("device registration failure (%s)", err)
+	}
+	err = c.SyncDeviceRegistry(repo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("device registry synchronization failure (%s)", err)
+	}
+
+	err = c.deleteAuthorization(authURL.URL.String(), authURL.SignKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("authorization removal failure (%s)", err)
+	}
+
+	privKey, err := repo.GetDeviceSigningPrivateKey()
 	if err != nil {
 		return nil, nil, fmt.Errorf("private signing key retrieval failure (%s)", err)
 	}
@@ -61,6 +61,40 @@ func (t *AuthorizationClientTest) TestGet(c *C) {
 	c.Assert(len(d) > 0, Equals, true)
 }
 
+func (t *AuthorizationClientTest) TestGetKeepsAuthorization(c *C) {
+	t.getClientRepository(c).SetAuthorization(
+		t.pubKey,
+		t.encryptionKey,
+		t.authorization,
+	)
+
+	_, err := t.doAuthorization(c)
+	c.Assert(err, IsNil)
+	data, err := t.client.getAuthorizationIfExists(t.getAuthorizationURL(c), t.privateKey)
+	c.Assert(err, IsNil)
+	c.Assert(len(data) > 0, Equals, true)
+}
+
+func (t *AuthorizationClientTest) TestGetIfExistsMissing(c *C) {
+	data, err := t.client.getAuthorizationIfExists(t.getAuthorizationURL(c), t.privateKey)
+	c.Assert(err, IsNil)
+	c.Assert(data, IsNil)
+}
+
+func (t *AuthorizationClientTest) TestDelete(c *C) {
+	t.getClientRepository(c).SetAuthorization(
+		t.pubKey,
+		t.encryptionKey,
+		t.authorization,
+	)
+
+	err := t.client.deleteAuthorization(t.getAuthorizationURL(c), t.privateKey)
+	c.Assert(err, IsNil)
+	data, err := t.client.getAuthorizationIfExists(t.getAuthorizationURL(c), t.privateKey)
+	c.Assert(err, IsNil)
+	c.Assert(data, IsNil)
+}
+
 func (t *AuthorizationClientTest) TestConnError(c *C) {
 	t.server.Close()
 	_, err := t.doAuthorization(c)
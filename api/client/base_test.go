@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/hoffie/larasync/api/common"
 	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
@@ -56,6 +57,7 @@ func (t *BaseTest) SetUpTest(c *C) {
 		t.serverURL(c), "",
 		func(string) bool { return true })
 	t.client.SetSigningPrivateKey(t.privateKey)
+	t.client.SetRetries(DefaultRetries, time.Millisecond)
 }
 
 func (t *BaseTest) SetUpSuite(c *C) {
@@ -116,3 +118,20 @@ func (t *BaseTest) getRepository(c *C) *repository.Repository {
 	c.Assert(err, IsNil)
 	return rep
 }
+
+// deviceRepository returns a new client repository which has registered
+// its own device.
+func (t *BaseTest) deviceRepository(c *C) *repository.ClientRepository {
+	r := repository.NewClient(c.MkDir())
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = r.SetKeysFromAuth(&repository.Authorization{
+		SigningKey:    t.privateKey,
+		EncryptionKey: t.encryptionKey,
+		HashingKey:    t.hashingKey,
+	})
+	c.Assert(err, IsNil)
+	err = r.RegisterDevice()
+	c.Assert(err, IsNil)
+	return r
+}
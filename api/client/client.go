@@ -2,6 +2,7 @@ package client
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/hoffie/larasync/api/tls"
 )
@@ -14,6 +15,10 @@ type Client struct {
 	netloc            string
 	adminSecret       []byte
 	signingPrivateKey [PrivateKeySize]byte
+	concurrency       int
+	retries           int
+	retryDelay        time.Duration
+	maxRetryAge       time.Duration
 }
 
 // NetlocToURL returns the URL matching the given netloc
@@ -29,11 +34,29 @@ func New(url, fingerprint string, fingerprintVerifier tls.VerificationFunc) *Cli
 		VerificationFunc:  fingerprintVerifier,
 	}
 	tr := &http.Transport{
-		DialTLS: fpv.DialTLS,
+		DialTLS:             fpv.DialTLS,
+		MaxIdleConnsPerHost: DefaultConcurrency,
 	}
 	return &Client{
-		http:    &http.Client{Transport: tr},
-		BaseURL: url,
+		http:        &http.Client{Transport: tr},
+		BaseURL:     url,
+		concurrency: DefaultConcurrency,
+		retries:     DefaultRetries,
+		retryDelay:  DefaultRetryDelay,
+		maxRetryAge: DefaultMaxRetryAge,
+	}
+}
+
+// SetConcurrency sets the number of objects which are transferred in
+// parallel by Uploaders and Downloaders created afterwards.
+func (c *Client) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	c.concurrency = n
+	tr, ok := c.http.Transport.(*http.Transport)
+	if ok {
+		tr.MaxIdleConnsPerHost = n
 	}
 }
 
@@ -47,9 +70,24 @@ func (c *Client) SetSigningPrivateKey(k [PrivateKeySize]byte) {
 	c.signingPrivateKey = k
 }
 
-// doRequest executes the given request and verifies the resulting status code
+// doRequest executes the given request and verifies the resulting status code.
+// Requests which fail transiently are repeated.
 func (c *Client) doRequest(req *http.Request, expStatus ...int) (*http.Response, error) {
-	resp, err := c.http.Do(req)
+	resp, err := c.doWithRetries(req, true)
+	return checkStatus(resp, err, expStatus)
+}
+
+// doNonIdempotentRequest executes the given request which must not be
+// processed twice by the server and verifies the resulting status code.
+// It is only repeated if the connection could not be established.
+func (c *Client) doNonIdempotentRequest(req *http.Request, expStatus ...int) (*http.Response, error) {
+	resp, err := c.doWithRetries(req, false)
+	return checkStatus(resp, err, expStatus)
+}
+
+// checkStatus verifies that the given response has one of the expected
+// status codes.
+func checkStatus(resp *http.Response, err error, expStatus []int) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hoffie/larasync/api/common"
+	"github.com/hoffie/larasync/repository"
+)
+
+// devicesRequest builds a request for the device registry; it is signed
+// with the given key.
+func (c *Client) devicesRequest(method string, data []byte, key [PrivateKeySize]byte) (*http.Request, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+"/devices", body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	common.SignWithKey(req, key)
+	return req, nil
+}
+
+// getDeviceRegistry returns the signed device registry stored on the
+// server or nil if there is none yet.
+func (c *Client) getDeviceRegistry(key [PrivateKeySize]byte) ([]byte, error) {
+	req, err := c.devicesRequest("GET", nil, key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(req, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// putDeviceRegistry uploads the given signed device registry.
+func (c *Client) putDeviceRegistry(data []byte, key [PrivateKeySize]byte) error {
+	req, err := c.devicesRequest("PUT", data, key)
+	if err != nil {
+		return err
+	}
+	_, err = c.doRequest(req, http.StatusOK)
+	return err
+}
+
+// SyncDeviceRegistry merges the device registry of the server into the
+// local one and uploads the result if the server lacks any locally known
+// device.
+// If the registry cannot be synchronized as the repository keys have been
+// rotated after revoking another device, the new keys are imported.
+func (c *Client) SyncDeviceRegistry(r *repository.ClientRepository) error {
+	registered, err := r.IsDeviceRegistered()
+	if err != nil {
+		return err
+	}
+	if !registered {
+		err = r.RegisterDevice()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.syncDeviceRegistry(r)
+	if err == nil {
+		return nil
+	}
+	imported, importErr := c.importKeyRotation(r)
+	if importErr != nil {
+		return fmt.Errorf("unable to import rotated repository keys (%s)", importErr)
+	}
+	if !imported {
+		return err
+	}
+	return c.syncDeviceRegistry(r)
+}
+
+// syncDeviceRegistry implements SyncDeviceRegistry. The requests are signed
+// with the repository signing key as this device may not be registered
+// with the server yet.
+func (c *Client) syncDeviceRegistry(r *repository.ClientRepository) error {
+	key, err := r.GetSigningPrivateKey()
+	if err != nil {
+		return err
+	}
+	remote, err := c.getDeviceRegistry(key)
+	if err != nil {
+		return err
+	}
+	incomplete, err := r.MergeDeviceRegistryBytes(remote)
+	if err != nil || !incomplete {
+		return err
+	}
+	data, err := r.GetDeviceRegistryBytes()
+	if err != nil {
+		return err
+	}
+	return c.putDeviceRegistry(data, key)
+}
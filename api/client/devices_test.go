@@ -0,0 +1,56 @@
+package client
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+type DevicesClientTest struct {
+	BaseTest
+}
+
+var _ = Suite(&DevicesClientTest{
+	BaseTest: newBaseTest(),
+})
+
+func (t *DevicesClientTest) SetUpTest(c *C) {
+	t.BaseTest.SetUpTest(c)
+	t.createRepository(c)
+}
+
+func (t *DevicesClientTest) TestSyncDeviceRegistry(c *C) {
+	first := t.deviceRepository(c)
+	err := t.client.SyncDeviceRegistry(first)
+	c.Assert(err, IsNil)
+
+	second := t.deviceRepository(c)
+	err = t.client.SyncDeviceRegistry(second)
+	c.Assert(err, IsNil)
+	err = t.client.SyncDeviceRegistry(first)
+	c.Assert(err, IsNil)
+
+	for _, r := range []*repository.ClientRepository{first, second} {
+		registry, err := r.DeviceRegistry()
+		c.Assert(err, IsNil)
+		c.Assert(registry.Devices, HasLen, 2)
+	}
+	serverRegistry, err := t.getRepository(c).DeviceRegistry()
+	c.Assert(err, IsNil)
+	c.Assert(serverRegistry.Devices, HasLen, 2)
+}
+
+func (t *DevicesClientTest) TestDeviceKeyAuthenticates(c *C) {
+	r := t.deviceRepository(c)
+	deviceKey, err := r.GetDeviceSigningPrivateKey()
+	c.Assert(err, IsNil)
+	t.client.SetSigningPrivateKey(deviceKey)
+
+	_, err = t.client.GetNIBs()
+	c.Assert(err, NotNil)
+
+	err = t.client.SyncDeviceRegistry(r)
+	c.Assert(err, IsNil)
+	_, err = t.client.GetNIBs()
+	c.Assert(err, IsNil)
+}
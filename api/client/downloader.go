@@ -2,24 +2,57 @@ package client
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
 
 	"github.com/hoffie/larasync/repository"
 	"github.com/hoffie/larasync/repository/nib"
 )
 
+// maxObjectAttempts is the number of times an object is downloaded while
+// the received data does not match its ID.
+const maxObjectAttempts = 3
+
 // Downloader returns the downloader configured for the client
-// and the passed ClientRepository.
+// and the passed ClientRepository. Objects which are not stored in the
+// repository are fetched through the client from then on.
 func (c *Client) Downloader(r *repository.ClientRepository) *Downloader {
+	r.SetObjectFetcher(c)
 	return &Downloader{
 		client: c,
 		r:      r,
+		pool:   newTransferPool(c.concurrency),
+		window: 2 * c.concurrency,
 	}
 }
 
 // Downloader handles downloads from server to client
 type Downloader struct {
-	client *Client
-	r      *repository.ClientRepository
+	client         *Client
+	r              *repository.ClientRepository
+	receivedNIBIDs []string
+	pool           *transferPool
+	// window is the maximum number of received NIBs whose objects are
+	// fetched before they are stored.
+	window int
+}
+
+// pendingNIB is a received NIB whose missing objects are being fetched.
+type pendingNIB struct {
+	data    []byte
+	n       *nib.NIB
+	fetched chan error
+	// stored is set if the NIB has already been stored by an earlier,
+	// interrupted download.
+	stored bool
+}
+
+// ReceivedNIBIDs returns the ids of the NIBs which have been stored
+// by the last GetAll or GetDelta call, including the ones which had
+// already been stored before.
+func (dl *Downloader) ReceivedNIBIDs() []string {
+	return dl.receivedNIBIDs
 }
 
 // GetAll ensures that the local state matches the remote state.
@@ -56,7 +89,7 @@ func (dl *Downloader) getFromServerTransactionID(transactionID int64) error {
 	if err != nil {
 		return err
 	}
-	return dl.processNIBResponse(nibResponse)
+	return dl.ProcessNIBResponse(nibResponse)
 }
 
 // getNIBs downloads all NIBs and stores them in the repository
@@ -65,12 +98,24 @@ func (dl *Downloader) getNIBs() error {
 	if err != nil {
 		return err
 	}
-	return dl.processNIBResponse(nibResponse)
+	return dl.ProcessNIBResponse(nibResponse)
 }
 
-// processNIBResponse synchronizes the given NIBResponse to the local client state.
-func (dl *Downloader) processNIBResponse(response *NIBGetResponse) error {
-	err := dl.processNIBBytes(response.NIBData)
+// ProcessNIBResponse synchronizes the given NIBResponse to the local client state.
+func (dl *Downloader) ProcessNIBResponse(response *NIBGetResponse) error {
+	// NIBs of newly registered devices can only be verified with an
+	// up-to-date device registry
+	err := dl.client.SyncDeviceRegistry(dl.r)
+	if err != nil {
+		return err
+	}
+	err = dl.processNIBBytes(response.NIBData)
+	if err != nil {
+		return err
+	}
+	// the transaction must not be recorded if the transfer broke off;
+	// the NIBs which have been stored are skipped by the next download.
+	err = response.Err()
 	if err != nil {
 		return err
 	}
@@ -84,48 +129,167 @@ func (dl *Downloader) processNIBResponse(response *NIBGetResponse) error {
 }
 
 // processNibBytes parses a channel and adds the NIBs being represented by each
-// passed byte array.
+// passed byte array. The objects of the next NIBs within the window are
+// fetched in parallel; the NIBs themselves are stored in the order in
+// which they have been received. NIBs which are stored already are
+// skipped, which resumes interrupted downloads.
 func (dl *Downloader) processNIBBytes(nibBytesIterator <-chan []byte) error {
+	dl.receivedNIBIDs = []string{}
+	// the state config is loaded lazily; load it before it is read by
+	// concurrent fetches.
+	_, err := dl.r.StateConfig()
+	if err != nil {
+		return err
+	}
+	pending := []*pendingNIB{}
 	for nibBytes := range nibBytesIterator {
 		// FIXME: overwrite checking!
-		n, err := dl.r.VerifyAndParseNIBBytes(nibBytes)
+		var n *nib.NIB
+		n, err = dl.r.VerifyAndParseNIBBytes(nibBytes)
 		if err != nil {
-			return err
+			break
 		}
-		err = dl.fetchMissingData(n)
+		p := &pendingNIB{
+			data:    nibBytes,
+			n:       n,
+			fetched: make(chan error, 1),
+			stored:  dl.isStored(n, nibBytes),
+		}
+		if p.stored {
+			p.fetched <- nil
+		} else {
+			go func() {
+				p.fetched <- dl.fetchMissingData(p.n)
+			}()
+		}
+		pending = append(pending, p)
+
+		if len(pending) >= dl.window {
+			err = dl.addPendingNIB(pending[0])
+			pending = pending[1:]
+			if err != nil {
+				break
+			}
+		}
+	}
+	for _, p := range pending {
 		if err != nil {
-			return err
+			// wait for running transfers before returning
+			<-p.fetched
+			continue
 		}
+		err = dl.addPendingNIB(p)
+	}
+	return err
+}
 
-		err = dl.r.AddNIBContent(bytes.NewReader(nibBytes))
+// addPendingNIB waits until the objects of the given NIB have been
+// fetched and stores it.
+func (dl *Downloader) addPendingNIB(p *pendingNIB) error {
+	err := <-p.fetched
+	if err != nil {
+		return err
+	}
+	if !p.stored {
+		err = dl.r.AddNIBContent(bytes.NewReader(p.data))
 		if err != nil {
 			return err
 		}
 	}
+	dl.receivedNIBIDs = append(dl.receivedNIBIDs, p.n.ID)
 	return nil
 }
 
-// fetchMissingData loads missing objects in the passed NIB.
+// isStored returns whether the given NIB is stored locally with exactly
+// the given content and whether all of its required objects are
+// available.
+func (dl *Downloader) isStored(n *nib.NIB, data []byte) bool {
+	if !dl.r.HasNIB(n.ID) {
+		return false
+	}
+	reader, err := dl.r.GetNIBReader(n.ID)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+	stored, err := ioutil.ReadAll(reader)
+	if err != nil || !bytes.Equal(stored, data) {
+		return false
+	}
+	objectIDs, err := dl.r.RequiredObjectIDs(n)
+	if err != nil {
+		return false
+	}
+	for _, objectID := range objectIDs {
+		if !dl.r.HasObject(objectID) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchMissingData loads the missing objects of the passed NIB which are
+// required by the repository. The metadata is loaded first as it
+// determines whether the item is selected for checkout.
 func (dl *Downloader) fetchMissingData(n *nib.NIB) error {
-	objectIDs := n.AllObjectIDs()
+	metadataIDs := []string{}
+	for _, rev := range n.Revisions {
+		metadataIDs = append(metadataIDs, rev.MetadataID)
+	}
+	err := dl.fetchMissingObjects(metadataIDs)
+	if err != nil {
+		return err
+	}
+	objectIDs, err := dl.r.RequiredObjectIDs(n)
+	if err != nil {
+		return err
+	}
+	return dl.fetchMissingObjects(objectIDs)
+}
+
+// fetchMissingObjects downloads the passed objects in parallel unless
+// they are already available locally.
+func (dl *Downloader) fetchMissingObjects(objectIDs []string) error {
+	missing := []string{}
 	for _, objectID := range objectIDs {
+		if !dl.r.HasObject(objectID) {
+			missing = append(missing, objectID)
+		}
+	}
+	return dl.pool.doAll(missing, func(objectID string) error {
+		// another transfer may have fetched the object in the meantime
 		if dl.r.HasObject(objectID) {
-			continue
+			return nil
 		}
-		err := dl.getObject(objectID)
-		if err != nil {
+		return dl.getObject(objectID)
+	})
+}
+
+// getObject downloads the named object. As the server is not trusted,
+// the object is only stored if it decrypts and hashes to its ID; the
+// download is repeated otherwise.
+func (dl *Downloader) getObject(objectID string) error {
+	for attempt := 1; ; attempt++ {
+		err := dl.downloadObject(objectID)
+		if err != repository.ErrObjectVerification {
 			return err
 		}
+		if attempt >= maxObjectAttempts {
+			return fmt.Errorf("unable to download object %s (%s)", objectID, err)
+		}
+		Log.Warn("downloaded object is invalid, retrying", "objectID", objectID,
+			"error", err)
 	}
-	return nil
 }
 
-// getObject downloads the named object
-func (dl *Downloader) getObject(objectID string) error {
+// downloadObject downloads the named object and stores it if it verifies.
+func (dl *Downloader) downloadObject(objectID string) error {
 	resp, err := dl.client.GetObject(objectID)
 	if err != nil {
 		return err
 	}
-	err = dl.r.AddObject(objectID, resp)
-	return err
+	if closer, ok := resp.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return dl.r.AddVerifiedObject(objectID, resp)
 }
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+type DownloaderTest struct {
+	BaseTest
+}
+
+var _ = Suite(&DownloaderTest{
+	BaseTest: newBaseTest(),
+})
+
+func (t *DownloaderTest) SetUpTest(c *C) {
+	t.BaseTest.SetUpTest(c)
+	t.createRepository(c)
+}
+
+// corruptingTransport replaces the content of the given number of object
+// downloads; a negative number corrupts all of them.
+type corruptingTransport struct {
+	http.RoundTripper
+	mutex     sync.Mutex
+	remaining int
+}
+
+func (t *corruptingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil || req.Method != "GET" || !strings.Contains(req.URL.Path, "/blobs/") {
+		return resp, err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.remaining == 0 {
+		return resp, err
+	}
+	t.remaining--
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewBufferString("corrupt"))
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+// pushFile pushes a file from another device and returns a client for
+// a new device which pulls through the given transport.
+func (t *DownloaderTest) pushFile(c *C, transport *corruptingTransport) (*repository.ClientRepository, *Client) {
+	theirs := t.deviceRepository(c)
+	path := filepath.Join(theirs.Path, "foo.txt")
+	err := ioutil.WriteFile(path, []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	err = theirs.AddItem(path)
+	c.Assert(err, IsNil)
+	err = t.deviceClient(c, theirs).Uploader(theirs).PushAll()
+	c.Assert(err, IsNil)
+
+	mine := t.deviceRepository(c)
+	client := t.deviceClient(c, mine)
+	err = client.SyncDeviceRegistry(mine)
+	c.Assert(err, IsNil)
+	transport.RoundTripper = client.http.Transport
+	client.http.Transport = transport
+	return mine, client
+}
+
+func (t *DownloaderTest) TestRetryBadObject(c *C) {
+	transport := &corruptingTransport{remaining: 1}
+	mine, client := t.pushFile(c, transport)
+
+	dl := client.Downloader(mine)
+	err := dl.GetAll()
+	c.Assert(err, IsNil)
+	c.Assert(transport.remaining, Equals, 0)
+	c.Assert(dl.ReceivedNIBIDs(), HasLen, 1)
+
+	result, err := mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 0)
+}
+
+func (t *DownloaderTest) TestRejectBadObject(c *C) {
+	transport := &corruptingTransport{remaining: -1}
+	mine, client := t.pushFile(c, transport)
+
+	err := client.Downloader(mine).GetAll()
+	c.Assert(err, NotNil)
+	objects, err := ioutil.ReadDir(filepath.Join(mine.GetManagementDir(), "objects"))
+	c.Assert(err, IsNil)
+	c.Assert(objects, HasLen, 0)
+	nibs, err := mine.GetAllNibs()
+	c.Assert(err, IsNil)
+	for range nibs {
+		c.Error("NIB with bad objects has been stored")
+	}
+}
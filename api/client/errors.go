@@ -12,4 +12,8 @@ var (
 	// ErrUnexpectedStatus is returned whenever the request did not yield
 	// the expected HTTP status code.
 	ErrUnexpectedStatus = errors.New("unexpected http status")
+
+	// ErrTransactionMismatch is returned if the server rejected a request
+	// because its current transaction is not the expected one.
+	ErrTransactionMismatch = errors.New("server transaction changed")
 )
@@ -1,11 +1,13 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/hoffie/larasync/api"
 	"github.com/hoffie/larasync/api/common"
@@ -26,12 +28,16 @@ func (c *Client) putNIBRequest(nibID string, nibReader io.Reader) (*http.Request
 }
 
 // handleNIBPreconditionError tries to get additional data from the precondition failed
-// error and returns the extracted error information.
+// error and returns the extracted error information. A response without
+// body means that the If-Match header did not match.
 func handleNIBPreconditionError(resp *http.Response) error {
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	if len(data) == 0 {
+		return ErrTransactionMismatch
+	}
 	jsonError := &api.ContentIDsJSONError{}
 	err = json.Unmarshal(data, jsonError)
 	if err != nil {
@@ -60,10 +66,61 @@ func (c *Client) PutNIB(nibID string, nibReader io.Reader) error {
 	return err
 }
 
+// commitNIBsRequest builds a request for adding the given signed NIBs in
+// a single server transaction
+func (c *Client) commitNIBsRequest(nibData [][]byte, ifMatch string) (*http.Request, error) {
+	body := &bytes.Buffer{}
+	encoder := bincontainer.NewEncoder(body)
+	for _, data := range nibData {
+		err := encoder.WriteChunk(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest("POST", c.BaseURL+"/nibs", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	common.SignWithKey(req, c.signingPrivateKey)
+	return req, nil
+}
+
+// CommitNIBs uploads the given signed NIBs which the server adds in a
+// single transaction; either all of them are stored or none. If ifMatch
+// is set, the server only accepts them if its current transaction has
+// the given id. Returns the id of the server transaction afterwards.
+func (c *Client) CommitNIBs(nibData [][]byte, ifMatch string) (int64, error) {
+	req, err := c.commitNIBsRequest(nibData, ifMatch)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.doNonIdempotentRequest(req, http.StatusOK, http.StatusPreconditionFailed)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return 0, handleNIBPreconditionError(resp)
+	}
+	return parseTransactionID(resp), nil
+}
+
 // NIBGetResponse encapsulates the response given from a NIB GET request.
 type NIBGetResponse struct {
 	NIBData             <-chan []byte
 	ServerTransactionID int64
+	err                 error
+}
+
+// Err returns the error which interrupted the transfer of the NIBs. It
+// may only be called after NIBData has been closed.
+func (r *NIBGetResponse) Err() error {
+	return r.err
 }
 
 // getNIBsRequest builds a request for getting a NIB list
@@ -89,6 +146,23 @@ func (c *Client) getNIBsFromTransactionRequest(lastTransactionID int64) (*http.R
 	return req, nil
 }
 
+// waitForNIBsRequest builds a request which asks the server to wait up to
+// the given duration for transactions newer than the passed Transaction ID.
+func (c *Client) waitForNIBsRequest(lastTransactionID int64, wait time.Duration) (*http.Request, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/nibs", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := req.URL.Query()
+	query.Add("from-transaction-id", strconv.FormatInt(lastTransactionID, 10))
+	query.Add("wait", strconv.FormatInt(int64(wait/time.Second), 10))
+	req.URL.RawQuery = query.Encode()
+
+	common.SignWithKey(req, c.signingPrivateKey)
+	return req, nil
+}
+
 // GetNIBsFromTransactionID returns the list of all nibs from the passed server transaction.
 func (c *Client) GetNIBsFromTransactionID(lastTransactionID int64) (*NIBGetResponse, error) {
 	req, err := c.getNIBsFromTransactionRequest(lastTransactionID)
@@ -98,6 +172,18 @@ func (c *Client) GetNIBsFromTransactionID(lastTransactionID int64) (*NIBGetRespo
 	return c.processNibGetRequest(req)
 }
 
+// WaitForNIBsFromTransactionID returns the list of all nibs from the passed
+// server transaction. If there are none yet, the server delays its answer
+// until new data arrives or until the wait duration passes; the
+// returned list is empty in the latter case.
+func (c *Client) WaitForNIBsFromTransactionID(lastTransactionID int64, wait time.Duration) (*NIBGetResponse, error) {
+	req, err := c.waitForNIBsRequest(lastTransactionID, wait)
+	if err != nil {
+		return nil, err
+	}
+	return c.processNibGetRequest(req)
+}
+
 // GetNIBs returns the list of all nib byte representations.
 func (c *Client) GetNIBs() (*NIBGetResponse, error) {
 	req, err := c.getNIBsRequest()
@@ -116,24 +202,25 @@ func (c *Client) processNibGetRequest(req *http.Request) (*NIBGetResponse, error
 	}
 	bin := bincontainer.NewDecoder(resp.Body)
 	res := make(chan []byte, 100)
+	nibResponse := &NIBGetResponse{
+		NIBData:             res,
+		ServerTransactionID: parseTransactionID(resp),
+	}
 	go func() {
+		defer close(res)
+		defer resp.Body.Close()
 		for {
 			chunk, err := bin.ReadChunk()
 			if err == io.EOF {
-				close(res)
 				return
 			}
 			if err != nil {
-				break
+				nibResponse.err = err
+				return
 			}
 			res <- chunk
 		}
-		close(res)
 	}()
-	nibResponse := &NIBGetResponse{
-		NIBData:             res,
-		ServerTransactionID: parseTransactionID(resp),
-	}
 	return nibResponse, nil
 }
 
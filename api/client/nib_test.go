@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"path/filepath"
+	"time"
 
 	"github.com/hoffie/larasync/helpers"
 	"github.com/hoffie/larasync/helpers/crypto"
@@ -97,6 +98,37 @@ func (t *NIBClientTest) TestGetFromTransactionIDResponse(c *C) {
 	c.Assert(response.ServerTransactionID, Equals, transaction.ID)
 }
 
+func (t *NIBClientTest) TestWaitForNIBsExisting(c *C) {
+	t.AddTestData(c)
+	response, err := t.client.WaitForNIBsFromTransactionID(0, time.Minute)
+	c.Assert(err, IsNil)
+	i := 0
+	for _ = range response.NIBData {
+		i++
+	}
+	c.Assert(i, Equals, 1)
+}
+
+func (t *NIBClientTest) TestWaitForNIBsNotified(c *C) {
+	t.AddTestData(c)
+	repository := t.getClientRepository(c)
+	transaction, err := repository.CurrentTransaction()
+	c.Assert(err, IsNil)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		t.AddDataWith(c, []byte("Hello world"))
+	}()
+	response, err := t.client.WaitForNIBsFromTransactionID(transaction.ID, time.Minute)
+	c.Assert(err, IsNil)
+	i := 0
+	for _ = range response.NIBData {
+		i++
+	}
+	c.Assert(i, Equals, 1)
+	c.Assert(response.ServerTransactionID > transaction.ID, Equals, true)
+}
+
 func (t *NIBClientTest) TestConnError(c *C) {
 	t.server.Close()
 	_, err := t.client.GetNIBs()
@@ -164,3 +196,30 @@ func (t *NIBClientTest) TestAddConnError(c *C) {
 	err := t.client.PutNIB(ID, bytes.NewReader(data))
 	c.Assert(err, NotNil)
 }
+
+func (t *NIBClientTest) TestCommit(c *C) {
+	_, data := t.prepareForNIBAddition(c)
+	repository := t.getRepository(c)
+	transaction, err := repository.CurrentTransaction()
+	c.Assert(err, IsNil)
+
+	transactionID, err := t.client.CommitNIBs([][]byte{data}, transaction.IDString())
+	c.Assert(err, IsNil)
+	current, err := repository.CurrentTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(transactionID, Equals, current.ID)
+	c.Assert(current.ID > transaction.ID, Equals, true)
+}
+
+func (t *NIBClientTest) TestCommitPreconditionFailed(c *C) {
+	_, data := t.prepareForNIBAddition(c)
+	repository := t.getRepository(c)
+	transaction, err := repository.CurrentTransaction()
+	c.Assert(err, IsNil)
+
+	_, err = t.client.CommitNIBs([][]byte{data}, transaction.PreviousIDString())
+	c.Assert(err, NotNil)
+	current, err := repository.CurrentTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(current.ID, Equals, transaction.ID)
+}
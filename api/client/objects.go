@@ -1,10 +1,14 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 
+	"github.com/hoffie/larasync/api"
 	"github.com/hoffie/larasync/api/common"
+	"github.com/hoffie/larasync/helpers/bincontainer"
 )
 
 // putObjectRequest builds a request for uploading an object
@@ -51,3 +55,74 @@ func (c *Client) GetObject(objectID string) (io.Reader, error) {
 	}
 	return resp.Body, err
 }
+
+// missingObjectsRequest builds a request for finding out which of the
+// given objects the server lacks
+func (c *Client) missingObjectsRequest(objectIDs []string) (*http.Request, error) {
+	body, err := json.Marshal(api.JSONObjectIDs{ObjectIDs: objectIDs})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", c.BaseURL+"/blobs/missing",
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	common.SignWithKey(req, c.signingPrivateKey)
+	return req, nil
+}
+
+// MissingObjects returns the objects of the given list which are not
+// stored on the server
+func (c *Client) MissingObjects(objectIDs []string) ([]string, error) {
+	req, err := c.missingObjectsRequest(objectIDs)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result := &api.JSONMissingObjects{}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return nil, err
+	}
+	return result.MissingContentIDs, nil
+}
+
+// putObjectsRequest builds a request for uploading several objects
+func (c *Client) putObjectsRequest(objects map[string][]byte) (*http.Request, error) {
+	body := &bytes.Buffer{}
+	encoder := bincontainer.NewEncoder(body)
+	for objectID, data := range objects {
+		err := encoder.WriteChunk([]byte(objectID))
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.WriteChunk(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest("POST", c.BaseURL+"/blobs", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	common.SignWithKey(req, c.signingPrivateKey)
+	return req, nil
+}
+
+// PutObjects uploads several objects to the server in a single request;
+// objects maps the object ids to their content
+func (c *Client) PutObjects(objects map[string][]byte) error {
+	req, err := c.putObjectsRequest(objects)
+	if err != nil {
+		return err
+	}
+	_, err = c.doRequest(req, http.StatusOK)
+	return err
+}
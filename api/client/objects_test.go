@@ -23,12 +23,18 @@ func (t *ObjectsClientTest) SetUpTest(c *C) {
 	t.BaseTest.SetUpTest(c)
 	t.data = []byte("This is a testfile.")
 
-	h := sha512.New()
-	h.Write(t.data)
-	t.objectKey = hex.EncodeToString(h.Sum(nil))
+	t.objectKey = hashHex(t.data)
 	t.createRepository(c)
 }
 
+// hashHex returns the hex encoded hash of the given data, which is a
+// valid object ID.
+func hashHex(data []byte) string {
+	h := sha512.New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func (t *ObjectsClientTest) TestNotExisting(c *C) {
 	_, err := t.client.GetObject(t.objectKey)
 	c.Assert(err, NotNil)
@@ -71,3 +77,33 @@ func (t *ObjectsClientTest) TestCreateConnError(c *C) {
 	err := t.client.PutObject(t.objectKey, bytes.NewReader(t.data))
 	c.Assert(err, NotNil)
 }
+
+func (t *ObjectsClientTest) TestMissingObjects(c *C) {
+	repository := t.getRepository(c)
+	err := repository.AddObject(t.objectKey, bytes.NewReader(t.data))
+	c.Assert(err, IsNil)
+
+	other := hashHex([]byte("other"))
+	missing, err := t.client.MissingObjects([]string{t.objectKey, other})
+	c.Assert(err, IsNil)
+	c.Assert(missing, DeepEquals, []string{other})
+}
+
+func (t *ObjectsClientTest) TestPutObjects(c *C) {
+	objects := map[string][]byte{
+		hashHex([]byte("first")):  []byte("first content"),
+		hashHex([]byte("second")): []byte("second content"),
+	}
+	err := t.client.PutObjects(objects)
+	c.Assert(err, IsNil)
+
+	repository := t.getRepository(c)
+	for objectID, expected := range objects {
+		reader, err := repository.GetObjectData(objectID)
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		c.Assert(err, IsNil)
+		c.Assert(data, DeepEquals, expected)
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultRetries is the default number of times a request is
+	// repeated after a transient failure.
+	DefaultRetries = 4
+	// DefaultRetryDelay is the time to wait before the first repetition;
+	// it is doubled for each further one.
+	DefaultRetryDelay = 250 * time.Millisecond
+	// DefaultMaxRetryAge is the age of a request's signature after which
+	// it is no longer repeated. Repeated requests keep their signature, so
+	// this has to stay below the maximum request age accepted by the
+	// server (10 seconds by default).
+	DefaultMaxRetryAge = 5 * time.Second
+)
+
+// SetRetries sets how often a request is repeated after a transient
+// failure and how long to wait before the first repetition.
+func (c *Client) SetRetries(retries int, delay time.Duration) {
+	if retries < 0 {
+		retries = 0
+	}
+	c.retries = retries
+	c.retryDelay = delay
+}
+
+// requestBody reads the body of the given request so that it can be sent
+// more than once.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
+
+// isTransientError returns whether the given error of an HTTP round trip
+// may disappear when repeating the request, such as timeouts or dropped
+// connections.
+func isTransientError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// isConnectError returns whether the given error of an HTTP round trip
+// occurred while connecting, i.e. before the request has been sent.
+func isConnectError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// requestAge returns how long ago the given request has been signed
+// according to its Date header. As the header has a resolution of
+// seconds, the age is rather overestimated.
+func requestAge(req *http.Request, start time.Time) time.Duration {
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return time.Since(start)
+	}
+	return time.Since(date)
+}
+
+// isTransientStatus returns whether the given response status indicates
+// a server side failure which may disappear when repeating the request.
+func isTransientStatus(statusCode int) bool {
+	return statusCode >= 500
+}
+
+// doWithRetries sends the given request and repeats it with exponential
+// backoff as long as it fails transiently and its signature is young
+// enough to be accepted by the server.
+// Requests which are not idempotent are only repeated if the connection
+// could not be established, as the server may have processed them even
+// if the response has been lost.
+func (c *Client) doWithRetries(req *http.Request, idempotent bool) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := c.http.Do(req)
+		if attempt >= c.retries || requestAge(req, start)+delay > c.maxRetryAge {
+			return resp, err
+		}
+		if err == nil && (!idempotent || !isTransientStatus(resp.StatusCode)) {
+			return resp, nil
+		}
+		if err != nil && !isTransientError(err) {
+			return nil, err
+		}
+		if err != nil && !idempotent && !isConnectError(err) {
+			return nil, err
+		}
+		if err == nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			Log.Warn("request failed, retrying", "url", req.URL.String(),
+				"status", resp.StatusCode, "delay", delay)
+		} else {
+			Log.Warn("request failed, retrying", "url", req.URL.String(),
+				"error", err, "delay", delay)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
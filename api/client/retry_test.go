@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hoffie/larasync/api/tls"
+
+	. "gopkg.in/check.v1"
+)
+
+type RetryTest struct {
+	server   *httptest.Server
+	client   *Client
+	mutex    sync.Mutex
+	requests int
+	bodies   [][]byte
+	// failures is the number of requests which are answered with
+	// failureStatus before the request succeeds.
+	failures      int
+	failureStatus int
+	// dropConnection makes failing requests close the connection instead
+	// of answering with failureStatus.
+	dropConnection bool
+}
+
+var _ = Suite(&RetryTest{})
+
+func (t *RetryTest) SetUpTest(c *C) {
+	t.requests = 0
+	t.bodies = nil
+	t.failures = 0
+	t.failureStatus = http.StatusServiceUnavailable
+	t.dropConnection = false
+	t.server = httptest.NewServer(http.HandlerFunc(t.handle))
+	t.client = New(t.server.URL, "", nil)
+	t.client.SetRetries(3, time.Millisecond)
+}
+
+func (t *RetryTest) TearDownTest(c *C) {
+	t.server.Close()
+}
+
+func (t *RetryTest) handle(rw http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.requests++
+	t.bodies = append(t.bodies, body)
+	if t.requests <= t.failures {
+		if t.dropConnection {
+			conn, _, err := rw.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		rw.WriteHeader(t.failureStatus)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (t *RetryTest) put(body []byte) error {
+	return t.client.PutObject("object", bytes.NewReader(body))
+}
+
+// requestCount returns the number of requests the server has received.
+func (t *RetryTest) requestCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.requests
+}
+
+func (t *RetryTest) commit() error {
+	_, err := t.client.CommitNIBs([][]byte{[]byte("nib")}, "")
+	return err
+}
+
+func (t *RetryTest) TestNoFailure(c *C) {
+	err := t.put([]byte("data"))
+	c.Assert(err, IsNil)
+	c.Assert(t.requests, Equals, 1)
+}
+
+func (t *RetryTest) TestRetryServerError(c *C) {
+	t.failures = 2
+	err := t.put([]byte("data"))
+	c.Assert(err, IsNil)
+	c.Assert(t.requests, Equals, 3)
+	for _, body := range t.bodies {
+		c.Assert(string(body), Equals, "data")
+	}
+}
+
+func (t *RetryTest) TestRetriesExhausted(c *C) {
+	t.failures = 10
+	err := t.put([]byte("data"))
+	c.Assert(err, Equals, ErrUnexpectedStatus)
+	c.Assert(t.requests, Equals, 4)
+}
+
+func (t *RetryTest) TestNoRetryOnClientError(c *C) {
+	t.failures = 1
+	t.failureStatus = http.StatusUnauthorized
+	err := t.put([]byte("data"))
+	c.Assert(err, Equals, ErrUnexpectedStatus)
+	c.Assert(t.requests, Equals, 1)
+}
+
+func (t *RetryTest) TestNoRetries(c *C) {
+	t.client.SetRetries(0, time.Millisecond)
+	t.failures = 1
+	err := t.put([]byte("data"))
+	c.Assert(err, Equals, ErrUnexpectedStatus)
+	c.Assert(t.requests, Equals, 1)
+}
+
+func (t *RetryTest) TestRetryDroppedConnection(c *C) {
+	t.failures = 2
+	t.dropConnection = true
+	err := t.put([]byte("data"))
+	c.Assert(err, IsNil)
+	c.Assert(t.requests, Equals, 3)
+}
+
+func (t *RetryTest) TestNoRetryAfterSignatureExpiry(c *C) {
+	t.client.maxRetryAge = time.Millisecond
+	t.failures = 1
+	err := t.put([]byte("data"))
+	c.Assert(err, Equals, ErrUnexpectedStatus)
+	c.Assert(t.requests, Equals, 1)
+}
+
+func (t *RetryTest) TestRequestAge(c *C) {
+	req, err := http.NewRequest("GET", t.server.URL, nil)
+	c.Assert(err, IsNil)
+	date := time.Now().Add(-20 * time.Second)
+	req.Header.Set("Date", date.UTC().Format(http.TimeFormat))
+	c.Assert(requestAge(req, time.Now()) >= 20*time.Second, Equals, true)
+}
+
+func (t *RetryTest) TestCommitNoRetryOnServerError(c *C) {
+	t.failures = 1
+	err := t.commit()
+	c.Assert(err, Equals, ErrUnexpectedStatus)
+	c.Assert(t.requests, Equals, 1)
+}
+
+func (t *RetryTest) TestCommitNoRetryOnDroppedConnection(c *C) {
+	t.failures = 1
+	t.dropConnection = true
+	err := t.commit()
+	c.Assert(err, NotNil)
+	c.Assert(t.requestCount(), Equals, 1)
+}
+
+func (t *RetryTest) TestCommitSucceeds(c *C) {
+	err := t.commit()
+	c.Assert(err, IsNil)
+	c.Assert(t.requests, Equals, 1)
+}
+
+func (t *RetryTest) TestConnError(c *C) {
+	t.server.Close()
+	err := t.put([]byte("data"))
+	c.Assert(err, NotNil)
+	c.Assert(isTransientError(err), Equals, true)
+	c.Assert(isConnectError(err), Equals, true)
+}
+
+func (t *RetryTest) TestFingerprintRejectedIsPermanent(c *C) {
+	err := &url.Error{Op: "Get", URL: t.server.URL, Err: tls.ErrFingerprintRejected}
+	c.Assert(isTransientError(err), Equals, false)
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/api/common"
+	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
+	"github.com/hoffie/larasync/repository"
+)
+
+// putKeysRequest builds a request which replaces the repository signing
+// public key and the device registry on the server.
+func (c *Client) putKeysRequest(pubKey [PublicKeySize]byte, registry []byte) (*http.Request, error) {
+	body, err := json.Marshal(api.JSONKeyRotation{
+		PubKey:         pubKey[:],
+		DeviceRegistry: registry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("PUT", c.BaseURL+"/keys", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	common.SignWithKey(req, c.signingPrivateKey)
+	return req, nil
+}
+
+// putKeys replaces the repository signing public key and the device
+// registry on the server after the repository keys have been rotated.
+func (c *Client) putKeys(pubKey [PublicKeySize]byte, registry []byte) error {
+	req, err := c.putKeysRequest(pubKey, registry)
+	if err != nil {
+		return err
+	}
+	_, err = c.doRequest(req, http.StatusOK)
+	return err
+}
+
+// RevokeDevice removes the device with the given ID from the repository.
+// The local repository is synchronized with the server first. Afterwards
+// the repository keys are rotated, the server is switched to the new keys,
+// the re-wrapped objects and re-signed NIBs are uploaded and the new keys
+// are handed to all remaining devices.
+// It returns the devices which could not be handed the new keys; they
+// have to be authorized again.
+func (c *Client) RevokeDevice(r *repository.ClientRepository, deviceID string) ([]*repository.Device, error) {
+	err := c.Downloader(r).GetDelta()
+	if err != nil {
+		return nil, fmt.Errorf("pull failed (%s)", err)
+	}
+	err = c.Uploader(r).PushDelta()
+	if err != nil {
+		return nil, fmt.Errorf("push failed (%s)", err)
+	}
+
+	objectIDs, err := r.RevokeDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := r.GetSigningPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := r.GetDeviceRegistryBytes()
+	if err != nil {
+		return nil, err
+	}
+	err = c.putKeys(pubKey, registry)
+	if err != nil {
+		return nil, fmt.Errorf("unable to replace the server keys (%s)", err)
+	}
+
+	ul := c.Uploader(r)
+	for _, objectID := range objectIDs {
+		err = ul.uploadObject(objectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = ul.PushAll()
+	if err != nil {
+		return nil, fmt.Errorf("push failed (%s)", err)
+	}
+
+	authorizations, skipped, err := r.DeviceAuthorizations()
+	if err != nil {
+		return nil, err
+	}
+	for _, authorization := range authorizations {
+		err = c.PutAuthorization(&authorization.Device.PublicKey,
+			bytes.NewReader(authorization.Data))
+		if err != nil {
+			return nil, fmt.Errorf("unable to hand the new keys to device %s (%s)",
+				authorization.Device.ID(), err)
+		}
+	}
+	return skipped, nil
+}
+
+// importKeyRotation fetches the repository keys which have been handed to
+// this device after another device has been revoked and imports them.
+// The keys are removed from the server once they have been imported.
+// It returns false if no keys are waiting for this device.
+func (c *Client) importKeyRotation(r *repository.ClientRepository) (bool, error) {
+	deviceKey, err := r.GetDeviceSigningPrivateKey()
+	if err != nil {
+		return false, err
+	}
+	pubKey := edhelpers.GetPublicKeyFromPrivate(deviceKey)
+	authorizationURL := c.BaseURL + "/authorizations/" + hex.EncodeToString(pubKey[:])
+	data, err := c.getAuthorizationIfExists(authorizationURL, deviceKey)
+	if err != nil {
+		return false, err
+	}
+	if data == nil {
+		return false, nil
+	}
+	auth, err := r.OpenDeviceAuthorization(data)
+	if err != nil {
+		return false, err
+	}
+	registry, err := c.getDeviceRegistry(auth.SigningKey)
+	if err != nil {
+		return false, err
+	}
+	err = r.ImportKeyRotation(auth, registry)
+	if err != nil {
+		return false, err
+	}
+	err = c.deleteAuthorization(authorizationURL, deviceKey)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
+	"github.com/hoffie/larasync/repository"
+)
+
+type RevocationClientTest struct {
+	BaseTest
+}
+
+var _ = Suite(&RevocationClientTest{
+	BaseTest: newBaseTest(),
+})
+
+func (t *RevocationClientTest) SetUpTest(c *C) {
+	t.BaseTest.SetUpTest(c)
+	t.createRepository(c)
+}
+
+// deviceClient returns a client which signs its requests with the device
+// key of the given repository.
+func (t *BaseTest) deviceClient(c *C, r *repository.ClientRepository) *Client {
+	key, err := r.GetDeviceSigningPrivateKey()
+	c.Assert(err, IsNil)
+	client := New(t.serverURL(c), "", func(string) bool { return true })
+	client.SetSigningPrivateKey(key)
+	return client
+}
+
+func (t *RevocationClientTest) deviceID(c *C, r *repository.ClientRepository) string {
+	id, err := r.DeviceID()
+	c.Assert(err, IsNil)
+	return id
+}
+
+func (t *RevocationClientTest) TestRevokeDevice(c *C) {
+	mine := t.deviceRepository(c)
+	theirs := t.deviceRepository(c)
+	lost := t.deviceRepository(c)
+	for _, r := range []*repository.ClientRepository{mine, theirs, lost, mine} {
+		err := t.client.SyncDeviceRegistry(r)
+		c.Assert(err, IsNil)
+	}
+
+	path := filepath.Join(mine.Path, "foo.txt")
+	err := ioutil.WriteFile(path, []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	err = mine.AddItem(path)
+	c.Assert(err, IsNil)
+	mineClient := t.deviceClient(c, mine)
+	err = mineClient.Uploader(mine).PushAll()
+	c.Assert(err, IsNil)
+
+	skipped, err := mineClient.RevokeDevice(mine, t.deviceID(c, lost))
+	c.Assert(err, IsNil)
+	c.Assert(skipped, HasLen, 0)
+
+	lostClient := t.deviceClient(c, lost)
+	_, err = lostClient.GetNIBs()
+	c.Assert(err, NotNil)
+	err = lostClient.SyncDeviceRegistry(lost)
+	c.Assert(err, NotNil)
+
+	theirsClient := t.deviceClient(c, theirs)
+	err = theirsClient.Downloader(theirs).GetAll()
+	c.Assert(err, IsNil)
+	err = theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadFile(filepath.Join(theirs.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "foo")
+
+	registry, err := theirs.DeviceRegistry()
+	c.Assert(err, IsNil)
+	c.Assert(registry.Get(t.deviceID(c, lost)), IsNil)
+	c.Assert(registry.Devices, HasLen, 2)
+
+	// the imported keys have been removed from the server
+	imported, err := theirsClient.importKeyRotation(theirs)
+	c.Assert(err, IsNil)
+	c.Assert(imported, Equals, false)
+}
+
+func (t *RevocationClientTest) TestImportKeyRotationFailureKeepsKeys(c *C) {
+	theirs := t.deviceRepository(c)
+	err := t.client.SyncDeviceRegistry(theirs)
+	c.Assert(err, IsNil)
+	key, err := theirs.GetDeviceSigningPrivateKey()
+	c.Assert(err, IsNil)
+	pubKey := edhelpers.GetPublicKeyFromPrivate(key)
+	err = t.client.PutAuthorization(&pubKey, bytes.NewBufferString("undecryptable"))
+	c.Assert(err, IsNil)
+
+	theirsClient := t.deviceClient(c, theirs)
+	_, err = theirsClient.importKeyRotation(theirs)
+	c.Assert(err, NotNil)
+	data, err := theirsClient.getAuthorizationIfExists(
+		theirsClient.BaseURL+"/authorizations/"+hex.EncodeToString(pubKey[:]), key)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "undecryptable")
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"sync"
+)
+
+// DefaultConcurrency is the default number of objects which are
+// transferred in parallel.
+const DefaultConcurrency = 4
+
+// transfer is an object transfer which is in progress.
+type transfer struct {
+	done chan struct{}
+	err  error
+}
+
+// transferPool limits the number of concurrent object transfers and
+// makes sure that an object is only transferred once at a time.
+type transferPool struct {
+	slots    chan struct{}
+	mutex    sync.Mutex
+	inFlight map[string]*transfer
+}
+
+// newTransferPool returns a pool which runs at most the given number of
+// transfers at once.
+func newTransferPool(concurrency int) *transferPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &transferPool{
+		slots:    make(chan struct{}, concurrency),
+		inFlight: map[string]*transfer{},
+	}
+}
+
+// do runs f, which transfers the object with the given id, as soon as a
+// slot is free and returns its error. Calls for an object which is
+// already being transferred wait for that transfer instead.
+func (p *transferPool) do(id string, f func() error) error {
+	p.mutex.Lock()
+	t, running := p.inFlight[id]
+	if !running {
+		t = &transfer{done: make(chan struct{})}
+		p.inFlight[id] = t
+	}
+	p.mutex.Unlock()
+	if running {
+		<-t.done
+		return t.err
+	}
+
+	p.slots <- struct{}{}
+	t.err = f()
+	<-p.slots
+
+	p.mutex.Lock()
+	delete(p.inFlight, id)
+	p.mutex.Unlock()
+	close(t.done)
+	return t.err
+}
+
+// doAll runs f for all given object ids in parallel within the limits of
+// the pool. It returns the first error; no further transfers are started
+// after an error.
+func (p *transferPool) doAll(ids []string, f func(id string) error) error {
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return firstErr != nil
+	}
+
+	workers := cap(p.slots)
+	if workers > len(ids) {
+		workers = len(ids)
+	}
+	queue := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				id := id
+				err := p.do(id, func() error { return f(id) })
+				if err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for _, id := range ids {
+		if failed() {
+			break
+		}
+		queue <- id
+	}
+	close(queue)
+	wg.Wait()
+	return firstErr
+}
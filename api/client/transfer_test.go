@@ -0,0 +1,105 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&TransferPoolTest{})
+
+type TransferPoolTest struct{}
+
+func (t *TransferPoolTest) ids(n int) []string {
+	ids := []string{}
+	for i := 0; i < n; i++ {
+		ids = append(ids, fmt.Sprintf("id%d", i))
+	}
+	return ids
+}
+
+func (t *TransferPoolTest) TestAll(c *C) {
+	pool := newTransferPool(3)
+	mutex := sync.Mutex{}
+	done := map[string]bool{}
+	err := pool.doAll(t.ids(10), func(id string) error {
+		mutex.Lock()
+		done[id] = true
+		mutex.Unlock()
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(done, HasLen, 10)
+}
+
+func (t *TransferPoolTest) TestConcurrencyLimit(c *C) {
+	pool := newTransferPool(3)
+	mutex := sync.Mutex{}
+	running := 0
+	maxRunning := 0
+	err := pool.doAll(t.ids(20), func(id string) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(maxRunning <= 3, Equals, true)
+	c.Assert(maxRunning > 1, Equals, true)
+}
+
+func (t *TransferPoolTest) TestError(c *C) {
+	pool := newTransferPool(1)
+	expected := errors.New("failed")
+	calls := 0
+	err := pool.doAll(t.ids(10), func(id string) error {
+		calls++
+		return expected
+	})
+	c.Assert(err, Equals, expected)
+	c.Assert(calls < 10, Equals, true)
+}
+
+func (t *TransferPoolTest) TestSameIDTransferredOnce(c *C) {
+	pool := newTransferPool(4)
+	mutex := sync.Mutex{}
+	calls := 0
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.do("id", func() error {
+				mutex.Lock()
+				calls++
+				mutex.Unlock()
+				<-release
+				return nil
+			})
+			c.Check(err, IsNil)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	c.Assert(calls, Equals, 1)
+}
+
+func (t *TransferPoolTest) TestEmpty(c *C) {
+	pool := newTransferPool(2)
+	err := pool.doAll([]string{}, func(id string) error {
+		return errors.New("unexpected call")
+	})
+	c.Assert(err, IsNil)
+}
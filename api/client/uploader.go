@@ -2,33 +2,103 @@ package client
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
 
 	"github.com/hoffie/larasync/repository"
 	"github.com/hoffie/larasync/repository/nib"
 )
 
+const (
+	// maxObjectQuery is the maximum number of objects which are checked
+	// for existence on the server in a single request.
+	maxObjectQuery = 1000
+	// maxBatchedObjectSize is the maximum size of objects which are
+	// uploaded together with others in a single request.
+	maxBatchedObjectSize = 64 * 1024
+	// maxBatchSize is the size from which on a batch of objects is sent.
+	maxBatchSize = 1024 * 1024
+	// maxPushAttempts is the number of times a push is tried while the
+	// server state keeps changing.
+	maxPushAttempts = 5
+)
+
 // Uploader returns the uploader for the given client in the passed
 // repository.
 func (c *Client) Uploader(r *repository.ClientRepository) *Uploader {
 	return &Uploader{
 		client: c,
 		r:      r,
+		pool:   newTransferPool(c.concurrency),
 	}
 }
 
 // Uploader handles uploads from server to client
 type Uploader struct {
-	client *Client
-	r      *repository.ClientRepository
+	client         *Client
+	r              *repository.ClientRepository
+	pool           *transferPool
+	receivedNIBIDs []string
+}
+
+// ReceivedNIBIDs returns the ids of the NIBs which have been pulled by the
+// last PushAll or PushDelta call because the server state had changed.
+func (ul *Uploader) ReceivedNIBIDs() []string {
+	return ul.receivedNIBIDs
 }
 
 // PushAll ensures that the remote state is synced with the local state.
+// Objects which the server has already are not uploaded again, so an
+// interrupted PushAll resumes where it stopped when it is repeated.
+// If the server state has changed since the last pull, the changes are
+// pulled and the push is repeated.
 func (ul *Uploader) PushAll() error {
+	return ul.pushWithPull(ul.pushAll)
+}
+
+// PushDelta pushes all nibs from the stored local transaction id.
+// If the server state has changed since the last pull, the changes are
+// pulled and the push is repeated.
+func (ul *Uploader) PushDelta() error {
+	return ul.pushWithPull(ul.pushDelta)
+}
+
+// pushWithPull runs the given push. Whenever it is rejected because the
+// server has changed, the server changes are pulled first, which resolves
+// conflicts with the local state, and the push is repeated.
+func (ul *Uploader) pushWithPull(push func() error) error {
+	ul.receivedNIBIDs = []string{}
+	for attempt := 1; ; attempt++ {
+		err := push()
+		if err != ErrTransactionMismatch {
+			return err
+		}
+		if attempt >= maxPushAttempts {
+			return fmt.Errorf("server changed during %d attempts to push", attempt)
+		}
+		Log.Info("Server changed, pulling before pushing again.")
+		dl := ul.client.Downloader(ul.r)
+		err = dl.GetDelta()
+		if err != nil {
+			return fmt.Errorf("pulling server changes failed (%s)", err)
+		}
+		ul.receivedNIBIDs = append(ul.receivedNIBIDs, dl.ReceivedNIBIDs()...)
+	}
+}
+
+// pushAll uploads all NIBs in a single commit.
+func (ul *Uploader) pushAll() error {
 	r := ul.r
 	transaction, err := r.CurrentTransaction()
 	if err != nil {
 		return err
 	}
+	// the server has to know this device to accept its NIB signatures
+	err = ul.client.SyncDeviceRegistry(r)
+	if err != nil {
+		return err
+	}
 	err = ul.uploadNIBs()
 	if err != nil {
 		return err
@@ -36,8 +106,8 @@ func (ul *Uploader) PushAll() error {
 	return ul.saveLastUploadedTransaction(transaction)
 }
 
-// PushDelta pushes all nibs from the stored local transaction id.
-func (ul *Uploader) PushDelta() error {
+// pushDelta uploads the transactions which have not been uploaded yet.
+func (ul *Uploader) pushDelta() error {
 	r := ul.r
 	s, err := r.StateConfig()
 	if err != nil {
@@ -48,7 +118,7 @@ func (ul *Uploader) PushDelta() error {
 	if defaultServer.LocalTransactionID != 0 {
 		err = ul.pushFromTransactionID(defaultServer.LocalTransactionID)
 	} else {
-		err = ul.PushAll()
+		err = ul.pushAll()
 	}
 	return err
 }
@@ -61,18 +131,21 @@ func (ul *Uploader) pushFromTransactionID(transactionID int64) error {
 	if err != nil {
 		return fmt.Errorf("unable to get transactions (%s)", err)
 	}
+	if len(transactions) > 0 {
+		err = ul.client.SyncDeviceRegistry(r)
+		if err != nil {
+			return err
+		}
+	}
 
-	var lastTransaction *repository.Transaction
+	// progress is recorded after each transaction so that an interrupted
+	// push resumes with the first transaction which has not been uploaded
 	for _, transaction := range transactions {
 		err = ul.uploadTransaction(transaction)
 		if err != nil {
 			return err
 		}
-		lastTransaction = transaction
-	}
-
-	if lastTransaction != nil {
-		err = ul.saveLastUploadedTransaction(lastTransaction)
+		err = ul.saveLastUploadedTransaction(transaction)
 		if err != nil {
 			return err
 		}
@@ -87,7 +160,7 @@ func (ul *Uploader) saveLastUploadedTransaction(transaction *repository.Transact
 	r := ul.r
 	s, err := r.StateConfig()
 	if err != nil {
-		return nil
+		return err
 	}
 	s.DefaultServer.LocalTransactionID = transaction.ID
 	err = s.Save()
@@ -100,76 +173,199 @@ func (ul *Uploader) saveLastUploadedTransaction(transaction *repository.Transact
 // uploadTransaction uploads all nibs in the added transaction.
 func (ul *Uploader) uploadTransaction(transaction *repository.Transaction) error {
 	r := ul.r
+	nibs := []*nib.NIB{}
 	for _, nibID := range transaction.NIBIDs {
 		nib, err := r.GetNIB(nibID)
 		if err != nil {
 			return fmt.Errorf("could not load NIB with id %s (%s)", nibID, err)
 		}
-		err = ul.uploadNIB(nib)
-		if err != nil {
-			return err
-		}
+		nibs = append(nibs, nib)
 	}
-	return nil
+	return ul.uploadNIBList(nibs)
 }
 
 // uploadNIBs uploads all local NIBs and content of the NIBs to
 // the server.
 func (ul *Uploader) uploadNIBs() error {
 	r := ul.r
-	nibs, err := r.GetAllNibs()
+	nibChannel, err := r.GetAllNibs()
 	if err != nil {
 		return fmt.Errorf("unable to get NIB list (%s)", err)
 	}
 
-	for n := range nibs {
-		err = ul.uploadNIB(n)
+	nibs := []*nib.NIB{}
+	for n := range nibChannel {
+		nibs = append(nibs, n)
+	}
+	return ul.uploadNIBList(nibs)
+}
+
+// uploadNIBList uploads the objects of the given NIBs which the server
+// lacks and commits the NIBs themselves afterwards, so that the server
+// stores either all of them or none.
+func (ul *Uploader) uploadNIBList(nibs []*nib.NIB) error {
+	if len(nibs) == 0 {
+		return nil
+	}
+	objectIDs := []string{}
+	seen := map[string]bool{}
+	for _, n := range nibs {
+		for _, objectID := range n.AllObjectIDs() {
+			if !seen[objectID] {
+				seen[objectID] = true
+				objectIDs = append(objectIDs, objectID)
+			}
+		}
+	}
+	err := ul.uploadMissingObjects(objectIDs)
+	if err != nil {
+		return err
+	}
+
+	// a NIB which has been changed several times is listed repeatedly,
+	// but may only be committed once
+	nibData := [][]byte{}
+	committed := map[string]bool{}
+	for _, n := range nibs {
+		if committed[n.ID] {
+			continue
+		}
+		committed[n.ID] = true
+		data, err := ul.readNIB(n.ID)
 		if err != nil {
 			return err
 		}
+		nibData = append(nibData, data)
 	}
+	return ul.commitNIBs(nibData)
+}
 
-	return nil
+// readNIB returns the signed representation of the given NIB.
+func (ul *Uploader) readNIB(nibID string) ([]byte, error) {
+	reader, err := ul.r.GetNIBReader(nibID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load NIB with id %s (%s)", nibID, err)
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
 }
 
-// uploadNIB uploads a single passed NIB to the remote server.
-func (ul *Uploader) uploadNIB(n *nib.NIB) error {
-	r := ul.r
-	client := ul.client
-	Log.Debug(fmt.Sprintf("Uploading nib with ID %s", n.ID))
-	nibReader, err := r.GetNIBReader(n.ID)
+// commitNIBs commits the given signed NIBs. They are only accepted if the
+// server has not changed since the last pull; ErrTransactionMismatch is
+// returned otherwise. If the server lacks objects which they reference,
+// these are uploaded and the commit is repeated once.
+func (ul *Uploader) commitNIBs(nibData [][]byte) error {
+	s, err := ul.r.StateConfig()
 	if err != nil {
 		return err
 	}
-	defer nibReader.Close()
-
-	err = client.PutNIB(n.ID, nibReader)
-	var objectIDs []string
-	if err == nil {
+	ifMatch := strconv.FormatInt(s.DefaultServer.RemoteTransactionID, 10)
+	Log.Debug(fmt.Sprintf("Committing %d NIBs on server transaction %s", len(nibData), ifMatch))
+	transactionID, err := ul.client.CommitNIBs(nibData, ifMatch)
+	if repository.IsNIBContentMissing(err) {
+		nibContentMissing := err.(*repository.ErrNIBContentMissing)
+		err = ul.uploadObjects(nibContentMissing.MissingContentIDs())
+		if err != nil {
+			return err
+		}
+		transactionID, err = ul.client.CommitNIBs(nibData, ifMatch)
+	}
+	if err == ErrTransactionMismatch {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("committing %d NIBs failed (%s)", len(nibData), err)
+	}
+	// the server state is the pulled one plus the commit, so there is
+	// nothing left to pull
+	if transactionID == 0 {
 		return nil
 	}
-	if !repository.IsNIBContentMissing(err) {
-		return fmt.Errorf("uploading nib %s failed (%s)", n.ID, err)
+	s.DefaultServer.RemoteTransactionID = transactionID
+	return s.Save()
+}
+
+// uploadMissingObjects asks the server which of the given objects it
+// lacks and uploads those.
+func (ul *Uploader) uploadMissingObjects(objectIDs []string) error {
+	for start := 0; start < len(objectIDs); start += maxObjectQuery {
+		end := start + maxObjectQuery
+		if end > len(objectIDs) {
+			end = len(objectIDs)
+		}
+		missing, err := ul.client.MissingObjects(objectIDs[start:end])
+		if err != nil {
+			return fmt.Errorf("checking for missing objects failed (%s)", err)
+		}
+		err = ul.uploadObjects(missing)
+		if err != nil {
+			return err
+		}
 	}
-	nibContentMissing := err.(*repository.ErrNIBContentMissing)
-	objectIDs = nibContentMissing.MissingContentIDs()
+	return nil
+}
+
+// uploadObjects uploads the given objects. Small objects are sent
+// together in batches; larger ones are uploaded in parallel.
+func (ul *Uploader) uploadObjects(objectIDs []string) error {
+	large := []string{}
+	batch := map[string][]byte{}
+	batchSize := 0
 	for _, objectID := range objectIDs {
-		err = ul.uploadObject(objectID)
+		data, small, err := ul.readSmallObject(objectID)
 		if err != nil {
 			return err
 		}
+		if !small {
+			large = append(large, objectID)
+			continue
+		}
+		batch[objectID] = data
+		batchSize += len(data)
+		if batchSize >= maxBatchSize {
+			err = ul.uploadBatch(batch)
+			if err != nil {
+				return err
+			}
+			batch = map[string][]byte{}
+			batchSize = 0
+		}
 	}
-	nibReader, err = r.GetNIBReader(n.ID)
-	if err != nil {
-		return err
+	if len(batch) > 0 {
+		err := ul.uploadBatch(batch)
+		if err != nil {
+			return err
+		}
 	}
-	defer nibReader.Close()
+	return ul.pool.doAll(large, ul.uploadObject)
+}
 
-	err = client.PutNIB(n.ID, nibReader)
+// readSmallObject returns the content of the given object and whether it
+// is small enough to be uploaded in a batch; the content of larger
+// objects is not returned.
+func (ul *Uploader) readSmallObject(objectID string) ([]byte, bool, error) {
+	object, err := ul.r.GetObjectData(objectID)
 	if err != nil {
-		return fmt.Errorf("uploading nib %s failed (%s)", n.ID, err)
+		return nil, false, fmt.Errorf("unable to load object %s (%s)", objectID, err)
 	}
+	defer object.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(object, maxBatchedObjectSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to load object %s (%s)", objectID, err)
+	}
+	if len(data) > maxBatchedObjectSize {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
 
+// uploadBatch uploads the given objects in a single request.
+func (ul *Uploader) uploadBatch(objects map[string][]byte) error {
+	Log.Debug(fmt.Sprintf("Uploading %d objects in a batch", len(objects)))
+	err := ul.client.PutObjects(objects)
+	if err != nil {
+		return fmt.Errorf("uploading %d objects failed (%s)", len(objects), err)
+	}
 	return nil
 }
 
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sync"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+type UploaderTest struct {
+	BaseTest
+}
+
+var _ = Suite(&UploaderTest{
+	BaseTest: newBaseTest(),
+})
+
+func (t *UploaderTest) SetUpTest(c *C) {
+	t.BaseTest.SetUpTest(c)
+	t.createRepository(c)
+}
+
+// recordingTransport records the method and path of all requests.
+type recordingTransport struct {
+	http.RoundTripper
+	mutex    sync.Mutex
+	requests []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mutex.Lock()
+	t.requests = append(t.requests, req.Method+" "+req.URL.Path)
+	t.mutex.Unlock()
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// count returns the number of recorded requests with the given method
+// whose path matches the given pattern.
+func (t *recordingTransport) count(c *C, method, pattern string) int {
+	count := 0
+	for _, request := range t.requests {
+		matched, err := path.Match(method+" "+pattern, request)
+		c.Assert(err, IsNil)
+		if matched {
+			count++
+		}
+	}
+	return count
+}
+
+func (t *UploaderTest) TestPushBatchesSmallObjects(c *C) {
+	r := t.deviceRepository(c)
+	client := t.deviceClient(c, r)
+	err := client.SyncDeviceRegistry(r)
+	c.Assert(err, IsNil)
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(r.Path, fmt.Sprintf("file%d.txt", i))
+		err := ioutil.WriteFile(path, []byte(fmt.Sprintf("content %d", i)), 0600)
+		c.Assert(err, IsNil)
+	}
+	err = r.AddItem(r.Path)
+	c.Assert(err, IsNil)
+
+	transport := &recordingTransport{RoundTripper: client.http.Transport}
+	client.http.Transport = transport
+	err = client.Uploader(r).PushAll()
+	c.Assert(err, IsNil)
+
+	c.Assert(transport.count(c, "POST", "/repositories/test/blobs/missing"), Equals, 1)
+	c.Assert(transport.count(c, "POST", "/repositories/test/blobs"), Equals, 1)
+	c.Assert(transport.count(c, "PUT", "/repositories/test/blobs/*"), Equals, 0)
+	server := t.getRepository(c)
+	nibs, err := r.GetAllNibs()
+	c.Assert(err, IsNil)
+	for n := range nibs {
+		c.Assert(server.HasNIB(n.ID), Equals, true)
+		for _, objectID := range n.AllObjectIDs() {
+			c.Assert(server.HasObject(objectID), Equals, true)
+		}
+	}
+}
+
+func (t *UploaderTest) TestPushLargeObject(c *C) {
+	r := t.deviceRepository(c)
+	client := t.deviceClient(c, r)
+	err := client.SyncDeviceRegistry(r)
+	c.Assert(err, IsNil)
+	path := filepath.Join(r.Path, "large.bin")
+	data := make([]byte, 2*maxBatchedObjectSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	err = ioutil.WriteFile(path, data, 0600)
+	c.Assert(err, IsNil)
+	err = r.AddItem(path)
+	c.Assert(err, IsNil)
+
+	err = client.Uploader(r).PushAll()
+	c.Assert(err, IsNil)
+
+	other := t.deviceRepository(c)
+	otherClient := t.deviceClient(c, other)
+	err = otherClient.SyncDeviceRegistry(other)
+	c.Assert(err, IsNil)
+	err = otherClient.Downloader(other).GetAll()
+	c.Assert(err, IsNil)
+	err = other.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	got, err := ioutil.ReadFile(filepath.Join(other.Path, "large.bin"))
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, data)
+}
+
+// writeAndAdd writes the given content to the named file of the passed
+// repository and adds it.
+func (t *UploaderTest) writeAndAdd(c *C, r *repository.ClientRepository, name string, content string) {
+	path := filepath.Join(r.Path, name)
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	c.Assert(err, IsNil)
+	err = r.AddItem(path)
+	c.Assert(err, IsNil)
+}
+
+func (t *UploaderTest) TestPushSendsTransaction(c *C) {
+	r := t.deviceRepository(c)
+	client := t.deviceClient(c, r)
+	t.writeAndAdd(c, r, "a.txt", "a")
+	err := client.Uploader(r).PushAll()
+	c.Assert(err, IsNil)
+
+	transaction, err := t.getRepository(c).CurrentTransaction()
+	c.Assert(err, IsNil)
+	sc, err := r.StateConfig()
+	c.Assert(err, IsNil)
+	c.Assert(sc.DefaultServer.RemoteTransactionID, Equals, transaction.ID)
+
+	transport := &recordingTransport{RoundTripper: client.http.Transport}
+	client.http.Transport = transport
+	t.writeAndAdd(c, r, "b.txt", "b")
+	err = client.Uploader(r).PushDelta()
+	c.Assert(err, IsNil)
+	// the server did not change in between, so nothing is pulled
+	c.Assert(transport.count(c, "GET", "/repositories/test/nibs"), Equals, 0)
+	c.Assert(transport.count(c, "POST", "/repositories/test/nibs"), Equals, 1)
+}
+
+func (t *UploaderTest) TestPushPullsServerChanges(c *C) {
+	mine := t.deviceRepository(c)
+	myClient := t.deviceClient(c, mine)
+	theirs := t.deviceRepository(c)
+	theirClient := t.deviceClient(c, theirs)
+	t.writeAndAdd(c, mine, "mine.txt", "mine")
+	t.writeAndAdd(c, theirs, "theirs.txt", "theirs")
+
+	err := theirClient.Uploader(theirs).PushDelta()
+	c.Assert(err, IsNil)
+	ul := myClient.Uploader(mine)
+	err = ul.PushDelta()
+	c.Assert(err, IsNil)
+
+	theirNIBID := t.nibID(c, theirs, "theirs.txt")
+	c.Assert(ul.ReceivedNIBIDs(), DeepEquals, []string{theirNIBID})
+	c.Assert(mine.HasNIB(theirNIBID), Equals, true)
+	server := t.getRepository(c)
+	c.Assert(server.HasNIB(t.nibID(c, mine, "mine.txt")), Equals, true)
+	c.Assert(server.HasNIB(theirNIBID), Equals, true)
+}
+
+func (t *UploaderTest) TestPushMergesConflict(c *C) {
+	mine := t.deviceRepository(c)
+	myClient := t.deviceClient(c, mine)
+	theirs := t.deviceRepository(c)
+	theirClient := t.deviceClient(c, theirs)
+	t.writeAndAdd(c, mine, "foo.txt", "mine")
+	t.writeAndAdd(c, theirs, "foo.txt", "theirs")
+
+	err := theirClient.Uploader(theirs).PushDelta()
+	c.Assert(err, IsNil)
+	err = myClient.Uploader(mine).PushDelta()
+	c.Assert(err, IsNil)
+
+	nibID := t.nibID(c, mine, "foo.txt")
+	server, err := t.getRepository(c).GetNIB(nibID)
+	c.Assert(err, IsNil)
+	local, err := mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	c.Assert(server.Revisions, HasLen, len(local.Revisions))
+}
+
+// nibID returns the id of the NIB of the named file in the given
+// repository.
+func (t *UploaderTest) nibID(c *C, r *repository.ClientRepository, name string) string {
+	tracker, err := r.NIBTracker()
+	c.Assert(err, IsNil)
+	found, err := tracker.Get(name)
+	c.Assert(err, IsNil)
+	return found.NIBID
+}
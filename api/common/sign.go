@@ -52,7 +52,14 @@ func SignWithKey(req *http.Request, key [PrivateKeySize]byte) {
 // matches the given public key. It also checks whether the request
 // is not outdated according to the provided maxAge.
 func ValidateRequest(req *http.Request, pubkey [PublicKeySize]byte, maxAge time.Duration) bool {
-	if !validateRequestSig(req, pubkey) {
+	return ValidateRequestWithKeys(req, [][PublicKeySize]byte{pubkey}, maxAge)
+}
+
+// ValidateRequestWithKeys checks whether the request signature is valid and
+// matches any of the given public keys. It also checks whether the request
+// is not outdated according to the provided maxAge.
+func ValidateRequestWithKeys(req *http.Request, pubkeys [][PublicKeySize]byte, maxAge time.Duration) bool {
+	if !validateRequestSig(req, pubkeys) {
 		return false
 	}
 	if !youngerThan(req, maxAge) {
@@ -62,8 +69,8 @@ func ValidateRequest(req *http.Request, pubkey [PublicKeySize]byte, maxAge time.
 }
 
 // validateRequestSig is a helper which ensures that the request's signature
-// is valid. It extracts the signature on its own.
-func validateRequestSig(req *http.Request, pubkey [PublicKeySize]byte) bool {
+// is valid for one of the given keys. It extracts the signature on its own.
+func validateRequestSig(req *http.Request, pubkeys [][PublicKeySize]byte) bool {
 	auth := req.Header.Get("Authorization")
 	if auth == "" {
 		return false
@@ -84,7 +91,7 @@ func validateRequestSig(req *http.Request, pubkey [PublicKeySize]byte) bool {
 	}
 	sigArr := new([SignatureSize]byte)
 	copy(sigArr[:], sigBytes[:SignatureSize])
-	return verifySig(req, pubkey, *sigArr)
+	return verifySig(req, pubkeys, *sigArr)
 }
 
 // youngerThan checks whether the request's Date header is at maximum
@@ -128,11 +135,16 @@ func getRequestHash(req *http.Request) []byte {
 	return hash
 }
 
-// verifySig checks if the signature matches the provided
-// public key and is valid for the given request.
-func verifySig(req *http.Request, pubkey [PublicKeySize]byte, sig [SignatureSize]byte) bool {
+// verifySig checks if the signature matches any of the provided
+// public keys and is valid for the given request.
+func verifySig(req *http.Request, pubkeys [][PublicKeySize]byte, sig [SignatureSize]byte) bool {
 	hash := getRequestHash(req)
-	return ed25519.Verify(&pubkey, hash, &sig)
+	for _, pubkey := range pubkeys {
+		if ed25519.Verify(&pubkey, hash, &sig) {
+			return true
+		}
+	}
+	return false
 }
 
 // PassphraseToKey converts the user-supplied passphrase to a key, usable for
@@ -20,6 +20,6 @@ func (t *SignTests) TestWindowsRequest(c *C) {
 	if err != nil {
 		panic(err)
 	}
-	sigCheck := validateRequestSig(t.req, adminPubkey)
+	sigCheck := validateRequestSig(t.req, [][PublicKeySize]byte{adminPubkey})
 	c.Assert(sigCheck, Equals, true)
 }
@@ -37,6 +37,15 @@ func (t *SignTests) TestAdminSigningCorrectSignature(c *C) {
 	c.Assert(t.adminSigned(), Equals, true)
 }
 
+func (t *SignTests) TestValidateRequestWithKeys(c *C) {
+	otherPubkey, err := GetAdminSecretPubkey([]byte("other"))
+	c.Assert(err, IsNil)
+	c.Assert(ValidateRequestWithKeys(t.req,
+		[][PublicKeySize]byte{otherPubkey, adminPubkey}, time.Minute), Equals, true)
+	c.Assert(ValidateRequestWithKeys(t.req,
+		[][PublicKeySize]byte{otherPubkey}, time.Minute), Equals, false)
+}
+
 func (t *SignTests) TestAdminSigningIgnoreUserAgent(c *C) {
 	t.req.Header.Set("User-Agent", "foo")
 	c.Assert(t.adminSigned(), Equals, true)
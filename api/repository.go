@@ -5,3 +5,33 @@ package api
 type JSONRepository struct {
 	PubKey []byte `json:"pub_key"`
 }
+
+// JSONKeyRotation structure which is being sent to the server
+// when the repository keys have been replaced after revoking
+// a device.
+type JSONKeyRotation struct {
+	PubKey         []byte `json:"pub_key"`
+	DeviceRegistry []byte `json:"device_registry"`
+}
+
+// JSONGCResult structure which is being returned by the server
+// after collecting the unreferenced objects of a repository.
+type JSONGCResult struct {
+	DryRun       bool     `json:"dry_run"`
+	Referenced   int      `json:"referenced"`
+	Unreferenced []string `json:"unreferenced"`
+	Size         int64    `json:"size"`
+	Recent       int      `json:"recent"`
+}
+
+// JSONObjectIDs structure which is being sent to the server to find
+// out which of the listed objects the repository lacks.
+type JSONObjectIDs struct {
+	ObjectIDs []string `json:"object_ids"`
+}
+
+// JSONMissingObjects structure which is being returned by the server
+// with the objects of a JSONObjectIDs request which it lacks.
+type JSONMissingObjects struct {
+	MissingContentIDs []string `json:"missing_content_ids"`
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/hex"
 	"io"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 
 	"github.com/hoffie/larasync/api/common"
+	"github.com/hoffie/larasync/repository"
 )
 
 // extractAuthorizationPubKey returns a public key which has been passed to the
@@ -26,24 +28,21 @@ func extractAuthorizationPubKey(req *http.Request) (publicKey [PublicKeySize]byt
 }
 
 // authorizationGet requests a authorization for a passed public key.
+// The authorization is kept until the client removes it, so that it is
+// not lost if the client fails to import it.
 func (s *Server) authorizationGet(rw http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-
-	publicKey := extractAuthorizationPubKey(req)
-	repositoryName := vars["repository"]
-	repository, err := s.rm.Open(repositoryName)
-
-	var readerErr error
-	var reader io.ReadCloser
-
-	if err == nil {
-		// FIXME: This is a possible timing attack which exposes if the repository
-		// does exist or not. At the moment there is no fix for this.
-		reader, readerErr = repository.GetAuthorizationReader(publicKey)
+	r, publicKey, ok := s.authorizationRepository(rw, req)
+	if !ok {
+		return
 	}
 
-	if !common.ValidateRequest(req, publicKey, s.maxRequestAge) || err != nil || readerErr != nil {
-		http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+	reader, err := r.GetAuthorizationReader(publicKey)
+	if os.IsNotExist(err) {
+		http.Error(rw, "Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(rw, "Internal Error", http.StatusInternalServerError)
 		return
 	}
 
@@ -52,8 +51,50 @@ func (s *Server) authorizationGet(rw http.ResponseWriter, req *http.Request) {
 
 	io.Copy(rw, reader)
 	_ = reader.Close()
+}
 
-	repository.DeleteAuthorization(publicKey)
+// authorizationDelete removes the authorization for a passed public key
+// once the client has imported it.
+func (s *Server) authorizationDelete(rw http.ResponseWriter, req *http.Request) {
+	r, publicKey, ok := s.authorizationRepository(rw, req)
+	if !ok {
+		return
+	}
+
+	err := r.DeleteAuthorization(publicKey)
+	if os.IsNotExist(err) {
+		http.Error(rw, "Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// authorizationRepository validates that the request has been signed with
+// the authorization key from the URL and returns the requested repository
+// and the key. Missing repositories are reported like missing
+// authorizations, so that their existence is not revealed.
+func (s *Server) authorizationRepository(rw http.ResponseWriter, req *http.Request) (*repository.Repository, [PublicKeySize]byte, bool) {
+	vars := mux.Vars(req)
+	publicKey := extractAuthorizationPubKey(req)
+	if !common.ValidateRequest(req, publicKey, s.maxRequestAge) {
+		http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+		return nil, publicKey, false
+	}
+
+	r, err := s.rm.Open(vars["repository"])
+	if os.IsNotExist(err) {
+		http.Error(rw, "Not Found", http.StatusNotFound)
+		return nil, publicKey, false
+	}
+	if err != nil {
+		http.Error(rw, "Internal Error", http.StatusInternalServerError)
+		return nil, publicKey, false
+	}
+	return r, publicKey, true
 }
 
 // authorizationPut adds a new authorization object to the repository.
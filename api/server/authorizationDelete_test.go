@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"os"
+
+	. "gopkg.in/check.v1"
+)
+
+type AuthorizationDeleteTests struct {
+	AuthorizationTests
+}
+
+var _ = Suite(&AuthorizationDeleteTests{getAuthorizationTest()})
+
+func (t *AuthorizationDeleteTests) SetUpTest(c *C) {
+	t.AuthorizationTests.SetUpTest(c)
+	t.httpMethod = "DELETE"
+	t.req = t.requestEmptyBody(c)
+}
+
+func (t *AuthorizationDeleteTests) TestNotSigned(c *C) {
+	t.createRepository(c)
+	t.addAuthorization(c, t.testAuthorization(c))
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *AuthorizationDeleteTests) TestSignedWithRepositoryKey(c *C) {
+	t.createRepository(c)
+	t.addAuthorization(c, t.testAuthorization(c))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *AuthorizationDeleteTests) TestNotFound(c *C) {
+	t.createRepository(c)
+	t.signRequestWithAuthKey()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusNotFound)
+}
+
+func (t *AuthorizationDeleteTests) TestDelete(c *C) {
+	t.createRepository(c)
+	t.addAuthorization(c, t.testAuthorization(c))
+	t.signRequestWithAuthKey()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+
+	repo := t.getRepository(c)
+	_, err := repo.GetAuthorizationReader(t.authPublicKey)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
@@ -4,9 +4,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
-
-	"github.com/hoffie/larasync/api/common"
 
 	. "gopkg.in/check.v1"
 )
@@ -22,6 +19,12 @@ func (t *AuthorizationGetTests) TestRepositoryNotExists(c *C) {
 	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
 }
 
+func (t *AuthorizationGetTests) TestRepositoryNotExistsSigned(c *C) {
+	t.signRequestWithAuthKey()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusNotFound)
+}
+
 func (t *AuthorizationGetTests) TestNotSigned(c *C) {
 	t.createRepository(c)
 	resp := t.getResponse(t.req)
@@ -41,7 +44,7 @@ func (t *AuthorizationGetTests) TestNotFound(c *C) {
 	t.signRequestWithAuthKey()
 	resp := t.getResponse(t.req)
 
-	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+	c.Assert(resp.Code, Equals, http.StatusNotFound)
 }
 
 func (t *AuthorizationGetTests) setUpWithExist(c *C) {
@@ -75,14 +78,16 @@ func (t *AuthorizationGetTests) TestGetBody(c *C) {
 	c.Assert(len(data) > 0, Equals, true)
 }
 
-func (t *AuthorizationGetTests) TestRemove(c *C) {
+// The authorization is only removed once the client deletes it.
+func (t *AuthorizationGetTests) TestKept(c *C) {
 	t.setUpWithExist(c)
 
 	t.getResponse(t.req)
 
 	repo := t.getRepository(c)
-	_, err := repo.GetAuthorizationReader(t.authPublicKey)
-	c.Assert(os.IsNotExist(err), Equals, true)
+	reader, err := repo.GetAuthorizationReader(t.authPublicKey)
+	c.Assert(err, IsNil)
+	reader.Close()
 }
 
 func (t *AuthorizationGetTests) TestPublicKeyExtractionFailure(c *C) {
@@ -96,7 +101,3 @@ func (t *AuthorizationGetTests) TestPublicKeyExtractionFailure(c *C) {
 	resp := t.getResponse(t.req)
 	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
 }
-
-func (t *AuthorizationGetTests) signRequestWithAuthKey() {
-	common.SignWithKey(t.req, t.authPrivateKey)
-}
@@ -60,3 +60,7 @@ func (t *AuthorizationTests) addAuthorization(c *C, auth *repository.Authorizati
 	err := repository.SetAuthorization(t.authPublicKey, t.encryptionKey, auth)
 	c.Assert(err, IsNil)
 }
+
+func (t *AuthorizationTests) signRequestWithAuthKey() {
+	common.SignWithKey(t.req, t.authPrivateKey)
+}
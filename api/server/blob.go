@@ -1,14 +1,36 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 
 	"github.com/gorilla/mux"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/helpers/bincontainer"
+	"github.com/hoffie/larasync/helpers/crypto"
 )
 
+// isValidBlobID returns whether the given blob ID is a lower case hex
+// encoded hash as used for all objects.
+// IDs passed in request bodies are not restricted by the router, so this
+// keeps them from escaping the object storage.
+func isValidBlobID(blobID string) bool {
+	if len(blobID) != crypto.StringHashSize {
+		return false
+	}
+	for _, r := range blobID {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
 // blobGet is the handler to request a blob for a specific
 // repository.
 func (s *Server) blobGet(rw http.ResponseWriter, req *http.Request) {
@@ -74,3 +96,95 @@ func (s *Server) blobPut(rw http.ResponseWriter, req *http.Request) {
 	rw.Header().Set("Location", req.URL.String())
 	rw.WriteHeader(http.StatusOK)
 }
+
+// blobsMissing returns which of the blobs listed in the request are
+// missing in a specific repository.
+func (s *Server) blobsMissing(rw http.ResponseWriter, req *http.Request) {
+	jsonHeader(rw)
+	vars := mux.Vars(req)
+	repositoryName := vars["repository"]
+
+	repository, err := s.rm.Open(repositoryName)
+	if err != nil {
+		errorJSONMessage(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	query := &api.JSONObjectIDs{}
+	err = json.NewDecoder(req.Body).Decode(query)
+	if err != nil {
+		errorJSONMessage(rw, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	Log.Debug(fmt.Sprintf("Repository %s: Checking %d blobs for existence", repositoryName, len(query.ObjectIDs)))
+	missing := []string{}
+	for _, blobID := range query.ObjectIDs {
+		if !isValidBlobID(blobID) {
+			errorJSONMessage(rw, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if !repository.HasObject(blobID) {
+			missing = append(missing, blobID)
+		}
+	}
+
+	out, err := json.Marshal(api.JSONMissingObjects{
+		MissingContentIDs: missing,
+	})
+	if err != nil {
+		errorJSONMessage(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+	rw.Write(out)
+}
+
+// blobsPost is the handler to set the content of several blobs of a
+// specific repository at once. The request body alternately holds the
+// blob IDs and their contents as bincontainer chunks.
+func (s *Server) blobsPost(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	repositoryName := vars["repository"]
+
+	repository, err := s.rm.Open(repositoryName)
+	if err != nil {
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	decoder := bincontainer.NewDecoder(req.Body)
+	for {
+		blobID, err := decoder.ReadChunk()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errorText(rw, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		data, err := decoder.ReadChunk()
+		if err != nil {
+			errorText(rw, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if !isValidBlobID(string(blobID)) {
+			errorText(rw, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		Log.Debug(fmt.Sprintf("Repository: %s, Adding blob with ID %s", repositoryName, blobID))
+		err = repository.AddObject(string(blobID), bytes.NewReader(data))
+		if err != nil {
+			Log.Warn(
+				fmt.Sprintf(
+					"Repository: %s, Could not add blob with ID %s. Error: %s",
+					repositoryName, blobID, err.Error(),
+				),
+			)
+			errorText(rw, "Internal Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/helpers/bincontainer"
+)
+
+type BlobBatchTests struct {
+	BaseTests
+}
+
+var _ = Suite(&BlobBatchTests{BaseTests: newBaseTest()})
+
+func (t *BlobBatchTests) SetUpTest(c *C) {
+	t.BaseTests.SetUpTest(c)
+	t.httpMethod = "POST"
+	t.setPath("/blobs")
+}
+
+func (t *BlobBatchTests) setPath(path string) {
+	t.getURL = func() string {
+		return fmt.Sprintf(
+			"http://example.org/repositories/%s%s",
+			t.repositoryName, path,
+		)
+	}
+}
+
+// objectID returns a valid object ID derived from the given name.
+func objectID(name string) string {
+	hash := sha512.Sum512([]byte(name))
+	return hex.EncodeToString(hash[:])
+}
+
+func (t *BlobBatchTests) missing(c *C, objectIDs []string) *httptest.ResponseRecorder {
+	t.setPath("/blobs/missing")
+	body, err := json.Marshal(api.JSONObjectIDs{ObjectIDs: objectIDs})
+	c.Assert(err, IsNil)
+	t.req = t.requestWithBytes(c, body)
+	t.signRequest()
+	return t.getResponse(t.req)
+}
+
+func (t *BlobBatchTests) TestMissing(c *C) {
+	r := t.createRepository(c)
+	existing, missing1, missing2 := objectID("existing"), objectID("missing1"), objectID("missing2")
+	err := r.AddObject(existing, bytes.NewBufferString("data"))
+	c.Assert(err, IsNil)
+
+	resp := t.missing(c, []string{existing, missing1, missing2})
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	result := &api.JSONMissingObjects{}
+	err = json.Unmarshal(resp.Body.Bytes(), result)
+	c.Assert(err, IsNil)
+	c.Assert(result.MissingContentIDs, DeepEquals, []string{missing1, missing2})
+}
+
+func (t *BlobBatchTests) TestMissingInvalidID(c *C) {
+	t.createRepository(c)
+	resp := t.missing(c, []string{objectID("a"), "../objectsX/foo"})
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *BlobBatchTests) TestMissingNone(c *C) {
+	t.createRepository(c)
+	resp := t.missing(c, []string{})
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	result := &api.JSONMissingObjects{}
+	err := json.Unmarshal(resp.Body.Bytes(), result)
+	c.Assert(err, IsNil)
+	c.Assert(result.MissingContentIDs, HasLen, 0)
+}
+
+func (t *BlobBatchTests) TestMissingBadRequest(c *C) {
+	t.createRepository(c)
+	t.setPath("/blobs/missing")
+	t.req = t.requestWithBytes(c, []byte("no json"))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *BlobBatchTests) TestMissingUnauthorized(c *C) {
+	t.createRepository(c)
+	t.setPath("/blobs/missing")
+	t.req = t.requestWithBytes(c, []byte("{}"))
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *BlobBatchTests) batchBody(c *C, chunks ...string) []byte {
+	buf := &bytes.Buffer{}
+	encoder := bincontainer.NewEncoder(buf)
+	for _, chunk := range chunks {
+		err := encoder.WriteChunk([]byte(chunk))
+		c.Assert(err, IsNil)
+	}
+	return buf.Bytes()
+}
+
+func (t *BlobBatchTests) TestPost(c *C) {
+	r := t.createRepository(c)
+	a, b := objectID("a"), objectID("b")
+	t.req = t.requestWithBytes(c, t.batchBody(c, a, "data a", b, "data b"))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+
+	for id, expected := range map[string]string{a: "data a", b: "data b"} {
+		reader, err := r.GetObjectData(id)
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, expected)
+	}
+}
+
+func (t *BlobBatchTests) TestPostIncomplete(c *C) {
+	r := t.createRepository(c)
+	t.req = t.requestWithBytes(c, t.batchBody(c, objectID("a"), "data a", objectID("b")))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+	c.Assert(r.HasObject(objectID("b")), Equals, false)
+}
+
+func (t *BlobBatchTests) TestPostInvalidID(c *C) {
+	t.createRepository(c)
+	t.req = t.requestWithBytes(c, t.batchBody(c, "../escape", "data"))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *BlobBatchTests) TestPostTraversalID(c *C) {
+	r := t.createRepository(c)
+	sibling := filepath.Join(r.GetManagementDir(), "objectsX")
+	err := os.Mkdir(sibling, 0700)
+	c.Assert(err, IsNil)
+
+	t.req = t.requestWithBytes(c, t.batchBody(c, "../objectsX/foo", "data"))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+	_, err = os.Stat(filepath.Join(sibling, "foo"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *BlobBatchTests) TestPostUpperCaseID(c *C) {
+	t.createRepository(c)
+	id := strings.ToUpper(objectID("a"))
+	t.req = t.requestWithBytes(c, t.batchBody(c, id, "data"))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *BlobBatchTests) TestPostUnauthorized(c *C) {
+	t.createRepository(c)
+	t.req = t.requestWithBytes(c, t.batchBody(c, objectID("a"), "data a"))
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
@@ -2,8 +2,11 @@ package server
 
 import (
 	"net/http"
+	"time"
 
 	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/helpers/lock"
 )
 
 type BlobPutTests struct {
@@ -58,3 +61,18 @@ func (t *BlobPutTests) TestBlobPutLocationHeader(c *C) {
 	location := resp.Header().Get("Location")
 	c.Assert(location, Equals, t.req.URL.String())
 }
+
+func (t *BlobPutTests) TestBlobPutDuringGC(c *C) {
+	r := t.createRepository(c)
+	storageLockTimeout = 20 * time.Millisecond
+	defer func() { storageLockTimeout = time.Minute }()
+	// another process collects the garbage
+	gc := lock.NewFileManager().GetFileLock(r.GetManagementDir(), "storage")
+	gc.Lock()
+	defer gc.Unlock()
+
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusServiceUnavailable)
+	c.Assert(r.HasObject(t.blobID), Equals, false)
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"time"
+
 	common "github.com/hoffie/larasync/api/common"
 )
 
@@ -12,3 +14,13 @@ const (
 	// SignatureSize denotes how many bytes a sig needs (binary encoded)
 	SignatureSize = common.SignatureSize
 )
+
+const (
+	// maxNIBListWait limits how long a NIB list request may wait for
+	// new transactions.
+	maxNIBListWait = 5 * time.Minute
+)
+
+// storageLockTimeout is how long uploads and commits wait for a garbage
+// collection in progress to finish.
+var storageLockTimeout = time.Minute
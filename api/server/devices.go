@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// devicesGet returns the signed device registry of the repository.
+func (s *Server) devicesGet(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	r, err := s.rm.Open(vars["repository"])
+	if err != nil {
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := r.GetDeviceRegistryBytes()
+	if os.IsNotExist(err) {
+		errorText(rw, "Not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.WriteHeader(http.StatusOK)
+	rw.Write(data)
+}
+
+// devicesPut replaces the device registry of the repository. The registry
+// has to be signed by the repository signing key.
+func (s *Server) devicesPut(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	r, err := s.rm.Open(vars["repository"])
+	if err != nil {
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = r.SetDeviceRegistryBytes(data)
+	if err == repository.ErrSignatureVerification || err == repository.ErrUnMarshalling {
+		errorText(rw, "Bad Request", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
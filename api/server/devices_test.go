@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/api/common"
+	"github.com/hoffie/larasync/repository"
+)
+
+type DevicesTests struct {
+	BaseTests
+}
+
+var _ = Suite(&DevicesTests{newBaseTest()})
+
+func (t *DevicesTests) SetUpTest(c *C) {
+	t.BaseTests.SetUpTest(c)
+	t.getURL = func() string {
+		return fmt.Sprintf(
+			"http://example.org/repositories/%s/devices",
+			t.repositoryName,
+		)
+	}
+	t.req = t.requestEmptyBody(c)
+}
+
+// deviceClient returns a client repository using the test repository keys
+// which has registered its own device.
+func (t *DevicesTests) deviceClient(c *C, signingKey [PrivateKeySize]byte) *repository.ClientRepository {
+	r := repository.NewClient(c.MkDir())
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = r.SetKeysFromAuth(&repository.Authorization{SigningKey: signingKey})
+	c.Assert(err, IsNil)
+	err = r.RegisterDevice()
+	c.Assert(err, IsNil)
+	return r
+}
+
+func (t *DevicesTests) putRegistry(c *C, r *repository.ClientRepository) *http.Request {
+	data, err := r.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+	t.httpMethod = "PUT"
+	t.req = t.requestWithBytes(c, data)
+	t.signRequest()
+	return t.req
+}
+
+func (t *DevicesTests) TestGetUnauthorized(c *C) {
+	t.createRepository(c)
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *DevicesTests) TestGetNotFound(c *C) {
+	t.createRepository(c)
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusNotFound)
+}
+
+func (t *DevicesTests) TestPutAndGet(c *C) {
+	t.createRepository(c)
+	r := t.deviceClient(c, t.privateKey)
+	resp := t.getResponse(t.putRegistry(c, r))
+	c.Assert(resp.Code, Equals, http.StatusOK)
+
+	t.httpMethod = "GET"
+	t.req = t.requestEmptyBody(c)
+	t.signRequest()
+	resp = t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	expected, err := r.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, expected)
+}
+
+func (t *DevicesTests) TestPutForeignRegistry(c *C) {
+	t.createRepository(c)
+	otherKey, err := common.PassphraseToKey([]byte("other"))
+	c.Assert(err, IsNil)
+	r := t.deviceClient(c, otherKey)
+	resp := t.getResponse(t.putRegistry(c, r))
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *DevicesTests) TestDeviceKeyAccepted(c *C) {
+	t.createRepository(c)
+	r := t.deviceClient(c, t.privateKey)
+	deviceKey, err := r.GetDeviceSigningPrivateKey()
+	c.Assert(err, IsNil)
+
+	t.req = t.requestEmptyBody(c)
+	common.SignWithKey(t.req, deviceKey)
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+
+	resp = t.getResponse(t.putRegistry(c, r))
+	c.Assert(resp.Code, Equals, http.StatusOK)
+
+	t.httpMethod = "GET"
+	t.req = t.requestEmptyBody(c)
+	common.SignWithKey(t.req, deviceKey)
+	resp = t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+}
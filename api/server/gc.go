@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/repository"
+)
+
+// parseGCGracePeriod extracts the grace period in seconds from the
+// grace-period parameter; it defaults to repository.DefaultGCGracePeriod.
+func parseGCGracePeriod(values url.Values) (time.Duration, error) {
+	graceString := values.Get("grace-period")
+	if graceString == "" {
+		return repository.DefaultGCGracePeriod, nil
+	}
+	graceSeconds, err := strconv.ParseInt(graceString, 10, 64)
+	if err != nil || graceSeconds < 0 {
+		return 0, fmt.Errorf("grace-period %s is not a valid number of seconds", graceString)
+	}
+	return time.Duration(graceSeconds) * time.Second, nil
+}
+
+// gcPost removes all objects of the repository which are no longer
+// referenced by any NIB. If the dry-run parameter is set, the objects are
+// only reported.
+func (s *Server) gcPost(rw http.ResponseWriter, req *http.Request) {
+	jsonHeader(rw)
+	vars := mux.Vars(req)
+	repositoryName := vars["repository"]
+	r, err := s.rm.Open(repositoryName)
+	if err != nil {
+		errorJSONMessage(rw, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	values := req.URL.Query()
+	gracePeriod, err := parseGCGracePeriod(values)
+	if err != nil {
+		errorJSONMessage(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, dryRun := values["dry-run"]
+
+	Log.Info(fmt.Sprintf("Repository %s: Collecting garbage (dry run: %t)", repositoryName, dryRun))
+	result, err := r.CollectGarbage(gracePeriod, dryRun)
+	if err != nil {
+		Log.Warn(fmt.Sprintf("Repository %s: Could not collect garbage. %s", repositoryName, err.Error()))
+		errorJSONMessage(rw, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	out, err := json.Marshal(api.JSONGCResult{
+		DryRun:       dryRun,
+		Referenced:   result.Referenced,
+		Unreferenced: result.Unreferenced,
+		Size:         result.Size,
+		Recent:       result.Recent,
+	})
+	if err != nil {
+		errorJSONMessage(rw, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	rw.Write(out)
+}
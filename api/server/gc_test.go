@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/api/common"
+)
+
+type GCTests struct {
+	BaseTests
+}
+
+var _ = Suite(&GCTests{BaseTests: newBaseTest()})
+
+func (t *GCTests) SetUpTest(c *C) {
+	t.BaseTests.SetUpTest(c)
+	t.getURL = func() string {
+		return fmt.Sprintf(
+			"http://example.org/repositories/%s/gc",
+			t.repositoryName,
+		)
+	}
+	t.httpMethod = "POST"
+	t.urlParams.Set("grace-period", "0")
+	t.req = t.requestEmptyBody(c)
+}
+
+// addOrphan stores an object which is not referenced by any NIB.
+func (t *GCTests) addOrphan(c *C) {
+	r := t.createRepository(c)
+	err := r.AddObject("orphan", bytes.NewBufferString("orphan"))
+	c.Assert(err, IsNil)
+}
+
+func (t *GCTests) gcResult(c *C) *api.JSONGCResult {
+	t.req = t.requestEmptyBody(c)
+	common.SignWithPassphrase(t.req, adminSecret)
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	result := &api.JSONGCResult{}
+	err := json.Unmarshal(resp.Body.Bytes(), result)
+	c.Assert(err, IsNil)
+	return result
+}
+
+func (t *GCTests) TestUnauthorized(c *C) {
+	t.addOrphan(c)
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *GCTests) TestRepositoryNotFound(c *C) {
+	common.SignWithPassphrase(t.req, adminSecret)
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusNotFound)
+}
+
+func (t *GCTests) TestInvalidGracePeriod(c *C) {
+	t.addOrphan(c)
+	t.urlParams.Set("grace-period", "soon")
+	t.req = t.requestEmptyBody(c)
+	common.SignWithPassphrase(t.req, adminSecret)
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *GCTests) TestCollect(c *C) {
+	t.addOrphan(c)
+	result := t.gcResult(c)
+	c.Assert(result.DryRun, Equals, false)
+	c.Assert(result.Unreferenced, DeepEquals, []string{"orphan"})
+	c.Assert(t.getRepository(c).HasObject("orphan"), Equals, false)
+}
+
+func (t *GCTests) TestDryRun(c *C) {
+	t.addOrphan(c)
+	t.urlParams.Set("dry-run", "")
+	result := t.gcResult(c)
+	c.Assert(result.DryRun, Equals, true)
+	c.Assert(result.Unreferenced, DeepEquals, []string{"orphan"})
+	c.Assert(t.getRepository(c).HasObject("orphan"), Equals, true)
+}
+
+func (t *GCTests) TestGracePeriod(c *C) {
+	t.addOrphan(c)
+	t.urlParams.Del("grace-period")
+	result := t.gcResult(c)
+	c.Assert(result.Unreferenced, HasLen, 0)
+	c.Assert(result.Recent, Equals, 1)
+	c.Assert(t.getRepository(c).HasObject("orphan"), Equals, true)
+}
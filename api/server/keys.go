@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/repository"
+)
+
+// keysPut replaces the repository signing public key and the device
+// registry after the keys of the repository have been rotated.
+func (s *Server) keysPut(rw http.ResponseWriter, req *http.Request) {
+	jsonHeader(rw)
+	vars := mux.Vars(req)
+	r, err := s.rm.Open(vars["repository"])
+	if err != nil {
+		errorJSONMessage(rw, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		errorJSONMessage(rw, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var rotation api.JSONKeyRotation
+	err = json.Unmarshal(body, &rotation)
+	if err != nil || len(rotation.PubKey) != PublicKeySize {
+		errorJSONMessage(rw, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var pubKey [PublicKeySize]byte
+	copy(pubKey[:], rotation.PubKey)
+	err = r.ReplaceSigningPublicKey(pubKey, rotation.DeviceRegistry)
+	if err == repository.ErrSignatureVerification || err == repository.ErrUnMarshalling {
+		errorJSONMessage(rw, "Bad Request", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		errorJSONMessage(rw, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/api/common"
+	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
+	"github.com/hoffie/larasync/repository"
+)
+
+type KeysTests struct {
+	BaseTests
+	newKey [PrivateKeySize]byte
+}
+
+var _ = Suite(&KeysTests{BaseTests: newBaseTest()})
+
+func (t *KeysTests) SetUpTest(c *C) {
+	t.BaseTests.SetUpTest(c)
+	t.getURL = func() string {
+		return fmt.Sprintf(
+			"http://example.org/repositories/%s/keys",
+			t.repositoryName,
+		)
+	}
+	t.httpMethod = "PUT"
+	var err error
+	t.newKey, err = common.PassphraseToKey([]byte("rotated"))
+	c.Assert(err, IsNil)
+}
+
+// rotationRequest returns a request which replaces the repository key by
+// the public key of pubKeyFrom and passes a registry signed by
+// registryKey.
+func (t *KeysTests) rotationRequest(c *C, pubKeyFrom, registryKey [PrivateKeySize]byte) *http.Request {
+	r := repository.NewClient(c.MkDir())
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = r.SetKeysFromAuth(&repository.Authorization{SigningKey: registryKey})
+	c.Assert(err, IsNil)
+	err = r.RegisterDevice()
+	c.Assert(err, IsNil)
+	registry, err := r.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+
+	pubKey := edhelpers.GetPublicKeyFromPrivate(pubKeyFrom)
+	body, err := json.Marshal(api.JSONKeyRotation{
+		PubKey:         pubKey[:],
+		DeviceRegistry: registry,
+	})
+	c.Assert(err, IsNil)
+	return t.requestWithBytes(c, body)
+}
+
+// devicesGetResponse requests the device registry signed with the given
+// key.
+func (t *KeysTests) devicesGetResponse(c *C, key [PrivateKeySize]byte) int {
+	req, err := http.NewRequest("GET", fmt.Sprintf(
+		"http://example.org/repositories/%s/devices", t.repositoryName), nil)
+	c.Assert(err, IsNil)
+	common.SignWithKey(req, key)
+	return t.getResponse(req).Code
+}
+
+func (t *KeysTests) TestUnauthorized(c *C) {
+	t.createRepository(c)
+	t.req = t.rotationRequest(c, t.newKey, t.newKey)
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *KeysTests) TestRotate(c *C) {
+	t.createRepository(c)
+	t.req = t.rotationRequest(c, t.newKey, t.newKey)
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+
+	c.Assert(t.devicesGetResponse(c, t.privateKey), Equals, http.StatusUnauthorized)
+	c.Assert(t.devicesGetResponse(c, t.newKey), Equals, http.StatusOK)
+}
+
+func (t *KeysTests) TestRotateForeignRegistry(c *C) {
+	t.createRepository(c)
+	t.req = t.rotationRequest(c, t.newKey, t.privateKey)
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+
+	c.Assert(t.devicesGetResponse(c, t.privateKey), Equals, http.StatusNotFound)
+}
+
+func (t *KeysTests) TestRotateInvalidKey(c *C) {
+	t.createRepository(c)
+	t.req = t.requestWithBytes(c, []byte(`{"pub_key": "AAAA"}`))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
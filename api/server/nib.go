@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -70,27 +72,7 @@ func (s *Server) nibPut(rw http.ResponseWriter, req *http.Request) {
 	err = repository.AddNIBContent(req.Body)
 
 	if err != nil {
-		if err == repositoryModule.ErrSignatureVerification {
-			Log.Debug(fmt.Sprintf("Repository %s: Signature Verification failed when trying to add NIB with ID %s", repositoryName, nibID))
-			errorText(rw, "Signature could not be verified", http.StatusUnauthorized)
-		} else if err == repositoryModule.ErrUnMarshalling {
-			Log.Debug(fmt.Sprintf("Repository %s: Unmarshalling failed when trying to add NIB with ID %s", repositoryName, nibID))
-			errorText(rw, "Could not extract NIB", http.StatusBadRequest)
-		} else if err == repositoryModule.ErrNIBConflict {
-			Log.Debug(fmt.Sprintf("Repository %s: Conflict when trying to add NIB with ID %s", repositoryName, nibID))
-			errorText(rw, "NIB conflict", http.StatusConflict)
-		} else if repositoryModule.IsNIBContentMissing(err) {
-			Log.Debug(fmt.Sprintf("Repository %s: Contents of NIB not in Server when trying to add NIB with ID %s", repositoryName, nibID))
-			nibError := err.(*repositoryModule.ErrNIBContentMissing)
-			jsonError := &api.ContentIDsJSONError{}
-			jsonError.Error = nibError.Error()
-			jsonError.Type = "missing_content_ids"
-			jsonError.MissingContentIDs = nibError.MissingContentIDs()
-			errorJSON(rw, jsonError, http.StatusPreconditionFailed)
-		} else {
-			Log.Warn(fmt.Sprintf("Repository %s: Internal Error when trying to add NIB with ID %s. %s", repositoryName, nibID, err.Error()))
-			errorText(rw, "Internal Error", http.StatusInternalServerError)
-		}
+		nibAddError(rw, fmt.Sprintf("Repository %s: NIB with ID %s", repositoryName, nibID), err)
 		return
 	}
 
@@ -99,6 +81,96 @@ func (s *Server) nibPut(rw http.ResponseWriter, req *http.Request) {
 	rw.WriteHeader(successReturnStatus)
 }
 
+// nibCommit is the handler which adds several NIBs to the repository in a
+// single transaction. The request body holds the signed NIBs as
+// bincontainer chunks; either all of them are added or none.
+func (s *Server) nibCommit(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	repositoryName := vars["repository"]
+
+	repository, err := s.rm.Open(repositoryName)
+	if err != nil {
+		errorJSONMessage(rw, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	nibData := [][]byte{}
+	decoder := bincontainer.NewDecoder(req.Body)
+	for {
+		data, err := decoder.ReadChunk()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errorText(rw, "Could not extract NIBs", http.StatusBadRequest)
+			return
+		}
+		nibData = append(nibData, data)
+	}
+
+	Log.Debug(fmt.Sprintf("Repository %s: Committing %d NIBs", repositoryName, len(nibData)))
+	err = repository.AddNIBContents(nibData)
+	if err == repositoryModule.ErrDuplicateNIB {
+		errorText(rw, "NIB occurs more than once", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		nibAddError(rw, fmt.Sprintf("Repository %s: commit of %d NIBs", repositoryName, len(nibData)), err)
+		return
+	}
+
+	attachCurrentTransactionHeader(repository, rw)
+	rw.WriteHeader(http.StatusOK)
+}
+
+// nibAddError writes the response for an error which occurred while adding
+// NIBs; context describes the request in the log.
+func nibAddError(rw http.ResponseWriter, context string, err error) {
+	if err == repositoryModule.ErrSignatureVerification {
+		Log.Debug(fmt.Sprintf("%s: Signature verification failed", context))
+		errorText(rw, "Signature could not be verified", http.StatusUnauthorized)
+	} else if err == repositoryModule.ErrUnMarshalling {
+		Log.Debug(fmt.Sprintf("%s: Unmarshalling failed", context))
+		errorText(rw, "Could not extract NIB", http.StatusBadRequest)
+	} else if err == repositoryModule.ErrNIBConflict {
+		Log.Debug(fmt.Sprintf("%s: Conflict", context))
+		errorText(rw, "NIB conflict", http.StatusConflict)
+	} else if repositoryModule.IsNIBContentMissing(err) {
+		Log.Debug(fmt.Sprintf("%s: Contents of NIB not in Server", context))
+		nibError := err.(*repositoryModule.ErrNIBContentMissing)
+		jsonError := &api.ContentIDsJSONError{}
+		jsonError.Error = nibError.Error()
+		jsonError.Type = "missing_content_ids"
+		jsonError.MissingContentIDs = nibError.MissingContentIDs()
+		errorJSON(rw, jsonError, http.StatusPreconditionFailed)
+	} else {
+		Log.Warn(fmt.Sprintf("%s: Internal Error. %s", context, err.Error()))
+		errorText(rw, "Internal Error", http.StatusInternalServerError)
+	}
+}
+
+// parseNIBListWait extracts the duration a NIB list request may wait for
+// new transactions. It is limited to maxNIBListWait.
+func parseNIBListWait(values url.Values) (time.Duration, error) {
+	waitString := values.Get("wait")
+	if waitString == "" {
+		return 0, nil
+	}
+	waitSeconds, err := strconv.ParseInt(waitString, 10, 64)
+	if err != nil || waitSeconds < 0 {
+		return 0, fmt.Errorf("wait %s is not a valid number of seconds", waitString)
+	}
+	wait := time.Duration(waitSeconds) * time.Second
+	if wait > maxNIBListWait {
+		wait = maxNIBListWait
+	}
+	return wait, nil
+}
+
+// nibList returns the NIBs of the repository. If a from-transaction-id is
+// given, only NIBs of newer transactions are returned; the additional wait
+// parameter blocks the request for up to the given number of seconds
+// until such a transaction exists.
 func (s *Server) nibList(rw http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	repositoryName := vars["repository"]
@@ -130,6 +202,20 @@ func (s *Server) nibList(rw http.ResponseWriter, req *http.Request) {
 			Log.Debug(fmt.Sprintf("Repository %s: Error while trying to extract transaction id of %s", repositoryName, fromRepositoryIDString[0]))
 			return
 		}
+		wait, err := parseNIBListWait(values)
+		if err != nil {
+			errorText(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if wait > 0 {
+			Log.Debug(fmt.Sprintf("Repository %s: Waiting up to %s for transactions after id %d", repositoryName, wait, afterTransactionID))
+			_, err = repository.WaitForTransactionAfter(afterTransactionID, wait)
+			if err != nil {
+				Log.Warn(fmt.Sprintf("Repository %s: Could not wait for transactions. %s", repositoryName, err.Error()))
+				errorText(rw, "Internal Error", http.StatusInternalServerError)
+				return
+			}
+		}
 		Log.Debug(fmt.Sprintf("Repository %s: Requesting NIB list after transaction id %d", repositoryName, afterTransactionID))
 		nibChannel, err = repository.GetNIBBytesFrom(afterTransactionID)
 	}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/hoffie/larasync/api"
+	"github.com/hoffie/larasync/helpers/bincontainer"
+	"github.com/hoffie/larasync/repository/nib"
+
+	. "gopkg.in/check.v1"
+)
+
+type NIBCommitTest struct {
+	NIBTest
+}
+
+var _ = Suite(&NIBCommitTest{getNIBTest()})
+
+func (t *NIBCommitTest) SetUpTest(c *C) {
+	t.NIBTest.SetUpTest(c)
+	t.httpMethod = "POST"
+	t.createRepository(c)
+}
+
+// testNIBs returns two NIBs whose contents are stored in the repository.
+func (t *NIBCommitTest) testNIBs(c *C) []*nib.NIB {
+	nibs := []*nib.NIB{}
+	for _, seed := range []string{"a", "b"} {
+		t.setNIBId(seed)
+		nibs = append(nibs, t.getTestNIB())
+	}
+	t.fillContentOfDefaultNIB(c)
+	return nibs
+}
+
+func (t *NIBCommitTest) commitBody(c *C, nibs ...*nib.NIB) []byte {
+	buf := &bytes.Buffer{}
+	encoder := bincontainer.NewEncoder(buf)
+	for _, n := range nibs {
+		err := encoder.WriteChunk(t.signNIBBytes(c, t.nibToBytes(n)))
+		c.Assert(err, IsNil)
+	}
+	return buf.Bytes()
+}
+
+func (t *NIBCommitTest) commit(c *C, ifMatch string, nibs ...*nib.NIB) *httptest.ResponseRecorder {
+	t.req = t.requestWithBytes(c, t.commitBody(c, nibs...))
+	if ifMatch != "" {
+		t.req.Header.Set("If-Match", ifMatch)
+	}
+	t.signRequest()
+	return t.getResponse(t.req)
+}
+
+func (t *NIBCommitTest) TestUnauthorized(c *C) {
+	nibs := t.testNIBs(c)
+	t.req = t.requestWithBytes(c, t.commitBody(c, nibs...))
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusUnauthorized)
+}
+
+func (t *NIBCommitTest) TestCommit(c *C) {
+	nibs := t.testNIBs(c)
+	resp := t.commit(c, "", nibs...)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+
+	repo := t.getRepository(c)
+	transaction, err := repo.CurrentTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(transaction.NIBIDs, DeepEquals, []string{nibs[0].ID, nibs[1].ID})
+	c.Assert(
+		resp.Header().Get("X-Current-Transaction-Id"),
+		Equals,
+		transaction.IDString(),
+	)
+}
+
+func (t *NIBCommitTest) TestCommitEmptyRepositoryPrecondition(c *C) {
+	resp := t.commit(c, "0", t.testNIBs(c)...)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+}
+
+func (t *NIBCommitTest) TestCommitPrecondition(c *C) {
+	t.addTestNIB(c)
+	repo := t.getRepository(c)
+	transaction, err := repo.CurrentTransaction()
+	c.Assert(err, IsNil)
+
+	resp := t.commit(c, transaction.IDString(), t.testNIBs(c)...)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+}
+
+func (t *NIBCommitTest) TestCommitPreconditionFailed(c *C) {
+	t.addTestNIB(c)
+	repo := t.getRepository(c)
+	transaction, err := repo.CurrentTransaction()
+	c.Assert(err, IsNil)
+	nibs := t.testNIBs(c)
+
+	resp := t.commit(c, transaction.PreviousIDString(), nibs...)
+	c.Assert(resp.Code, Equals, http.StatusPreconditionFailed)
+	c.Assert(repo.HasNIB(nibs[0].ID), Equals, false)
+}
+
+func (t *NIBCommitTest) TestCommitContentMissing(c *C) {
+	nibs := t.testNIBs(c)
+	rev, err := nibs[1].LatestRevision()
+	c.Assert(err, IsNil)
+	rev.ContentIDs = []string{"missing"}
+
+	resp := t.commit(c, "", nibs...)
+	c.Assert(resp.Code, Equals, http.StatusPreconditionFailed)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	jsonError := &api.ContentIDsJSONError{}
+	err = json.Unmarshal(data, jsonError)
+	c.Assert(err, IsNil)
+	c.Assert(jsonError.MissingContentIDs, DeepEquals, []string{"missing"})
+
+	repo := t.getRepository(c)
+	c.Assert(repo.HasNIB(nibs[0].ID), Equals, false)
+}
+
+func (t *NIBCommitTest) TestCommitConflict(c *C) {
+	existing := t.addTestNIB(c)
+	changed := t.getTestNIB()
+	rev, err := changed.LatestRevision()
+	c.Assert(err, IsNil)
+	rev.MetadataID = "changed"
+	repo := t.getRepository(c)
+	t.fillNIBContentObjects(c, repo, changed)
+	t.setNIBId("new")
+	added := t.getTestNIB()
+
+	resp := t.commit(c, "", added, changed)
+	c.Assert(resp.Code, Equals, http.StatusConflict)
+	c.Assert(repo.HasNIB(added.ID), Equals, false)
+	stored, err := repo.GetNIB(existing.ID)
+	c.Assert(err, IsNil)
+	c.Assert(stored.Revisions, HasLen, 1)
+}
+
+func (t *NIBCommitTest) TestCommitDuplicate(c *C) {
+	nibs := t.testNIBs(c)
+	resp := t.commit(c, "", nibs[0], nibs[0])
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+func (t *NIBCommitTest) TestCommitMalformed(c *C) {
+	t.req = t.requestWithBytes(c, []byte("no container"))
+	t.signRequest()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
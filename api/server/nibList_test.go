@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -187,3 +188,47 @@ func (t *NIBListTest) TestNoCurrentTransactionIDHeader(c *C) {
 		"",
 	)
 }
+
+func (t *NIBListTest) waitRequest(c *C, fromTransactionID int64, wait string) {
+	t.urlParams.Add("from-transaction-id", strconv.FormatInt(fromTransactionID, 10))
+	t.urlParams.Add("wait", wait)
+	t.req = t.requestEmptyBody(c)
+	t.signRequest()
+}
+
+func (t *NIBListTest) TestWaitInvalid(c *C) {
+	t.waitRequest(c, 0, "foo")
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusBadRequest)
+}
+
+// It should not wait if newer transactions already exist.
+func (t *NIBListTest) TestWaitExisting(c *C) {
+	nibs := t.createNibList(c)
+	t.waitRequest(c, 0, "60")
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	AssertNibSetsEqual(c, nibs, t.getNIBsFromReader(c, resp.Body))
+}
+
+func (t *NIBListTest) TestWaitTimeout(c *C) {
+	t.waitRequest(c, 0, "1")
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	c.Assert(t.getNIBsFromReader(c, resp.Body), HasLen, 0)
+}
+
+// It should return as soon as a new transaction is added.
+func (t *NIBListTest) TestWaitNotified(c *C) {
+	t.waitRequest(c, 0, "60")
+	added := make(chan *nib.NIB)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		added <- t.addTestNIB(c)
+	}()
+	start := time.Now()
+	resp := t.getResponse(t.req)
+	c.Assert(resp.Code, Equals, http.StatusOK)
+	c.Assert(time.Since(start) < 30*time.Second, Equals, true)
+	AssertNibSetsEqual(c, []*nib.NIB{<-added}, t.getNIBsFromReader(c, resp.Body))
+}
@@ -68,27 +68,61 @@ func (s *Server) setupRoutes() {
 		s.requireAdminAuth(s.repositoryList)).Methods("GET")
 	s.router.HandleFunc("/repositories/{repository}",
 		s.requireAdminAuth(s.repositoryCreate)).Methods("PUT")
+	s.router.HandleFunc("/repositories/{repository}/gc",
+		s.requireAdminAuth(
+			s.requireExistingRepository(
+				s.synchronizeWith("nibPUT", s.gcPost),
+			),
+		),
+	).Methods("POST")
 
+	s.router.HandleFunc("/repositories/{repository}/blobs",
+		s.requireRepositoryAuth(s.lockStorage(s.blobsPost))).Methods("POST")
+	s.router.HandleFunc("/repositories/{repository}/blobs/missing",
+		s.requireRepositoryAuth(s.blobsMissing)).Methods("POST")
 	s.router.HandleFunc("/repositories/{repository}/blobs/{blobID}",
 		s.requireRepositoryAuth(s.blobGet)).Methods("GET")
 	s.router.HandleFunc("/repositories/{repository}/blobs/{blobID}",
-		s.requireRepositoryAuth(s.blobPut)).Methods("PUT")
+		s.requireRepositoryAuth(s.lockStorage(s.blobPut))).Methods("PUT")
 
 	s.router.HandleFunc("/repositories/{repository}/nibs",
 		s.requireRepositoryAuth(s.nibList)).Methods("GET")
+	s.router.HandleFunc("/repositories/{repository}/nibs",
+		s.requireRepositoryAuth(
+			s.synchronizeWith(
+				"nibPUT",
+				s.lockStorage(s.checkTransactionPrecondition(s.nibCommit)),
+			),
+		),
+	).Methods("POST")
 	s.router.HandleFunc("/repositories/{repository}/nibs/{nibID}",
 		s.requireRepositoryAuth(s.nibGet)).Methods("GET")
 	s.router.HandleFunc("/repositories/{repository}/nibs/{nibID}",
 		s.requireRepositoryAuth(
 			s.synchronizeWith(
 				"nibPUT",
-				s.checkTransactionPrecondition(s.nibPut),
+				s.lockStorage(s.checkTransactionPrecondition(s.nibPut)),
 			),
 		),
 	).Methods("PUT")
 
+	s.router.HandleFunc("/repositories/{repository}/devices",
+		s.requireRepositoryAuth(s.devicesGet)).Methods("GET")
+	s.router.HandleFunc("/repositories/{repository}/devices",
+		s.requireRepositoryAuth(
+			s.synchronizeWith("devicesPUT", s.devicesPut),
+		),
+	).Methods("PUT")
+	s.router.HandleFunc("/repositories/{repository}/keys",
+		s.requireRepositoryAuth(
+			s.synchronizeWith("devicesPUT", s.keysPut),
+		),
+	).Methods("PUT")
+
 	s.router.HandleFunc("/repositories/{repository}/authorizations/{authPublicKey}",
 		s.authorizationGet).Methods("GET")
+	s.router.HandleFunc("/repositories/{repository}/authorizations/{authPublicKey}",
+		s.authorizationDelete).Methods("DELETE")
 	s.router.HandleFunc("/repositories/{repository}/authorizations/{authPublicKey}",
 		s.requireRepositoryAuth(s.authorizationPut)).Methods("PUT")
 
@@ -109,6 +143,20 @@ func (s *Server) requireAdminAuth(f http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// requireExistingRepository wraps a HandlerFunc and only calls it if the
+// requested repository exists. It is meant for admin requests, which may
+// learn about the existence of repositories.
+func (s *Server) requireExistingRepository(f http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		if !s.rm.Exists(vars["repository"]) {
+			errorJSONMessage(rw, "Not found", http.StatusNotFound)
+			return
+		}
+		f(rw, req)
+	}
+}
+
 // requireAuth wraps a handlerFunc and only calls it if the request is
 // authenticated
 func (s *Server) requireRepositoryAuth(f http.HandlerFunc) http.HandlerFunc {
@@ -129,17 +177,14 @@ func (s *Server) requireRepositoryAuth(f http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		var pubKeyArray [PublicKeySize]byte
-		pubKey, err := repository.GetSigningPublicKey()
+		// the repository key and all registered device keys are accepted
+		pubKeys, err := repository.AcceptedSigningPublicKeys()
 		if err != nil {
 			http.Error(rw, "Internal Error", http.StatusInternalServerError)
 			return
 		}
 
-		copy(pubKeyArray[0:PublicKeySize], pubKey[:PublicKeySize])
-		// TODO: Find if there is a better way for this.
-
-		if !common.ValidateRequest(req, pubKeyArray, s.maxRequestAge) {
+		if !common.ValidateRequestWithKeys(req, pubKeys, s.maxRequestAge) {
 			http.Error(rw, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -172,13 +217,39 @@ func (s *Server) synchronizeWith(roleName string, f http.HandlerFunc) http.Handl
 	}
 }
 
+// lockStorage can be used as a wrapper. The endpoint then holds the storage
+// lock of the repository shared, so that a garbage collection, which may
+// run in another process, cannot remove objects while they are stored or
+// referenced by new NIBs.
+func (s *Server) lockStorage(f http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		repository, err := s.rm.Open(vars["repository"])
+		if err != nil {
+			http.Error(rw, "Internal Error", http.StatusInternalServerError)
+			return
+		}
+
+		l := repository.StorageLock()
+		err = l.RLockTimeout(storageLockTimeout)
+		if err != nil {
+			Log.Warn(fmt.Sprintf("Repository %s: Could not take the storage lock. %s",
+				vars["repository"], err.Error()))
+			http.Error(rw, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.RUnlock()
+		f(rw, req)
+	}
+}
+
 // checkTransactionPrecondition checks if there is a if-match header set that this
 // entry is the current transaction id in the system.
 func (s *Server) checkTransactionPrecondition(f http.HandlerFunc) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		vars := mux.Vars(req)
 		repositoryName := vars["repository"]
-		repository, err := s.rm.Open(repositoryName)
+		r, err := s.rm.Open(repositoryName)
 		if err != nil {
 			http.Error(rw, "Internal Error", http.StatusInternalServerError)
 			return
@@ -187,13 +258,18 @@ func (s *Server) checkTransactionPrecondition(f http.HandlerFunc) http.HandlerFu
 		header := req.Header
 		ifMatch := header.Get("If-Match")
 		if ifMatch != "" {
-			currentTransaction, err := repository.CurrentTransaction()
-			if err != nil {
+			// a repository without transactions matches the id 0
+			currentID := "0"
+			currentTransaction, err := r.CurrentTransaction()
+			if err != nil && err != repository.ErrTransactionNotExists {
 				http.Error(rw, "Internal Error", http.StatusInternalServerError)
 				return
 			}
+			if currentTransaction != nil {
+				currentID = currentTransaction.IDString()
+			}
 
-			if ifMatch != currentTransaction.IDString() {
+			if ifMatch != currentID {
 				rw.WriteHeader(http.StatusPreconditionFailed)
 				return
 			}
@@ -43,12 +43,40 @@ func (d *Dispatcher) cmdActions() []cli.Command {
 			Name:   "clone",
 			Usage:  "downloads an already initialized repository",
 			Action: d.wrapAction(d.cloneAction),
+			Flags:  d.cloneFlags(),
+		},
+		{
+			Name:   "devices",
+			Usage:  "lists the devices of the repository.",
+			Action: d.wrapAction(d.devicesAction),
+		},
+		{
+			Name:   "fsck",
+			Usage:  "checks the integrity of the repository.",
+			Action: d.wrapAction(d.fsckAction),
+			Flags:  d.fsckFlags(),
 		},
 		{
 			Name:   "init",
 			Usage:  "initialize a new repository.",
 			Action: d.wrapAction(d.initAction),
 		},
+		{
+			Name:   "log",
+			Usage:  "lists the revisions of the given path.",
+			Action: d.wrapAction(d.logAction),
+		},
+		{
+			Name:   "mv",
+			Usage:  "moves the given file or directory and records the move.",
+			Action: d.wrapAction(d.mvAction),
+		},
+		{
+			Name:   "prune",
+			Usage:  "removes old revisions according to the retention policy.",
+			Action: d.wrapAction(d.pruneAction),
+			Flags:  d.pruneFlags(),
+		},
 		{
 			Name:   "pull",
 			Usage:  "downlodas the current state from the server.",
@@ -71,22 +99,71 @@ func (d *Dispatcher) cmdActions() []cli.Command {
 			Usage:  "resets the stored server fingerprint",
 			Action: d.wrapAction(d.resetFingerprintAction),
 		},
+		{
+			Name:   "restore",
+			Usage:  "restores an older revision of the given file or directory.",
+			Action: d.wrapAction(d.restoreAction),
+			Flags:  d.restoreFlags(),
+		},
+		{
+			Name:   "revoke-device",
+			Usage:  "revokes the access of a device and replaces the repository keys.",
+			Action: d.wrapAction(d.revokeDeviceAction),
+		},
 		{
 			Name:   "server",
 			Usage:  "run in server mode.",
 			Action: d.wrapAction(d.serverAction),
 			Flags:  d.serverFlags(),
+			Subcommands: []cli.Command{
+				{
+					Name:   "fsck",
+					Usage:  "checks the integrity of the repositories.",
+					Action: d.wrapAction(d.serverFsckAction),
+					Flags:  d.serverFlags(),
+				},
+				{
+					Name:   "gc",
+					Usage:  "removes objects which are no longer referenced.",
+					Action: d.wrapAction(d.serverGCAction),
+					Flags:  d.serverGCFlags(),
+				},
+			},
 		},
 		{
 			Name:   "server-fingerprint",
 			Usage:  "print server certificate's public key fingerprint",
 			Action: d.wrapAction(d.serverFingerprintAction),
 		},
+		{
+			Name:   "sparse",
+			Usage:  "selects the items which are checked out.",
+			Action: d.wrapAction(d.sparseAction),
+			Flags:  d.sparseFlags(),
+		},
+		{
+			Name:   "status",
+			Usage:  "shows changes between the work dir, the repository and the server.",
+			Action: d.wrapAction(d.statusAction),
+			Flags:  d.statusFlags(),
+		},
 		{
 			Name:   "sync",
 			Usage:  "uploads and downloads all files from and to the repository.",
 			Action: d.wrapAction(d.syncAction),
 			Flags:  d.syncFlags(),
 		},
+		{
+			Name:   "thin",
+			Usage:  "keeps only the objects of the current checkout locally.",
+			Action: d.wrapAction(d.thinAction),
+			Flags:  d.thinFlags(),
+		},
+		{
+			Name:   "watch",
+			Usage:  "continuously synchronizes changes in the repository.",
+			Action: d.wrapAction(d.watchAction),
+			Flags:  d.watchFlags(),
+		},
 	}
 }
@@ -14,6 +14,12 @@ func (d *Dispatcher) addAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	err = r.AddItem(absPath)
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Unable to add the given item to the repository (%s)\n", err)
@@ -33,6 +33,12 @@ func (d *Dispatcher) authorizeNewClientAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, false)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	authorizationBytes, err := r.SerializedAuthorization(encryptionKey)
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Error: %s\n", err)
@@ -45,6 +51,15 @@ func (d *Dispatcher) authorizeNewClientAction() int {
 		return 1
 	}
 
+	// the authorization hands out the repository keys, so the request is
+	// signed with them instead of the device key to prove their validity.
+	repositoryKey, err := r.GetSigningPrivateKey()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	client.SetSigningPrivateKey(repositoryKey)
+
 	defaultServer := d.sc.DefaultServer
 	authURL, err := apiclient.NewAuthURL(client.BaseURL, signingPrivKey, &encryptionKey,
 		defaultServer.Fingerprint)
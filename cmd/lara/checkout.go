@@ -28,6 +28,12 @@ func (d *Dispatcher) checkoutPathAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	err = r.CheckoutPath(absPath)
 	if err != nil {
 		fmt.Fprintf(d.stderr,
@@ -44,6 +50,12 @@ func (d *Dispatcher) checkoutAllPathsAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	err = r.CheckoutAllPaths()
 	if err != nil {
 		fmt.Fprintf(d.stderr,
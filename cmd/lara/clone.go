@@ -5,6 +5,7 @@ import (
 	"os"
 
 	apiclient "github.com/hoffie/larasync/api/client"
+	"github.com/hoffie/larasync/repository"
 )
 
 // syncAction implements the "lara clone" command.
@@ -18,15 +19,28 @@ func (d *Dispatcher) cloneAction() int {
 
 	urlString := args[0]
 	repoName := args[1]
-	client, repo, err := apiclient.ImportAuthorization(repoName, urlString)
+	client, repo, err := d.cloneClient(urlString, repoName)
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Error: Unable to import authorization (%s)\n", err)
 		return 1
 	}
+	unlock, err := d.lockRepository(repo, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+	d.configureConcurrency(client)
 	dl := client.Downloader(repo)
 	err = dl.GetAll()
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Error: Could not load data from server. (%s)\n", err)
+		fmt.Fprintln(d.stderr, "Run the same command again to resume the download.")
+		return 1
+	}
+	err = setCloning(repo, false)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: Unable to save state config (%s)\n", err)
 		return 1
 	}
 
@@ -38,3 +52,37 @@ func (d *Dispatcher) cloneAction() int {
 	}
 	return d.checkoutAllPathsAction()
 }
+
+// cloneClient returns the client and the repository for cloning into
+// the given directory. A clone which has been interrupted is resumed;
+// otherwise the authorization is imported from the given URL, which
+// can only be used once.
+func (d *Dispatcher) cloneClient(urlString, repoName string) (*apiclient.Client, *repository.ClientRepository, error) {
+	repo := repository.NewClient(repoName)
+	sc, err := repo.StateConfig()
+	if err == nil && sc.Cloning {
+		log.Info("Resuming interrupted clone.")
+		client, err := d.clientFor(repo)
+		return client, repo, err
+	}
+	client, repo, err := apiclient.ImportAuthorization(repoName, urlString)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = setCloning(repo, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, repo, nil
+}
+
+// setCloning records whether the initial download of the given cloned
+// repository is still in progress.
+func setCloning(r *repository.ClientRepository, cloning bool) error {
+	sc, err := r.StateConfig()
+	if err != nil {
+		return err
+	}
+	sc.Cloning = cloning
+	return sc.Save()
+}
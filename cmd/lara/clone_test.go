@@ -7,6 +7,8 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/hoffie/larasync/repository"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -48,3 +50,43 @@ func (t *CloneTests) TestClone(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(gotContent, DeepEquals, testFileContent)
 }
+
+func (t *CloneTests) TestResumeInterruptedClone(c *C) {
+	testFileName := "foo.txt"
+	testFileContent := []byte("test content")
+	clonePath := filepath.Join(t.dir, "test-clone")
+
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	c.Assert(t.d.run([]string{"authorize-new-client"}), Equals, 0)
+	url := authURLRegex.FindString(t.out.String())
+	err := ioutil.WriteFile(testFileName, testFileContent, 0600)
+	c.Assert(err, IsNil)
+	c.Assert(t.d.run([]string{"add", testFileName}), Equals, 0)
+	c.Assert(t.d.run([]string{"sync"}), Equals, 0)
+
+	err = os.Chdir(t.dir)
+	c.Assert(err, IsNil)
+	c.Assert(t.d.run([]string{"clone", url, clonePath}), Equals, 0)
+
+	// pretend that the download has been interrupted; the authorization
+	// URL has been used already, so the clone has to be resumed
+	clone := repository.NewClient(clonePath)
+	sc, err := clone.StateConfig()
+	c.Assert(err, IsNil)
+	sc.Cloning = true
+	c.Assert(sc.Save(), IsNil)
+	err = os.Remove(filepath.Join(clonePath, testFileName))
+	c.Assert(err, IsNil)
+	err = os.Chdir(t.dir)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.d.run([]string{"clone", url, clonePath}), Equals, 0)
+	gotContent, err := ioutil.ReadFile(filepath.Join(clonePath, testFileName))
+	c.Assert(err, IsNil)
+	c.Assert(gotContent, DeepEquals, testFileContent)
+	clone = repository.NewClient(clonePath)
+	sc, err = clone.StateConfig()
+	c.Assert(err, IsNil)
+	c.Assert(sc.Cloning, Equals, false)
+}
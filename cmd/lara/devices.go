@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+const (
+	// deviceIDListLength is the number of characters of device IDs which
+	// are displayed when listing devices.
+	deviceIDListLength = 16
+)
+
+// devicesAction implements the "lara devices" command.
+func (d *Dispatcher) devicesAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, false)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+	registry, err := r.DeviceRegistry()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to read the device registry (%s)\n", err)
+		return 1
+	}
+	ownID, err := r.DeviceID()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to determine this device (%s)\n", err)
+		return 1
+	}
+
+	for _, device := range registry.Devices {
+		id := device.ID()
+		note := ""
+		if id == ownID {
+			note = "(this device)"
+		}
+		fmt.Fprintf(d.stdout, "%s  %s  %-20s %s\n", id[:deviceIDListLength],
+			time.Unix(device.UTCTimestamp, 0).Format(timeFormat), device.Name, note)
+	}
+	return 0
+}
+
+// findDevice returns the device whose ID starts with the given query or
+// whose name equals it.
+func findDevice(registry *repository.DeviceRegistry, query string) (*repository.Device, error) {
+	var found *repository.Device
+	for _, device := range registry.Devices {
+		if !strings.HasPrefix(device.ID(), query) && device.Name != query {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%q matches more than one device; please specify the device ID", query)
+		}
+		found = device
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no device matches %q", query)
+	}
+	return found, nil
+}
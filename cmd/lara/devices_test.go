@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DevicesTests struct {
+	BaseTests
+}
+
+var _ = Suite(&DevicesTests{BaseTests{}})
+
+func (t *DevicesTests) TestArgs(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"devices", "foo"}), Equals, 1)
+}
+
+func (t *DevicesTests) TestDevices(c *C) {
+	t.initRepo(c)
+	t.runAndExpectCode(c, []string{"devices"}, 0)
+	lines := strings.Split(strings.TrimSpace(t.out.String()), "\n")
+	c.Assert(lines, HasLen, 1)
+	c.Assert(strings.HasSuffix(lines[0], "(this device)"), Equals, true)
+}
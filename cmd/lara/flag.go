@@ -1,7 +1,12 @@
 package main
 
 import (
+	"time"
+
 	"github.com/codegangsta/cli"
+
+	"github.com/hoffie/larasync/api/client"
+	"github.com/hoffie/larasync/repository"
 )
 
 // globalFlags returns the flags that should be
@@ -23,6 +28,23 @@ func (d *Dispatcher) serverFlags() []cli.Flag {
 	}
 }
 
+// serverGCFlags returns the flags that should be
+// registered as flags available in the "server gc"
+// subcommand.
+func (d *Dispatcher) serverGCFlags() []cli.Flag {
+	return append(d.serverFlags(),
+		cli.BoolFlag{
+			Name:  "dry-run, n",
+			Usage: "only reports the objects which would be removed",
+		},
+		cli.DurationFlag{
+			Name:  "grace-period",
+			Value: repository.DefaultGCGracePeriod,
+			Usage: "minimum age of unreferenced objects before they are removed",
+		},
+	)
+}
+
 // pushFlags returns the flags that should be
 // registered as flags available in the "push"
 // subcommand.
@@ -32,6 +54,26 @@ func (d *Dispatcher) pushFlags() []cli.Flag {
 			Name:  "full, f",
 			Usage: "forces a full synchronization to the server",
 		},
+		d.jobsFlag(),
+	}
+}
+
+// cloneFlags returns the flags that should be
+// registered as flags available in the "clone"
+// subcommand.
+func (d *Dispatcher) cloneFlags() []cli.Flag {
+	return []cli.Flag{
+		d.jobsFlag(),
+	}
+}
+
+// jobsFlag returns the flag which configures the number of objects
+// which are transferred in parallel.
+func (d *Dispatcher) jobsFlag() cli.Flag {
+	return cli.IntFlag{
+		Name:  "jobs, j",
+		Value: client.DefaultConcurrency,
+		Usage: "number of objects which are transferred in parallel",
 	}
 }
 
@@ -52,3 +94,135 @@ func (d *Dispatcher) syncFlags() []cli.Flag {
 	// the push action.
 	return d.pushFlags()
 }
+
+// sparseFlags returns the flags that should be
+// registered as flags available in the "sparse"
+// subcommand.
+func (d *Dispatcher) sparseFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "include",
+			Value: &cli.StringSlice{},
+			Usage: "path pattern of items which are checked out (may be repeated)",
+		},
+		cli.StringSliceFlag{
+			Name:  "exclude",
+			Value: &cli.StringSlice{},
+			Usage: "path pattern of items which are not checked out (may be repeated)",
+		},
+		cli.BoolFlag{
+			Name:  "off",
+			Usage: "checks out all items again",
+		},
+	}
+}
+
+// statusFlags returns the flags that should be
+// registered as flags available in the "status"
+// subcommand.
+func (d *Dispatcher) statusFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "also lists unchanged files",
+		},
+		cli.BoolFlag{
+			Name:  "offline",
+			Usage: "does not query the server",
+		},
+	}
+}
+
+// thinFlags returns the flags that should be
+// registered as flags available in the "thin"
+// subcommand.
+func (d *Dispatcher) thinFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  "cache-size",
+			Usage: "MiB of older objects which are kept in addition",
+		},
+		cli.BoolFlag{
+			Name:  "off",
+			Usage: "keeps all objects locally again",
+		},
+	}
+}
+
+// watchFlags returns the flags that should be
+// registered as flags available in the "watch"
+// subcommand.
+func (d *Dispatcher) watchFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.DurationFlag{
+			Name:  "debounce",
+			Value: 2 * time.Second,
+			Usage: "time without changes before they are synchronized",
+		},
+		cli.DurationFlag{
+			Name:  "poll-interval",
+			Value: time.Minute,
+			Usage: "maximum time a request waits for changes on the server",
+		},
+	}
+}
+
+// fsckFlags returns the flags that should be
+// registered as flags available in the "fsck"
+// subcommand.
+func (d *Dispatcher) fsckFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "repair",
+			Usage: "re-fetches missing and bad objects from the server",
+		},
+	}
+}
+
+// pruneFlags returns the flags that should be
+// registered as flags available in the "prune"
+// subcommand.
+func (d *Dispatcher) pruneFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  "keep-last",
+			Usage: "keeps the given number of most recent revisions",
+		},
+		cli.IntFlag{
+			Name:  "keep-days",
+			Usage: "keeps all revisions of the given number of past days",
+		},
+		cli.IntFlag{
+			Name:  "keep-daily",
+			Usage: "keeps the latest revision of each of the given number of past days",
+		},
+		cli.IntFlag{
+			Name:  "keep-weekly",
+			Usage: "keeps the latest revision of each of the given number of past weeks",
+		},
+		cli.BoolFlag{
+			Name:  "save",
+			Usage: "stores the given policy as the repository's retention policy",
+		},
+	}
+}
+
+// restoreFlags returns the flags that should be
+// registered as flags available in the "restore"
+// subcommand.
+func (d *Dispatcher) restoreFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  "revision, r",
+			Usage: "number of the revision to restore as listed by \"lara log\"",
+		},
+		cli.StringFlag{
+			Name:  "at",
+			Usage: "restores the state at the given time (" + timeFormat + ")",
+		},
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "restores to the given path instead of the original one",
+		},
+	}
+}
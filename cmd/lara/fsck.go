@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hoffie/larasync/helpers/lock"
+	"github.com/hoffie/larasync/repository"
+)
+
+// fsckAction implements the "lara fsck" command; it checks the integrity
+// of the repository and optionally re-fetches bad objects from the server.
+func (d *Dispatcher) fsckAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	repair := d.context.Bool("repair")
+	unlock, err := d.lockRepository(r, repair)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+
+	result, err := r.Fsck()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to check the repository (%s)\n", err)
+		return 1
+	}
+	if repair && len(result.Problems) > 0 {
+		client, err := d.clientFor(r)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: %s\n", err)
+			return 1
+		}
+		r.SetObjectFetcher(client)
+		err = r.RepairObjects(result)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to repair the repository (%s)\n", err)
+			return 1
+		}
+	}
+
+	d.printFsckResult("", result)
+	if result.Unrepaired() > 0 {
+		return 1
+	}
+	return 0
+}
+
+// serverFsckAction implements "lara server fsck [NAME...]"; it checks the
+// integrity of the given repositories or of all repositories of the
+// server. A running server is coordinated with through the storage lock
+// of each repository.
+func (d *Dispatcher) serverFsckAction() int {
+	cfg, err := d.loadServerConfig()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to load server config (%s)\n", err)
+		return 1
+	}
+	rm, err := repository.NewManager(cfg.Repository.BasePath)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to open the repositories (%s)\n", err)
+		return 1
+	}
+	names := []string(d.context.Args())
+	if len(names) == 0 {
+		names, err = rm.ListNames()
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to list the repositories (%s)\n", err)
+			return 1
+		}
+	}
+
+	exitCode := 0
+	for _, name := range names {
+		r, err := rm.Open(name)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to open repository %s (%s)\n", name, err)
+			exitCode = 1
+			continue
+		}
+		result, err := r.Fsck()
+		if err == lock.ErrTimeout {
+			fmt.Fprintf(d.stderr, "Error: repository %s is busy with uploads, try again later\n", name)
+			exitCode = 1
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to check repository %s (%s)\n", name, err)
+			exitCode = 1
+			continue
+		}
+		d.printFsckResult(name+": ", result)
+		if result.Unrepaired() > 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// printFsckResult lists the problems of the given result and outputs a
+// summary; each line starts with the given prefix.
+func (d *Dispatcher) printFsckResult(prefix string, result *repository.FsckResult) {
+	repaired := 0
+	for _, problem := range result.Problems {
+		suffix := ""
+		if problem.Repaired {
+			suffix = " (repaired)"
+			repaired++
+		}
+		fmt.Fprintf(d.stdout, "%s%s %s: %s%s\n", prefix, problem.Kind,
+			problem.ID, problem.Message, suffix)
+	}
+	fmt.Fprintf(d.stdout,
+		"%sChecked %d transaction(s), %d NIB(s) and %d object(s): %d problem(s), %d repaired\n",
+		prefix, result.Transactions, result.NIBs, result.Objects,
+		len(result.Problems), repaired)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type FsckTests struct {
+	BaseTests
+}
+
+var _ = Suite(&FsckTests{BaseTests{}})
+
+// syncFile adds foo.txt and synchronizes it with the server.
+func (t *FsckTests) syncFile(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+}
+
+// corruptObjects overwrites all locally stored objects.
+func (t *FsckTests) corruptObjects(c *C) int {
+	dir := filepath.Join(".lara", "objects")
+	entries, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	for _, entry := range entries {
+		err = ioutil.WriteFile(filepath.Join(dir, entry.Name()), []byte("corrupt"), 0600)
+		c.Assert(err, IsNil)
+	}
+	return len(entries)
+}
+
+func (t *FsckTests) TestArgs(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"fsck", "foo"}), Equals, 1)
+}
+
+func (t *FsckTests) TestClean(c *C) {
+	t.syncFile(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"fsck"}, 0)
+	c.Assert(strings.Contains(t.out.String(), ": 0 problem(s)"), Equals, true)
+}
+
+func (t *FsckTests) TestBadObjects(c *C) {
+	t.syncFile(c)
+	count := t.corruptObjects(c)
+	t.out.Reset()
+	c.Assert(t.d.run([]string{"fsck"}), Equals, 1)
+	c.Assert(strings.Count(t.out.String(), "bad object"), Equals, count)
+}
+
+func (t *FsckTests) TestRepair(c *C) {
+	t.syncFile(c)
+	count := t.corruptObjects(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"fsck", "--repair"}, 0)
+	c.Assert(strings.Count(t.out.String(), "(repaired)"), Equals, count)
+
+	t.runAndExpectCode(c, []string{"fsck"}, 0)
+	data, err := ioutil.ReadFile("foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "foo")
+}
+
+func (t *FsckTests) TestRepairWithoutServer(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	t.corruptObjects(c)
+	c.Assert(t.d.run([]string{"fsck", "--repair"}), Equals, 1)
+}
@@ -31,7 +31,7 @@ func (d *Dispatcher) clientFor(r *repository.ClientRepository) (*client.Client,
 	if defaultServer.URL == "" {
 		return nil, fmt.Errorf("no default server configured (state)")
 	}
-	privKey, err := r.GetSigningPrivateKey()
+	privKey, err := r.GetDeviceSigningPrivateKey()
 	if err != nil {
 		return nil, fmt.Errorf("unable to get signing private key (%s)", err)
 	}
@@ -44,8 +44,22 @@ func (d *Dispatcher) clientFor(r *repository.ClientRepository) (*client.Client,
 func (d *Dispatcher) clientForState(sc *repository.StateConfig) *client.Client {
 	d.sc = sc
 	defaultServer := sc.DefaultServer
-	return client.New(defaultServer.URL, defaultServer.Fingerprint,
+	c := client.New(defaultServer.URL, defaultServer.Fingerprint,
 		d.confirmFingerprint)
+	d.configureConcurrency(c)
+	return c
+}
+
+// configureConcurrency applies the number of parallel transfers which
+// has been requested by the "jobs" flag to the given client.
+func (d *Dispatcher) configureConcurrency(c *client.Client) {
+	if d.context == nil {
+		return
+	}
+	jobs := d.context.Int("jobs")
+	if jobs > 0 {
+		c.SetConcurrency(jobs)
+	}
 }
 
 // promptPassword outputs the given prompt text and waits for a value to be entered
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/hoffie/larasync/repository"
+	"github.com/hoffie/larasync/repository/chunker"
 )
 
 // initAction initializes a new repository.
@@ -38,5 +39,10 @@ func (d *Dispatcher) initAction() int {
 		fmt.Fprintf(d.stderr, "Unable to generate encryption keys\n")
 		return 1
 	}
+	err = repo.SetChunkerConfig(chunker.DefaultConfig())
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Unable to store chunker configuration\n")
+		return 1
+	}
 	return 0
 }
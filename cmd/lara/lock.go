@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hoffie/larasync/helpers/lock"
+	"github.com/hoffie/larasync/repository"
+)
+
+// repositoryLockRole names the lock file in the management directory
+// which is held by commands working on the repository.
+const repositoryLockRole = "repository"
+
+// repositoryLockTimeout is how long a command waits for other lara
+// processes to release the repository.
+var repositoryLockTimeout = time.Minute
+
+// lockRepository takes the lock of the given repository which protects it
+// against other lara processes, such as a running "lara watch". Commands
+// which change the repository take it exclusively, commands which only
+// read take it shared. Actions which are run from other actions of this
+// dispatcher use the lock which is held already.
+// It returns the function which releases the lock.
+func (d *Dispatcher) lockRepository(r *repository.ClientRepository, exclusive bool) (func(), error) {
+	managementDir, err := filepath.Abs(r.GetManagementDir())
+	if err != nil {
+		return nil, err
+	}
+	if d.lockedRepository == managementDir {
+		return func() {}, nil
+	}
+
+	l := lock.CurrentFileManager().GetFileLock(managementDir, repositoryLockRole)
+	take, release := l.RLockTimeout, l.RUnlock
+	if exclusive {
+		take, release = l.LockTimeout, l.Unlock
+	}
+	err = take(0)
+	if err == lock.ErrTimeout {
+		fmt.Fprintln(d.stderr, "Waiting for another lara process to release the repository...")
+		err = take(repositoryLockTimeout)
+	}
+	if err == lock.ErrTimeout {
+		return nil, fmt.Errorf("the repository is in use by another lara process")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock the repository (%s)", err)
+	}
+	d.lockedRepository = managementDir
+	return func() {
+		d.lockedRepository = ""
+		release()
+	}, nil
+}
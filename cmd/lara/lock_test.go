@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/helpers/lock"
+)
+
+type LockTests struct {
+	BaseTests
+	// other holds the repository lock like another lara process
+	other *lock.FileLock
+}
+
+var _ = Suite(&LockTests{BaseTests: BaseTests{}})
+
+func (t *LockTests) SetUpTest(c *C) {
+	t.BaseTests.SetUpTest(c)
+	t.initRepo(c)
+	managementDir, err := filepath.Abs(".lara")
+	c.Assert(err, IsNil)
+	t.other = lock.NewFileManager().GetFileLock(managementDir, repositoryLockRole)
+	err = ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+}
+
+func (t *LockTests) TearDownTest(c *C) {
+	repositoryLockTimeout = time.Minute
+	t.BaseTests.TearDownTest(c)
+}
+
+func (t *LockTests) stderr(c *C) string {
+	data, err := ioutil.ReadAll(t.err)
+	c.Assert(err, IsNil)
+	return string(data)
+}
+
+func (t *LockTests) TestWaitForOtherProcess(c *C) {
+	t.other.Lock()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		t.other.Unlock()
+	}()
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	c.Assert(strings.Contains(t.stderr(c), "Waiting for another lara process"), Equals, true)
+}
+
+func (t *LockTests) TestTimeout(c *C) {
+	repositoryLockTimeout = 20 * time.Millisecond
+	t.other.Lock()
+	defer t.other.Unlock()
+	c.Assert(t.d.run([]string{"add", "foo.txt"}), Equals, 1)
+	c.Assert(strings.Contains(t.stderr(c), "in use by another lara process"), Equals, true)
+}
+
+func (t *LockTests) TestSharedForReading(c *C) {
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	repositoryLockTimeout = 20 * time.Millisecond
+	t.other.RLock()
+	defer t.other.RUnlock()
+	t.runAndExpectCode(c, []string{"log", "foo.txt"}, 0)
+	c.Assert(t.d.run([]string{"add", "foo.txt"}), Equals, 1)
+}
+
+func (t *LockTests) TestStatusQueryingServerIsExclusive(c *C) {
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	repositoryLockTimeout = 20 * time.Millisecond
+	t.other.RLock()
+	defer t.other.RUnlock()
+	t.runAndExpectCode(c, []string{"status", "--offline"}, 0)
+	c.Assert(t.d.run([]string{"status"}), Equals, 1)
+	c.Assert(strings.Contains(t.stderr(c), "in use by another lara process"), Equals, true)
+}
+
+func (t *LockTests) TestReleased(c *C) {
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	c.Assert(t.other.LockTimeout(0), IsNil)
+	t.other.Unlock()
+}
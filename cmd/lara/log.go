@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+const (
+	// timeFormat is used when displaying and parsing local times.
+	timeFormat = "2006-01-02 15:04:05"
+)
+
+// logAction implements the "lara log" command.
+func (d *Dispatcher) logAction() int {
+	if len(d.context.Args()) != 1 {
+		fmt.Fprint(d.stderr, "Error: exactly one path has to be specified\n")
+		return 1
+	}
+	absPath, root, err := d.parseFirstPathArg()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, false)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+	history, err := r.History(absPath)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to read the history of the path (%s)\n", err)
+		return 1
+	}
+
+	// newest revisions first
+	for i := len(history) - 1; i >= 0; i-- {
+		rev := history[i]
+		device := rev.DeviceID
+		if device == "" {
+			device = "unknown device"
+		}
+		size := fmt.Sprintf("%d bytes", rev.Size)
+		if rev.Deleted {
+			size = "deleted"
+		} else if rev.Size < 0 {
+			size = "size unknown"
+		}
+		fmt.Fprintf(d.stdout, "revision %-4d %s  %-20s %s\n", rev.Number,
+			time.Unix(rev.UTCTimestamp, 0).Format(timeFormat), device, size)
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type LogTests struct {
+	BaseTests
+}
+
+var _ = Suite(&LogTests{BaseTests: BaseTests{}})
+
+func (t *LogTests) TestNoPath(c *C) {
+	c.Assert(t.d.run([]string{"log"}), Equals, 1)
+}
+
+func (t *LogTests) TestUnknownPath(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"log", "foo.txt"}), Equals, 1)
+}
+
+func (t *LogTests) TestLog(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	err = ioutil.WriteFile("foo.txt", []byte("foobar"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+
+	t.runAndExpectCode(c, []string{"log", "foo.txt"}, 0)
+	lines := strings.Split(strings.TrimSpace(t.out.String()), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(strings.HasPrefix(lines[0], "revision 2"), Equals, true)
+	c.Assert(strings.HasSuffix(lines[0], "6 bytes"), Equals, true)
+	c.Assert(strings.HasPrefix(lines[1], "revision 1"), Equals, true)
+	c.Assert(strings.HasSuffix(lines[1], "3 bytes"), Equals, true)
+}
@@ -38,6 +38,9 @@ type Dispatcher struct {
 	sc            *repository.StateConfig
 	serverCfgPath string
 	exitCode      int
+	// lockedRepository is the management directory of the repository
+	// whose lock is held by the running action.
+	lockedRepository string
 }
 
 // initApp initializes the app structure.
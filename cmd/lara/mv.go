@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// mvAction implements the "lara mv" command.
+func (d *Dispatcher) mvAction() int {
+	args := d.context.Args()
+	if len(args) != 2 {
+		fmt.Fprint(d.stderr, "Error: a source and a target path have to be specified\n")
+		return 1
+	}
+	srcPath, root, err := d.parseFirstPathArg()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	dstPath, err := filepath.Abs(args[1])
+	if err != nil {
+		fmt.Fprint(d.stderr, "Error: unable to resolve target path\n")
+		return 1
+	}
+	stat, err := os.Stat(dstPath)
+	if err == nil && stat.IsDir() {
+		dstPath = filepath.Join(dstPath, filepath.Base(srcPath))
+	}
+	if !strings.HasPrefix(dstPath, root+string(filepath.Separator)) {
+		fmt.Fprint(d.stderr, "Error: the target path is not part of the repository\n")
+		return 1
+	}
+
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+	err = r.Move(srcPath, dstPath)
+	if err == repository.ErrMoveTargetExists {
+		fmt.Fprint(d.stderr, "Error: the target path exists already\n")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to move the path (%s)\n", err)
+		return 1
+	}
+	return 0
+}
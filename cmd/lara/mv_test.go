@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type MvTests struct {
+	BaseTests
+}
+
+var _ = Suite(&MvTests{BaseTests{}})
+
+func (t *MvTests) TestArgs(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"mv", "foo"}), Equals, 1)
+}
+
+func (t *MvTests) TestMove(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+
+	t.runAndExpectCode(c, []string{"mv", "foo.txt", "bar.txt"}, 0)
+	_, err = os.Stat("foo.txt")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	data, err := ioutil.ReadFile("bar.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "foo")
+}
+
+func (t *MvTests) TestMoveIntoDirectory(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	err = os.Mkdir("dir", 0700)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "."}, 0)
+
+	t.runAndExpectCode(c, []string{"mv", "foo.txt", "dir"}, 0)
+	_, err = os.Stat(filepath.Join("dir", "foo.txt"))
+	c.Assert(err, IsNil)
+}
+
+func (t *MvTests) TestTargetExists(c *C) {
+	t.initRepo(c)
+	for _, name := range []string{"foo.txt", "bar.txt"} {
+		err := ioutil.WriteFile(name, []byte(name), 0600)
+		c.Assert(err, IsNil)
+	}
+	t.runAndExpectCode(c, []string{"add", "."}, 0)
+
+	c.Assert(t.d.run([]string{"mv", "foo.txt", "bar.txt"}), Equals, 1)
+	data, err := ioutil.ReadFile("bar.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "bar.txt")
+}
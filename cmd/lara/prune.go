@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// pruneKeepFlags lists the flags which define a retention policy.
+var pruneKeepFlags = []string{"keep-last", "keep-days", "keep-daily", "keep-weekly"}
+
+// pruneKeepFlagsHint is displayed if a retention policy is missing.
+const pruneKeepFlagsHint = "--keep-last, --keep-days, --keep-daily or --keep-weekly"
+
+// pruneAction implements the "lara prune" command.
+func (d *Dispatcher) pruneAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+
+	policy, err := d.retentionPolicy(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	if d.context.Bool("save") {
+		err = r.SetRetentionPolicy(policy)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to store the retention policy (%s)\n", err)
+			return 1
+		}
+	}
+
+	result, err := r.Prune(policy, time.Now())
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to prune the history (%s)\n", err)
+		return 1
+	}
+	fmt.Fprintf(d.stdout, "Removed %d revision(s) of %d item(s)\n",
+		result.Revisions, result.Items)
+	return 0
+}
+
+// retentionPolicy returns the policy given on the command line or the
+// configured one if no policy flags have been passed.
+func (d *Dispatcher) retentionPolicy(r *repository.ClientRepository) (*repository.RetentionPolicy, error) {
+	for _, flag := range pruneKeepFlags {
+		if d.context.IsSet(flag) {
+			return &repository.RetentionPolicy{
+				KeepLast:   d.context.Int("keep-last"),
+				KeepDays:   d.context.Int("keep-days"),
+				KeepDaily:  d.context.Int("keep-daily"),
+				KeepWeekly: d.context.Int("keep-weekly"),
+			}, nil
+		}
+	}
+	if d.context.Bool("save") {
+		return nil, fmt.Errorf("--save requires %s", pruneKeepFlagsHint)
+	}
+	policy, err := r.RetentionPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the retention policy (%s)", err)
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("no retention policy configured; pass %s",
+			pruneKeepFlagsHint)
+	}
+	return policy, nil
+}
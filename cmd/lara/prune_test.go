@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+type PruneTests struct {
+	BaseTests
+}
+
+var _ = Suite(&PruneTests{BaseTests: BaseTests{}})
+
+// addRevisions adds the given number of revisions of foo.txt.
+func (t *PruneTests) addRevisions(c *C, count int) {
+	t.initRepo(c)
+	for i := 0; i < count; i++ {
+		err := ioutil.WriteFile("foo.txt", []byte(strings.Repeat("x", i+1)), 0600)
+		c.Assert(err, IsNil)
+		t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	}
+}
+
+func (t *PruneTests) TestNoPolicy(c *C) {
+	t.addRevisions(c, 2)
+	t.runAndExpectCode(c, []string{"prune"}, 1)
+}
+
+func (t *PruneTests) TestSaveWithoutPolicy(c *C) {
+	t.addRevisions(c, 2)
+	t.runAndExpectCode(c, []string{"prune", "--save"}, 1)
+}
+
+func (t *PruneTests) TestKeepLast(c *C) {
+	t.addRevisions(c, 3)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"prune", "--keep-last", "2"}, 0)
+	c.Assert(t.out.String(), Equals, "Removed 1 revision(s) of 1 item(s)\n")
+
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"log", "foo.txt"}, 0)
+	c.Assert(strings.Count(t.out.String(), "\n"), Equals, 2)
+}
+
+func (t *PruneTests) TestSave(c *C) {
+	t.addRevisions(c, 3)
+	t.runAndExpectCode(c, []string{"prune", "--keep-last", "2", "--save"}, 0)
+
+	root, err := os.Getwd()
+	c.Assert(err, IsNil)
+	policy, err := repository.NewClient(root).RetentionPolicy()
+	c.Assert(err, IsNil)
+	c.Assert(policy, DeepEquals, &repository.RetentionPolicy{KeepLast: 2})
+
+	err = ioutil.WriteFile("foo.txt", []byte("new"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"prune"}, 0)
+	c.Assert(t.out.String(), Equals, "Removed 1 revision(s) of 1 item(s)\n")
+}
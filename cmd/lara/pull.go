@@ -17,6 +17,12 @@ func (d *Dispatcher) pullAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	client, err := d.clientFor(r)
 	if err != nil {
 		fmt.Fprint(d.stderr, err)
@@ -35,5 +41,9 @@ func (d *Dispatcher) pullAction() int {
 		fmt.Fprintf(d.stderr, "Error: pull failed (%s)\n", err)
 		return 1
 	}
+	err = evictObjects(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Warning: unable to evict objects (%s)\n", err)
+	}
 	return 0
 }
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository"
 )
 
 type PullTests struct {
@@ -52,12 +54,26 @@ func (t *PullTests) initializeRepository(c *C) {
 
 	err = removeFilesInDir(filepath.Join(".lara", "nibs"))
 	c.Assert(err, IsNil)
+	t.forgetRemoteTransaction(c)
 
 	t.runAndExpectCode(c, []string{"checkout"}, 0)
 	_, err = os.Stat(t.testFile)
 	c.Assert(os.IsNotExist(err), Equals, true)
 }
 
+// forgetRemoteTransaction resets the server transaction which has been
+// recorded by the push, so that the pushed NIBs appear to come from
+// another device.
+func (t *PullTests) forgetRemoteTransaction(c *C) {
+	scPath := filepath.Join(".lara", "state.json")
+	sc := &repository.StateConfig{Path: scPath}
+	err := sc.Load()
+	c.Assert(err, IsNil)
+	sc.DefaultServer.RemoteTransactionID = 0
+	err = sc.Save()
+	c.Assert(err, IsNil)
+}
+
 func (t *PullTests) verifyExpectedDataStructure(c *C) {
 	_, err := os.Stat(t.testFile)
 	c.Assert(os.IsNotExist(err), Equals, true)
@@ -17,6 +17,12 @@ func (d *Dispatcher) pushAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 
 	client, err := d.clientFor(r)
 	if err != nil {
@@ -40,5 +46,12 @@ func (d *Dispatcher) pushAction() int {
 		return 1
 	}
 
+	// the uploader pulls changes which arrived in the meantime
+	err = r.CheckoutNIBs(ul.ReceivedNIBIDs())
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: checkout failed (%s)\n", err)
+		return 1
+	}
+
 	return 0
 }
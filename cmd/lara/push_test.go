@@ -90,3 +90,38 @@ func (t *PushTests) TestDoublePush(c *C) {
 	t.runAndExpectCode(c, []string{"push"}, 0)
 	t.verifyRepository(c)
 }
+
+func (t *PushTests) TestPushChecksOutPulledChanges(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	t.runAndExpectCode(c, []string{"authorize-new-client"}, 0)
+	url := authURLRegex.FindString(t.out.String())
+	err := ioutil.WriteFile("foo.txt", []byte("mine"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+
+	clonePath := filepath.Join(t.dir, "clone")
+	t.runAndExpectCode(c, []string{"clone", url, clonePath}, 0)
+	err = os.Chdir(clonePath)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile("foo.txt", []byte("theirs"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+
+	// the server has moved on, so pushing pulls the remote change first
+	err = os.Chdir(filepath.Join(t.dir, "repo"))
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile("bar.txt", []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "bar.txt"}, 0)
+	t.runAndExpectCode(c, []string{"push"}, 0)
+	content, err := ioutil.ReadFile("foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "theirs")
+
+	// a later sync must not revert the remote change
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+	content, err = ioutil.ReadFile("foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "theirs")
+}
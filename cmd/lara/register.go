@@ -28,6 +28,12 @@ func (d *Dispatcher) registerAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	pubKey, err := r.GetSigningPublicKey()
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Error: unable to retrieve local signing public key\n")
@@ -51,6 +57,11 @@ func (d *Dispatcher) registerAction() int {
 		fmt.Fprintf(d.stderr, "Error: unable to register (%s)\n", err)
 		return 1
 	}
+	err = client.SyncDeviceRegistry(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to upload the device registry (%s)\n", err)
+		return 1
+	}
 	err = sc.Save()
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Error: unable to save repo state (%s)\n", err)
@@ -24,6 +24,12 @@ func (d *Dispatcher) resetFingerprintAction() int {
 		return 0
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	sc, err := r.StateConfig()
 	if err != nil {
 		fmt.Fprintf(d.stderr, "Error: Unable to load state config (%s)\n", err)
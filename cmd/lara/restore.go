@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// restoreAction implements the "lara restore" command.
+func (d *Dispatcher) restoreAction() int {
+	if len(d.context.Args()) != 1 {
+		fmt.Fprint(d.stderr, "Error: exactly one path has to be specified\n")
+		return 1
+	}
+	absPath, root, err := d.parseFirstPathArg()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+
+	target := ""
+	if d.context.String("to") != "" {
+		target, err = filepath.Abs(d.context.String("to"))
+		if err != nil {
+			fmt.Fprint(d.stderr, "Error: unable to resolve target path\n")
+			return 1
+		}
+	}
+
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+	err = d.enableObjectFetching(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Warning: evicted objects cannot be fetched (%s)\n", err)
+	}
+	revision := d.context.Int("revision")
+	at := d.context.String("at")
+	switch {
+	case revision > 0 && at != "":
+		err = errors.New("--revision and --at are mutually exclusive")
+	case revision > 0:
+		err = r.RestoreRevision(absPath, int64(revision), target)
+	case at != "":
+		var t time.Time
+		t, err = parseTime(at)
+		if err == nil {
+			err = r.RestoreAt(absPath, t, target)
+		}
+	default:
+		err = errors.New("either --revision or --at has to be specified")
+	}
+	if err == repository.ErrWorkDirConflict {
+		fmt.Fprint(d.stderr,
+			"Error: the path has changes which have not been added yet\n")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to restore the path (%s)\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseTime parses the given time in one of the supported formats.
+// Times without time zone are interpreted in the local time zone.
+func parseTime(value string) (time.Time, error) {
+	layouts := []string{time.RFC3339, timeFormat, "2006-01-02 15:04", "2006-01-02"}
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time %q (use the format %q)",
+		value, timeFormat)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type RestoreTests struct {
+	BaseTests
+}
+
+var _ = Suite(&RestoreTests{BaseTests: BaseTests{}})
+
+// addTwoRevisions adds foo.txt with the content "first" and "second".
+func (t *RestoreTests) addTwoRevisions(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("first"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	err = ioutil.WriteFile("foo.txt", []byte("second"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+}
+
+func (t *RestoreTests) readFile(c *C, path string) string {
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	return string(data)
+}
+
+func (t *RestoreTests) TestNoRevision(c *C) {
+	t.addTwoRevisions(c)
+	c.Assert(t.d.run([]string{"restore", "foo.txt"}), Equals, 1)
+}
+
+func (t *RestoreTests) TestRevisionAndTime(c *C) {
+	t.addTwoRevisions(c)
+	c.Assert(t.d.run([]string{"restore", "--revision", "1",
+		"--at", "2015-01-01", "foo.txt"}), Equals, 1)
+}
+
+func (t *RestoreTests) TestRevision(c *C) {
+	t.addTwoRevisions(c)
+	t.runAndExpectCode(c, []string{"restore", "--revision", "1", "foo.txt"}, 0)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "first")
+}
+
+func (t *RestoreTests) TestRevisionTo(c *C) {
+	t.addTwoRevisions(c)
+	t.runAndExpectCode(c, []string{"restore", "--revision", "1",
+		"--to", "old.txt", "foo.txt"}, 0)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "second")
+	c.Assert(t.readFile(c, "old.txt"), Equals, "first")
+}
+
+func (t *RestoreTests) TestUnknownRevision(c *C) {
+	t.addTwoRevisions(c)
+	c.Assert(t.d.run([]string{"restore", "--revision", "3", "foo.txt"}), Equals, 1)
+}
+
+func (t *RestoreTests) TestWorkDirConflict(c *C) {
+	t.addTwoRevisions(c)
+	err := ioutil.WriteFile("foo.txt", []byte("unsaved"), 0600)
+	c.Assert(err, IsNil)
+	c.Assert(t.d.run([]string{"restore", "--revision", "1", "foo.txt"}), Equals, 1)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "unsaved")
+}
+
+func (t *RestoreTests) TestDirectoryAt(c *C) {
+	t.initRepo(c)
+	err := os.Mkdir("dir", 0700)
+	c.Assert(err, IsNil)
+	path := filepath.Join("dir", "foo.txt")
+	err = ioutil.WriteFile(path, []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "dir"}, 0)
+	err = os.RemoveAll("dir")
+	c.Assert(err, IsNil)
+
+	at := time.Now().Add(time.Minute).Format(timeFormat)
+	t.runAndExpectCode(c, []string{"restore", "--at", at, "dir"}, 0)
+	c.Assert(t.readFile(c, path), Equals, "foo")
+}
+
+func (t *RestoreTests) TestParseTime(c *C) {
+	exp := time.Date(2015, 3, 4, 5, 6, 7, 0, time.Local)
+	parsed, err := parseTime("2015-03-04 05:06:07")
+	c.Assert(err, IsNil)
+	c.Assert(parsed.Equal(exp), Equals, true)
+
+	parsed, err = parseTime("2015-03-04")
+	c.Assert(err, IsNil)
+	c.Assert(parsed.Equal(time.Date(2015, 3, 4, 0, 0, 0, 0, time.Local)), Equals, true)
+
+	_, err = parseTime("yesterday")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// revokeDeviceAction implements "lara revoke-device DEVICE"
+func (d *Dispatcher) revokeDeviceAction() int {
+	args := d.context.Args()
+	if len(args) != 1 {
+		fmt.Fprint(d.stderr,
+			"Error: please specify the ID or name of the device\n"+
+				"\te.g. lara revoke-device 0123456789abcdef\n")
+		return 1
+	}
+
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+	client, err := d.clientFor(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	err = client.SyncDeviceRegistry(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to synchronize the device registry (%s)\n", err)
+		return 1
+	}
+	registry, err := r.DeviceRegistry()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to read the device registry (%s)\n", err)
+		return 1
+	}
+	device, err := findDevice(registry, args[0])
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+
+	skipped, err := client.RevokeDevice(r, device.ID())
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to revoke the device (%s)\n", err)
+		return 1
+	}
+	for _, other := range skipped {
+		fmt.Fprintf(d.stderr,
+			"Warning: the new keys could not be handed to device %s (%s); "+
+				"it has to be authorized again\n",
+			other.ID()[:deviceIDListLength], other.Name)
+	}
+	fmt.Fprintf(d.stdout, "Revoked device %s; the repository keys have been replaced\n",
+		device.ID()[:deviceIDListLength])
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RevokeDeviceTests struct {
+	BaseTests
+}
+
+var _ = Suite(&RevokeDeviceTests{BaseTests{}})
+
+func (t *RevokeDeviceTests) TestNoArgs(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	c.Assert(t.d.run([]string{"revoke-device"}), Equals, 1)
+}
+
+func (t *RevokeDeviceTests) TestUnknownDevice(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	c.Assert(t.d.run([]string{"revoke-device", "unknown"}), Equals, 1)
+}
+
+func (t *RevokeDeviceTests) TestRevokeOwnDevice(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"devices"}, 0)
+	id := strings.Fields(t.out.String())[0]
+	c.Assert(t.d.run([]string{"revoke-device", id}), Equals, 1)
+}
+
+func (t *RevokeDeviceTests) TestRevokeDevice(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"devices"}, 0)
+	revokedID := strings.Fields(t.out.String())[0]
+	t.runAndExpectCode(c, []string{"authorize-new-client"}, 0)
+	url := authURLRegex.FindString(t.out.String())
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+
+	clonePath := filepath.Join(t.dir, "clone")
+	t.runAndExpectCode(c, []string{"clone", url, clonePath}, 0)
+	err = os.Chdir(clonePath)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"revoke-device", revokedID}, 0)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"devices"}, 0)
+	lines := strings.Split(strings.TrimSpace(t.out.String()), "\n")
+	c.Assert(lines, HasLen, 1)
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+	content, err := ioutil.ReadFile("foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "foo")
+
+	err = os.Chdir(filepath.Join(t.dir, "repo"))
+	c.Assert(err, IsNil)
+	c.Assert(t.d.run([]string{"sync"}), Equals, 1)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hoffie/larasync/helpers/lock"
+	"github.com/hoffie/larasync/repository"
+)
+
+// serverGCAction implements "lara server gc [NAME...]"; it collects the
+// garbage of the given repositories or of all repositories of the server.
+// A running server is coordinated with through the storage lock of each
+// repository.
+func (d *Dispatcher) serverGCAction() int {
+	cfg, err := d.loadServerConfig()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to load server config (%s)\n", err)
+		return 1
+	}
+	rm, err := repository.NewManager(cfg.Repository.BasePath)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to open the repositories (%s)\n", err)
+		return 1
+	}
+	names := []string(d.context.Args())
+	if len(names) == 0 {
+		names, err = rm.ListNames()
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to list the repositories (%s)\n", err)
+			return 1
+		}
+	}
+
+	dryRun := d.context.Bool("dry-run")
+	gracePeriod := d.context.Duration("grace-period")
+	exitCode := 0
+	for _, name := range names {
+		r, err := rm.Open(name)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to open repository %s (%s)\n", name, err)
+			exitCode = 1
+			continue
+		}
+		result, err := r.CollectGarbage(gracePeriod, dryRun)
+		if err == lock.ErrTimeout {
+			fmt.Fprintf(d.stderr, "Error: repository %s is busy with uploads, try again later\n", name)
+			exitCode = 1
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to collect garbage in %s (%s)\n", name, err)
+			exitCode = 1
+			continue
+		}
+		d.printGCResult(name, result, dryRun)
+	}
+	return exitCode
+}
+
+// printGCResult outputs a summary of the given result; dry runs
+// additionally list the objects which would be removed.
+func (d *Dispatcher) printGCResult(name string, result *repository.GCResult, dryRun bool) {
+	verb := "removed"
+	if dryRun {
+		verb = "would remove"
+		for _, id := range result.Unreferenced {
+			fmt.Fprintf(d.stdout, "%s: %s\n", name, id)
+		}
+	}
+	fmt.Fprintf(d.stdout,
+		"%s: %d referenced, %s %d unreferenced (%d bytes), kept %d recent\n",
+		name, result.Referenced, verb, len(result.Unreferenced), result.Size,
+		result.Recent)
+}
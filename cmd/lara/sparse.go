@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// sparseAction implements the "lara sparse" command.
+func (d *Dispatcher) sparseAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+
+	include := d.context.StringSlice("include")
+	exclude := d.context.StringSlice("exclude")
+	if d.context.Bool("off") && (len(include) > 0 || len(exclude) > 0) {
+		fmt.Fprint(d.stderr, "Error: --off cannot be combined with patterns\n")
+		return 1
+	}
+
+	if d.context.Bool("off") {
+		err = r.SetSparseConfig(nil)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to store the configuration (%s)\n", err)
+			return 1
+		}
+		fmt.Fprint(d.stdout, "All items are selected; run \"lara sync --full\" "+
+			"to download and check out the previously skipped items\n")
+		return 0
+	}
+
+	if len(include) == 0 && len(exclude) == 0 {
+		config, err := r.SparseConfig()
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to read the configuration (%s)\n", err)
+			return 1
+		}
+		printSparseConfig(d, config)
+		return 0
+	}
+
+	err = r.SetSparseConfig(&repository.SparseConfig{
+		Include: include,
+		Exclude: exclude,
+	})
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to store the configuration (%s)\n", err)
+		return 1
+	}
+	fmt.Fprint(d.stdout, "Selection stored; run \"lara sync --full\" "+
+		"to download and check out newly selected items\n")
+	return 0
+}
+
+// printSparseConfig outputs the given selection.
+func printSparseConfig(d *Dispatcher, config *repository.SparseConfig) {
+	if config == nil {
+		fmt.Fprint(d.stdout, "All items are selected\n")
+		return
+	}
+	if len(config.Include) > 0 {
+		fmt.Fprintf(d.stdout, "include: %s\n", strings.Join(config.Include, ", "))
+	}
+	if len(config.Exclude) > 0 {
+		fmt.Fprintf(d.stdout, "exclude: %s\n", strings.Join(config.Exclude, ", "))
+	}
+}
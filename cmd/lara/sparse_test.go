@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SparseTests struct {
+	BaseTests
+}
+
+var _ = Suite(&SparseTests{BaseTests{}})
+
+func (t *SparseTests) TestArgs(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"sparse", "foo"}), Equals, 1)
+}
+
+func (t *SparseTests) TestShowDefault(c *C) {
+	t.initRepo(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"sparse"}, 0)
+	c.Assert(t.out.String(), Equals, "All items are selected\n")
+}
+
+func (t *SparseTests) TestSetAndShow(c *C) {
+	t.initRepo(c)
+	t.runAndExpectCode(c, []string{"sparse", "--include", "docs",
+		"--include", "*.txt", "--exclude", "docs/private"}, 0)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"sparse"}, 0)
+	c.Assert(t.out.String(), Equals,
+		"include: docs, *.txt\nexclude: docs/private\n")
+
+	t.runAndExpectCode(c, []string{"sparse", "--off"}, 0)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"sparse"}, 0)
+	c.Assert(t.out.String(), Equals, "All items are selected\n")
+}
+
+func (t *SparseTests) TestOffWithPatterns(c *C) {
+	t.initRepo(c)
+	t.runAndExpectCode(c, []string{"sparse", "--off", "--include", "docs"}, 1)
+}
+
+func (t *SparseTests) TestInvalidPattern(c *C) {
+	t.initRepo(c)
+	t.runAndExpectCode(c, []string{"sparse", "--include", "["}, 1)
+}
+
+func (t *SparseTests) TestStatus(c *C) {
+	t.initRepo(c)
+	for _, name := range []string{"foo.txt", "bar.jpg"} {
+		err := ioutil.WriteFile(name, []byte(name), 0600)
+		c.Assert(err, IsNil)
+	}
+	t.runAndExpectCode(c, []string{"sparse", "--exclude", "*.jpg"}, 0)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"status", "--offline"}, 0)
+	c.Assert(strings.Contains(t.out.String(), "foo.txt"), Equals, true)
+	c.Assert(strings.Contains(t.out.String(), "bar.jpg"), Equals, false)
+}
+
+func (t *SparseTests) TestSyncSkipsUnselected(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	for _, name := range []string{"foo.txt", "bar.jpg"} {
+		err := ioutil.WriteFile(name, []byte(name), 0600)
+		c.Assert(err, IsNil)
+	}
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+
+	t.runAndExpectCode(c, []string{"sparse", "--exclude", "*.jpg"}, 0)
+	for _, name := range []string{"foo.txt", "bar.jpg"} {
+		err := os.Remove(name)
+		c.Assert(err, IsNil)
+	}
+	t.runAndExpectCode(c, []string{"sync", "--full"}, 0)
+	_, err := os.Stat("foo.txt")
+	c.Assert(err, IsNil)
+	_, err = os.Stat("bar.jpg")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hoffie/larasync/repository"
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// statusAction implements the "lara status" command.
+func (d *Dispatcher) statusAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	// querying the server synchronizes the device registry, which may
+	// change the repository.
+	offline := d.context.Bool("offline")
+	unlock, err := d.lockRepository(r, !offline)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+
+	var remoteNIBs []*nib.NIB
+	remoteKnown := false
+	if !offline {
+		remoteNIBs, err = d.remoteChanges(r)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Warning: unable to query the server state (%s)\n", err)
+		} else {
+			remoteKnown = true
+		}
+	}
+
+	status, err := r.Status(remoteNIBs)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to determine the status (%s)\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(d.stdout, "%d local transaction(s) not pushed to the server\n",
+		status.UnpushedTransactions)
+	if remoteKnown && status.RemoteChanges > 0 {
+		fmt.Fprintf(d.stdout, "The server has %d changed item(s) not pulled yet\n",
+			status.RemoteChanges)
+	} else if remoteKnown {
+		fmt.Fprint(d.stdout, "The server has no changes which have not been pulled\n")
+	}
+
+	all := d.context.Bool("all")
+	for _, file := range status.Files {
+		if file.State == repository.FileClean && !all {
+			continue
+		}
+		fmt.Fprintf(d.stdout, "%-12s %s\n", file.State.String()+":", file.Path)
+	}
+	return 0
+}
+
+// remoteChanges returns the NIBs which have been changed on the server
+// since the last download. The device registry is synchronized first, so
+// the repository has to be locked exclusively.
+func (d *Dispatcher) remoteChanges(r *repository.ClientRepository) ([]*nib.NIB, error) {
+	client, err := d.clientFor(r)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := r.StateConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = client.SyncDeviceRegistry(r)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.GetNIBsFromTransactionID(sc.DefaultServer.RemoteTransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	nibs := []*nib.NIB{}
+	for nibBytes := range response.NIBData {
+		n, err := r.VerifyAndParseNIBBytes(nibBytes)
+		if err != nil {
+			return nil, err
+		}
+		nibs = append(nibs, n)
+	}
+	err = response.Err()
+	if err != nil {
+		return nil, err
+	}
+	return nibs, nil
+}
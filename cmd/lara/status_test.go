@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type StatusTests struct {
+	BaseTests
+}
+
+var _ = Suite(&StatusTests{BaseTests: BaseTests{}})
+
+func (t *StatusTests) TestTooManyArgs(c *C) {
+	c.Assert(t.d.run([]string{"status", "foo"}), Equals, 1)
+}
+
+func (t *StatusTests) TestOffline(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"status", "--offline"}, 0)
+	c.Assert(strings.Contains(t.out.String(), "new:"), Equals, true)
+	c.Assert(strings.Contains(t.out.String(), "foo.txt"), Equals, true)
+}
+
+func (t *StatusTests) TestWithServer(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	err := ioutil.WriteFile("foo.txt", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	t.runAndExpectCode(c, []string{"push"}, 0)
+
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"status", "--all"}, 0)
+	out := t.out.String()
+	c.Assert(strings.Contains(out, "0 local transaction(s) not pushed"), Equals, true)
+	c.Assert(strings.Contains(out, "no changes which have not been pulled"), Equals, true)
+	c.Assert(strings.Contains(out, "clean:"), Equals, true)
+}
+
+func (t *StatusTests) TestIgnored(c *C) {
+	t.initRepo(c)
+	err := ioutil.WriteFile("foo.log", []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	t.runAndExpectCode(c, []string{"add", "foo.log"}, 0)
+	err = ioutil.WriteFile(".laraignore", []byte("*.log\n"), 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile("bar.log", []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"status", "--offline"}, 0)
+	out := t.out.String()
+	c.Assert(strings.Contains(out, "ignored:     foo.log"), Equals, true)
+	c.Assert(strings.Contains(out, "bar.log"), Equals, false)
+	t.runAndExpectCode(c, []string{"add", "bar.log"}, 1)
+}
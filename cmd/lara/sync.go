@@ -18,6 +18,12 @@ func (d *Dispatcher) syncAction() int {
 		return 1
 	}
 	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
 	client, err := d.clientFor(r)
 	if err != nil {
 		fmt.Fprint(d.stderr, err)
@@ -50,5 +56,13 @@ func (d *Dispatcher) syncAction() int {
 		return 1
 	}
 
-	return d.checkoutAllPathsAction()
+	exitCode := d.checkoutAllPathsAction()
+	if exitCode != 0 {
+		return exitCode
+	}
+	err = evictObjects(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Warning: unable to evict objects (%s)\n", err)
+	}
+	return 0
 }
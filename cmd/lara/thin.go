@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+// bytesPerMiB is used to convert the cache size given on the command line.
+const bytesPerMiB = 1024 * 1024
+
+// thinAction implements the "lara thin" command.
+func (d *Dispatcher) thinAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+
+	if d.context.Bool("off") {
+		err = r.SetThinClientConfig(nil)
+		if err != nil {
+			fmt.Fprintf(d.stderr, "Error: unable to store the configuration (%s)\n", err)
+			return 1
+		}
+		fmt.Fprint(d.stdout, "Thin client mode disabled; run \"lara pull --full\" "+
+			"to download all evicted objects\n")
+		return 0
+	}
+
+	err = r.SetThinClientConfig(&repository.ThinClientConfig{
+		CacheSize: int64(d.context.Int("cache-size")) * bytesPerMiB,
+	})
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to store the configuration (%s)\n", err)
+		return 1
+	}
+	result, err := r.EvictObjects()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to evict objects (%s)\n", err)
+		return 1
+	}
+	fmt.Fprintf(d.stdout, "Evicted %d object(s) (%d bytes), %d bytes cached\n",
+		result.Evicted, result.EvictedSize, result.CachedSize)
+	return 0
+}
+
+// evictObjects removes the objects which are no longer needed locally if
+// the repository is a thin client.
+func evictObjects(r *repository.ClientRepository) error {
+	config, err := r.ThinClientConfig()
+	if err != nil || config == nil {
+		return err
+	}
+	_, err = r.EvictObjects()
+	return err
+}
+
+// enableObjectFetching configures thin client repositories to fetch
+// evicted objects from the server.
+func (d *Dispatcher) enableObjectFetching(r *repository.ClientRepository) error {
+	config, err := r.ThinClientConfig()
+	if err != nil || config == nil {
+		return err
+	}
+	client, err := d.clientFor(r)
+	if err != nil {
+		return err
+	}
+	r.SetObjectFetcher(client)
+	return nil
+}
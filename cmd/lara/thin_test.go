@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ThinTests struct {
+	BaseTests
+}
+
+var _ = Suite(&ThinTests{BaseTests{}})
+
+// syncTwoRevisions adds two revisions of foo.txt and synchronizes them
+// with the server.
+func (t *ThinTests) syncTwoRevisions(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	for _, content := range []string{"first", "second"} {
+		err := ioutil.WriteFile("foo.txt", []byte(content), 0600)
+		c.Assert(err, IsNil)
+		t.runAndExpectCode(c, []string{"add", "foo.txt"}, 0)
+	}
+	t.runAndExpectCode(c, []string{"sync"}, 0)
+}
+
+func (t *ThinTests) TestArgs(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"thin", "foo"}), Equals, 1)
+}
+
+func (t *ThinTests) TestEvictAndRestore(c *C) {
+	t.syncTwoRevisions(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"thin"}, 0)
+	c.Assert(strings.HasPrefix(t.out.String(), "Evicted 1 object(s)"), Equals, true)
+
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"log", "foo.txt"}, 0)
+	c.Assert(strings.Contains(t.out.String(), "size unknown"), Equals, true)
+
+	t.runAndExpectCode(c, []string{"restore", "--revision", "1", "foo.txt"}, 0)
+	data, err := ioutil.ReadFile("foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "first")
+}
+
+func (t *ThinTests) TestCacheSize(c *C) {
+	t.syncTwoRevisions(c)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"thin", "--cache-size", "1"}, 0)
+	c.Assert(strings.HasPrefix(t.out.String(), "Evicted 0 object(s)"), Equals, true)
+}
+
+func (t *ThinTests) TestOff(c *C) {
+	t.syncTwoRevisions(c)
+	t.runAndExpectCode(c, []string{"thin"}, 0)
+	t.runAndExpectCode(c, []string{"thin", "--off"}, 0)
+	t.runAndExpectCode(c, []string{"pull", "--full"}, 0)
+	t.out.Reset()
+	t.runAndExpectCode(c, []string{"log", "foo.txt"}, 0)
+	c.Assert(strings.Contains(t.out.String(), "size unknown"), Equals, false)
+}
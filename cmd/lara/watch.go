@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hoffie/larasync/api/client"
+	"github.com/hoffie/larasync/helpers/watcher"
+	"github.com/hoffie/larasync/repository"
+)
+
+// checkoutTmpPrefix is the prefix of temporary files written during
+// checkout; changes to them are not reported.
+const checkoutTmpPrefix = ".lara."
+
+// watchAction implements the "lara watch" command.
+func (d *Dispatcher) watchAction() int {
+	if len(d.context.Args()) != 0 {
+		fmt.Fprint(d.stderr, "Error: this command takes no arguments\n")
+		return 1
+	}
+
+	root, err := d.getRootFromWd()
+	if err != nil {
+		return 1
+	}
+	r := repository.NewClient(root)
+	client, err := d.clientFor(r)
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+		return 1
+	}
+
+	managementDir := r.GetManagementDir()
+	w, err := watcher.New(root, d.context.Duration("debounce"), func(absPath string) bool {
+		if strings.HasPrefix(filepath.Base(absPath), checkoutTmpPrefix) {
+			return true
+		}
+		return absPath == managementDir ||
+			strings.HasPrefix(absPath, managementDir+string(filepath.Separator))
+	})
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to watch the repository (%s)\n", err)
+		return 1
+	}
+	err = w.Start()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to watch the repository (%s)\n", err)
+		return 1
+	}
+	defer w.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	pollFrom := make(chan int64)
+	defer close(pollFrom)
+	polled := make(chan *serverPollResult, 1)
+	go pollServer(client, d.context.Duration("poll-interval"), pollFrom, polled)
+	var transactionID int64
+	requestPoll := func() {
+		transactionID = d.pollTransactionID(r, transactionID)
+		pollFrom <- transactionID
+	}
+
+	d.reportWatchSync(d.watchSync(r, client, []string{root}, nil))
+	requestPoll()
+	for {
+		select {
+		case paths := <-w.Changes:
+			d.reportWatchSync(d.watchSync(r, client, paths, nil))
+		case result := <-polled:
+			d.reportWatchSync(d.watchSync(r, client, nil, result))
+			requestPoll()
+		case err := <-w.Errors:
+			fmt.Fprintf(d.stderr, "Error: watching failed (%s)\n", err)
+		case <-interrupt:
+			return 0
+		}
+	}
+}
+
+// pollTransactionID returns the transaction ID of the server which has
+// been received last. If it cannot be determined, the error is printed
+// and the given last known ID is returned, so that polling goes on.
+func (d *Dispatcher) pollTransactionID(r *repository.ClientRepository, last int64) int64 {
+	sc, err := r.StateConfig()
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: unable to load state config (%s)\n", err)
+		return last
+	}
+	return sc.DefaultServer.RemoteTransactionID
+}
+
+// serverPollResult is the outcome of a single long-poll request.
+type serverPollResult struct {
+	response *client.NIBGetResponse
+	err      error
+}
+
+// pollServer waits for changes on the server after each transaction id
+// received from pollFrom and passes the result to polled. Requests which
+// return early without new data, e.g. due to errors, are delayed to the
+// full wait duration to not poll in a tight loop.
+func pollServer(c *client.Client, wait time.Duration, pollFrom <-chan int64, polled chan<- *serverPollResult) {
+	for transactionID := range pollFrom {
+		started := time.Now()
+		response, err := c.WaitForNIBsFromTransactionID(transactionID, wait)
+		if err != nil || response.ServerTransactionID <= transactionID {
+			time.Sleep(wait - time.Since(started))
+		}
+		polled <- &serverPollResult{response: response, err: err}
+	}
+}
+
+// reportWatchSync prints the error of a failed synchronization cycle;
+// the watch keeps running and retries with the next cycle.
+func (d *Dispatcher) reportWatchSync(err error) {
+	if err != nil {
+		fmt.Fprintf(d.stderr, "Error: %s\n", err)
+	}
+}
+
+// watchSync runs one synchronization cycle: the given changed paths are
+// recorded, the server state is fetched, the local state is pushed and
+// the NIBs which have been received are checked out; thin clients evict
+// the objects which are no longer needed afterwards. If a poll result is
+// passed, its data is used instead of fetching the server state again.
+// The repository is locked during the cycle only, so that other lara
+// commands can run in between.
+func (d *Dispatcher) watchSync(r *repository.ClientRepository, c *client.Client, paths []string, polled *serverPollResult) error {
+	unlock, err := d.lockRepository(r, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// other processes may have synchronized since the last cycle
+	sc, err := r.StateConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load state config (%s)", err)
+	}
+	err = sc.Load()
+	if err != nil {
+		return fmt.Errorf("unable to load state config (%s)", err)
+	}
+
+	for _, absPath := range paths {
+		err := d.recordChange(r, absPath)
+		if err != nil {
+			return fmt.Errorf("adding local changes failed (%s)", err)
+		}
+	}
+
+	dl := c.Downloader(r)
+	if polled == nil {
+		err = dl.GetDelta()
+	} else if polled.err != nil {
+		err = polled.err
+	} else {
+		err = dl.ProcessNIBResponse(polled.response)
+	}
+	if err != nil {
+		return fmt.Errorf("syncing data from server failed (%s)", err)
+	}
+
+	ul := c.Uploader(r)
+	err = ul.PushDelta()
+	if err != nil {
+		return fmt.Errorf("uploading data to the server failed (%s)", err)
+	}
+
+	// the uploader pulls changes which arrived in the meantime
+	err = r.CheckoutNIBs(append(dl.ReceivedNIBIDs(), ul.ReceivedNIBIDs()...))
+	if err != nil {
+		return fmt.Errorf("checkout failed (%s)", err)
+	}
+	err = evictObjects(r)
+	if err != nil {
+		return fmt.Errorf("evicting objects failed (%s)", err)
+	}
+	return nil
+}
+
+// recordChange adds the given path to the repository or records its
+// deletion if it does not exist anymore. Changes of ignored items are
+// not recorded.
+func (d *Dispatcher) recordChange(r *repository.ClientRepository, absPath string) error {
+	if absPath != r.Path {
+		ignored, err := r.IsIgnored(absPath)
+		if err != nil || ignored {
+			return err
+		}
+	}
+	_, err := os.Lstat(absPath)
+	if os.IsNotExist(err) {
+		err = r.DeleteItem(absPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return r.AddItem(absPath)
+}
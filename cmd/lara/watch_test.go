@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository"
+)
+
+type WatchTests struct {
+	BaseTests
+}
+
+var _ = Suite(&WatchTests{BaseTests: BaseTests{}})
+
+func (t *WatchTests) TestTooManyArgs(c *C) {
+	c.Assert(t.d.run([]string{"watch", "foo"}), Equals, 1)
+}
+
+func (t *WatchTests) TestNoServer(c *C) {
+	t.initRepo(c)
+	c.Assert(t.d.run([]string{"watch"}), Equals, 1)
+}
+
+func (t *WatchTests) TestSyncChangedPaths(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	root, err := os.Getwd()
+	c.Assert(err, IsNil)
+	r := repository.NewClient(root)
+	client, err := t.d.clientFor(r)
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(root, "foo.txt")
+	err = ioutil.WriteFile(path, []byte("watched"), 0600)
+	c.Assert(err, IsNil)
+	err = t.d.watchSync(r, client, []string{path}, nil)
+	c.Assert(err, IsNil)
+
+	nibs, err := ioutil.ReadDir(filepath.Join(t.serverRepoPath(), "nibs"))
+	c.Assert(err, IsNil)
+	c.Assert(len(nibs), Equals, 1)
+
+	err = os.Remove(path)
+	c.Assert(err, IsNil)
+	err = t.d.watchSync(r, client, []string{path}, nil)
+	c.Assert(err, IsNil)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *WatchTests) TestIgnoredPaths(c *C) {
+	t.initRepo(c)
+	t.registerServerInRepo(c)
+	root, err := os.Getwd()
+	c.Assert(err, IsNil)
+	r := repository.NewClient(root)
+	client, err := t.d.clientFor(r)
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(filepath.Join(root, ".laraignore"), []byte("*.swp\n"), 0600)
+	c.Assert(err, IsNil)
+	paths := []string{filepath.Join(root, "foo.swp"), filepath.Join(root, "foo.txt")}
+	for _, path := range paths {
+		err = ioutil.WriteFile(path, []byte("data"), 0600)
+		c.Assert(err, IsNil)
+	}
+	err = t.d.watchSync(r, client, paths, nil)
+	c.Assert(err, IsNil)
+
+	nibs, err := ioutil.ReadDir(filepath.Join(t.serverRepoPath(), "nibs"))
+	c.Assert(err, IsNil)
+	c.Assert(len(nibs), Equals, 1)
+}
+
+func (t *WatchTests) TestPollTransactionIDFallsBack(c *C) {
+	t.initRepo(c)
+	root, err := os.Getwd()
+	c.Assert(err, IsNil)
+	r := repository.NewClient(root)
+	c.Assert(t.d.pollTransactionID(r, 7), Equals, int64(0))
+
+	// the state config cannot be read if it is a directory
+	path := filepath.Join(".lara", "state.json")
+	err = os.RemoveAll(path)
+	c.Assert(err, IsNil)
+	err = os.Mkdir(path, 0700)
+	c.Assert(err, IsNil)
+	r = repository.NewClient(root)
+	c.Assert(t.d.pollTransactionID(r, 7), Equals, int64(7))
+	c.Assert(strings.Contains(t.err.String(), "unable to load state config"), Equals, true)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"testing/iotest"
 
 	. "gopkg.in/check.v1"
 )
@@ -116,3 +117,20 @@ func (t *Tests) TestDecodeDataWithEOF(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(chunk, DeepEquals, []byte{'a'})
 }
+
+func (t *Tests) TestDecodePartialReads(c *C) {
+	buf := &bytes.Buffer{}
+	e := NewEncoder(buf)
+	c.Assert(e.WriteChunk([]byte("first")), IsNil)
+	c.Assert(e.WriteChunk([]byte("second")), IsNil)
+
+	d := NewDecoder(iotest.OneByteReader(buf))
+	chunk, err := d.ReadChunk()
+	c.Assert(err, IsNil)
+	c.Assert(string(chunk), Equals, "first")
+	chunk, err = d.ReadChunk()
+	c.Assert(err, IsNil)
+	c.Assert(string(chunk), Equals, "second")
+	_, err = d.ReadChunk()
+	c.Assert(err, Equals, io.EOF)
+}
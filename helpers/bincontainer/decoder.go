@@ -38,11 +38,11 @@ func (e *Decoder) ReadChunk() ([]byte, error) {
 // returns it.
 func (e *Decoder) readData(length uint32) ([]byte, error) {
 	chunk := make([]byte, length)
-	read, err := e.r.Read(chunk)
-	if uint32(read) != length {
+	_, err := io.ReadFull(e.r, chunk)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
 		return nil, ErrIncomplete
 	}
-	if err != nil && err != io.EOF {
+	if err != nil {
 		return nil, err
 	}
 	return chunk, nil
@@ -52,14 +52,11 @@ func (e *Decoder) readData(length uint32) ([]byte, error) {
 // uint32 value.
 func (e *Decoder) readLength() (uint32, error) {
 	binLength := make([]byte, lengthSpecSize)
-	read, err := e.r.Read(binLength)
-	if read == 0 && err == io.EOF {
-		return 0, err
-	}
-	if read != lengthSpecSize {
+	_, err := io.ReadFull(e.r, binLength)
+	if err == io.ErrUnexpectedEOF {
 		return 0, ErrIncomplete
 	}
-	if err != nil && err != io.EOF {
+	if err != nil {
 		return 0, err
 	}
 
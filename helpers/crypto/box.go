@@ -103,3 +103,42 @@ func (b *Box) DecryptContent(enc []byte) ([]byte, error) {
 
 	return content, nil
 }
+
+// PlainTextSize returns the length of the plain text which is contained
+// in a ciphertext of the given length as produced by EncryptWithRandomKey.
+func PlainTextSize(encryptedSize int64) int64 {
+	if encryptedSize < encryptedContentMinSize {
+		return 0
+	}
+	return encryptedSize - encryptedContentMinSize
+}
+
+// Rewrap takes a ciphertext as produced by EncryptWithRandomKey and
+// returns it with its per-file key encrypted by the given new key
+// instead of this Box's key. The content itself is not re-encrypted.
+func (b *Box) Rewrap(enc []byte, newKey [EncryptionKeySize]byte) ([]byte, error) {
+	if len(enc) < encryptedContentMinSize {
+		return nil, errors.New("truncated ciphertext")
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], enc[:nonceSize])
+	l := EncryptionKeySize + secretbox.Overhead
+	encryptedFileKey := enc[nonceSize : nonceSize+l]
+	encryptionKey := b.privateKey
+	var fileKey []byte
+	fileKey, success := secretbox.Open(fileKey, encryptedFileKey, &nonce, &encryptionKey)
+	if !success {
+		return nil, errors.New("file key decryption failed")
+	}
+
+	var newNonce [nonceSize]byte
+	_, err := rand.Read(newNonce[:])
+	if err != nil {
+		return nil, err
+	}
+	out := newNonce[:]
+	out = secretbox.Seal(out, fileKey, &newNonce, &newKey)
+	out = append(out, enc[nonceSize+l:]...)
+	return out, nil
+}
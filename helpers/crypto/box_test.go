@@ -51,3 +51,40 @@ func (t *TestBox) TestDecryptionOtherKey(c *C) {
 
 	c.Assert(err, NotNil)
 }
+
+func (t *TestBox) TestPlainTextSize(c *C) {
+	testData := []byte("This is testdata")
+	encrypted, err := t.getBox().EncryptWithRandomKey(testData)
+	c.Assert(err, IsNil)
+
+	size := PlainTextSize(int64(len(encrypted)))
+	c.Assert(size, Equals, int64(len(testData)))
+}
+
+func (t *TestBox) TestRewrap(c *C) {
+	testData := []byte("This is testdata")
+	encrypted, err := t.getBox().EncryptWithRandomKey(testData)
+	c.Assert(err, IsNil)
+
+	newKey := [EncryptionKeySize]byte{}
+	_, err = rand.Read(newKey[:])
+	c.Assert(err, IsNil)
+	rewrapped, err := t.getBox().Rewrap(encrypted, newKey)
+	c.Assert(err, IsNil)
+	c.Assert(len(rewrapped), Equals, len(encrypted))
+
+	_, err = t.getBox().DecryptContent(rewrapped)
+	c.Assert(err, NotNil)
+	decrypted, err := NewBox(newKey).DecryptContent(rewrapped)
+	c.Assert(err, IsNil)
+	c.Assert(decrypted, DeepEquals, testData)
+}
+
+func (t *TestBox) TestRewrapOtherKey(c *C) {
+	encrypted, err := t.getBox().EncryptWithRandomKey([]byte("This is testdata"))
+	c.Assert(err, IsNil)
+
+	otherKey := [EncryptionKeySize]byte{}
+	_, err = NewBox(otherKey).Rewrap(encrypted, t.encKey)
+	c.Assert(err, NotNil)
+}
@@ -10,6 +10,9 @@ const (
 	// HashingKeySize is the amount of bytes used to initialize
 	// the Hasher for Hashing purposes.
 	HashingKeySize = 32
+	// StringHashSize is the length of the hex encoded hashes returned
+	// by StringHash.
+	StringHashSize = 2 * sha512.Size
 )
 
 // Hasher is a helper which can be used to Hash bytes of data
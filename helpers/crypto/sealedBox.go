@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"code.google.com/p/go.crypto/curve25519"
+	"code.google.com/p/go.crypto/nacl/box"
+)
+
+const (
+	// BoxKeySize is the size of the public and private keys which are
+	// used to encrypt data for a specific recipient.
+	BoxKeySize = 32
+
+	// pre-computed minimal length of a sealed message
+	sealedMinSize = BoxKeySize + nonceSize + box.Overhead
+)
+
+// GenerateBoxKey returns a new random key pair which can be used to
+// receive data encrypted with Seal.
+func GenerateBoxKey() (publicKey, privateKey *[BoxKeySize]byte, err error) {
+	return box.GenerateKey(rand.Reader)
+}
+
+// BoxPublicKey returns the public key which belongs to the given private
+// box key.
+func BoxPublicKey(privateKey [BoxKeySize]byte) [BoxKeySize]byte {
+	var publicKey [BoxKeySize]byte
+	curve25519.ScalarBaseMult(&publicKey, &privateKey)
+	return publicKey
+}
+
+// Seal encrypts the given data so that only the owner of the private key
+// belonging to the given public key is able to decrypt it.
+// The result is prefixed by an ephemeral public key and the nonce.
+func Seal(publicKey [BoxKeySize]byte, data []byte) ([]byte, error) {
+	ephemeralPublic, ephemeralPrivate, err := GenerateBoxKey()
+	if err != nil {
+		return nil, err
+	}
+	var nonce [nonceSize]byte
+	_, err = rand.Read(nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	out := append(ephemeralPublic[:], nonce[:]...)
+	return box.Seal(out, data, &nonce, &publicKey, ephemeralPrivate), nil
+}
+
+// OpenSealed is the counter-part of Seal, i.e. it returns the plain text
+// again.
+func OpenSealed(privateKey [BoxKeySize]byte, enc []byte) ([]byte, error) {
+	if len(enc) < sealedMinSize {
+		return nil, errors.New("truncated ciphertext")
+	}
+	var ephemeralPublic [BoxKeySize]byte
+	copy(ephemeralPublic[:], enc[:BoxKeySize])
+	var nonce [nonceSize]byte
+	copy(nonce[:], enc[BoxKeySize:BoxKeySize+nonceSize])
+
+	var data []byte
+	data, success := box.Open(data, enc[BoxKeySize+nonceSize:], &nonce, &ephemeralPublic, &privateKey)
+	if !success {
+		return nil, errors.New("decryption failed")
+	}
+	return data, nil
+}
@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SealedBoxTests struct {
+	publicKey  [BoxKeySize]byte
+	privateKey [BoxKeySize]byte
+}
+
+var _ = Suite(&SealedBoxTests{})
+
+func (t *SealedBoxTests) SetUpTest(c *C) {
+	pub, priv, err := GenerateBoxKey()
+	c.Assert(err, IsNil)
+	t.publicKey = *pub
+	t.privateKey = *priv
+}
+
+func (t *SealedBoxTests) TestBoxPublicKey(c *C) {
+	c.Assert(BoxPublicKey(t.privateKey), Equals, t.publicKey)
+}
+
+func (t *SealedBoxTests) TestSealOpen(c *C) {
+	testData := []byte("This is testdata")
+	enc, err := Seal(t.publicKey, testData)
+	c.Assert(err, IsNil)
+
+	data, err := OpenSealed(t.privateKey, enc)
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, testData)
+}
+
+func (t *SealedBoxTests) TestOpenOtherKey(c *C) {
+	enc, err := Seal(t.publicKey, []byte("This is testdata"))
+	c.Assert(err, IsNil)
+
+	_, otherPrivateKey, err := GenerateBoxKey()
+	c.Assert(err, IsNil)
+	_, err = OpenSealed(*otherPrivateKey, enc)
+	c.Assert(err, NotNil)
+}
+
+func (t *SealedBoxTests) TestOpenTruncated(c *C) {
+	_, err := OpenSealed(t.privateKey, []byte{1, 2, 3})
+	c.Assert(err, NotNil)
+}
@@ -120,3 +120,36 @@ func (r *VerifyingReader) VerifyAfterRead() bool {
 	sum := r.hash.Sum(nil)
 	return ed25519.Verify(&r.pubKey, sum, &r.sig)
 }
+
+// VerifySignedData checks whether the given data, as written by a
+// SigningWriter, carries a valid signature of any of the given public keys.
+// It returns the signed data without the signature and whether the
+// verification succeeded.
+func VerifySignedData(pubKeys [][PublicKeySize]byte, data []byte) ([]byte, bool) {
+	if len(data) < SignatureSize {
+		return nil, false
+	}
+	signed := data[:len(data)-SignatureSize]
+	var sig [SignatureSize]byte
+	copy(sig[:], data[len(signed):])
+
+	hash := sha512.New()
+	hash.Write(signed)
+	sum := hash.Sum(nil)
+	for _, pubKey := range pubKeys {
+		if ed25519.Verify(&pubKey, sum, &sig) {
+			return signed, true
+		}
+	}
+	return nil, false
+}
+
+// StripSignature returns the data written by a SigningWriter without its
+// signature. The signature is NOT verified; only use this for data which
+// has been verified before.
+func StripSignature(data []byte) ([]byte, error) {
+	if len(data) < SignatureSize {
+		return nil, errors.New("data too short to be signed")
+	}
+	return data[:len(data)-SignatureSize], nil
+}
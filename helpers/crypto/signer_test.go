@@ -87,3 +87,52 @@ func (t *SignerTests) TestReadAndWriteWrongPubkey(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(v.VerifyAfterRead(), Equals, false)
 }
+
+func (t *SignerTests) TestVerifySignedData(c *C) {
+	testBytes := []byte("Test")
+	pubKey, privKey, err := ed25519.GenerateKey(
+		bytes.NewBufferString("just some deterministic 'random' bytes"))
+	c.Assert(err, IsNil)
+	otherPubKey, _, err := ed25519.GenerateKey(
+		bytes.NewBufferString("some other deterministic 'random' bytes"))
+	c.Assert(err, IsNil)
+
+	data := &bytes.Buffer{}
+	s := NewSigningWriter(*privKey, data)
+	_, err = s.Write(testBytes)
+	c.Assert(err, IsNil)
+	err = s.Finalize()
+	c.Assert(err, IsNil)
+
+	signed, ok := VerifySignedData(
+		[][PublicKeySize]byte{*otherPubKey, *pubKey}, data.Bytes())
+	c.Assert(ok, Equals, true)
+	c.Assert(signed, DeepEquals, testBytes)
+
+	_, ok = VerifySignedData([][PublicKeySize]byte{*otherPubKey}, data.Bytes())
+	c.Assert(ok, Equals, false)
+
+	_, ok = VerifySignedData([][PublicKeySize]byte{*pubKey}, testBytes)
+	c.Assert(ok, Equals, false)
+}
+
+func (t *SignerTests) TestStripSignature(c *C) {
+	testBytes := []byte("Test")
+	_, privKey, err := ed25519.GenerateKey(
+		bytes.NewBufferString("just some deterministic 'random' bytes"))
+	c.Assert(err, IsNil)
+
+	data := &bytes.Buffer{}
+	s := NewSigningWriter(*privKey, data)
+	_, err = s.Write(testBytes)
+	c.Assert(err, IsNil)
+	err = s.Finalize()
+	c.Assert(err, IsNil)
+
+	signed, err := StripSignature(data.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(signed, DeepEquals, testBytes)
+
+	_, err = StripSignature(testBytes)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,34 @@
+// +build !windows
+
+package lock
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile tries to take the flock of the given file without blocking
+// and returns whether it succeeded.
+func lockFile(file *os.File, exclusive bool) (bool, error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			return true, nil
+		}
+		if err == syscall.EWOULDBLOCK {
+			return false, nil
+		}
+		if err != syscall.EINTR {
+			return false, err
+		}
+	}
+}
+
+// unlockFile releases the flock of the given file.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,179 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pollInterval is the time between two attempts to take a file lock
+// which is held by another process.
+const pollInterval = 10 * time.Millisecond
+
+// ErrTimeout is returned if a lock could not be taken in time.
+var ErrTimeout = errors.New("timeout while waiting for lock")
+
+// FileManager implements the Manager interface with locks which are
+// backed by advisory file locks and thereby protect against other
+// processes as well.
+type FileManager struct {
+	mutex sync.Mutex
+	locks map[string]*FileLock
+}
+
+// NewFileManager returns a new FileManager.
+func NewFileManager() *FileManager {
+	return &FileManager{locks: map[string]*FileLock{}}
+}
+
+// Get returns a unique Lock for the given path and role.
+// Calling this function again with the same input parameters
+// will return the same lock. Locking it blocks until the lock is
+// acquired.
+func (fm *FileManager) Get(path string, role string) sync.Locker {
+	return fm.GetFileLock(path, role)
+}
+
+// GetFileLock returns the lock for the given path and role. It is
+// backed by the file named after the role in the directory path.
+func (fm *FileManager) GetFileLock(path string, role string) *FileLock {
+	lockPath := filepath.Join(path, role+".lock")
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	l, ok := fm.locks[lockPath]
+	if !ok {
+		l = &FileLock{path: lockPath}
+		fm.locks[lockPath] = l
+	}
+	return l
+}
+
+// FileLock is a reader/writer lock which is shared between all
+// goroutines and processes which lock the same file. Locks within a
+// process are handed out the same way as between processes, so an
+// exclusive lock excludes all other holders.
+type FileLock struct {
+	path  string
+	mutex sync.Mutex
+	file  *os.File
+	// readers is the number of holders of the shared lock in this process.
+	readers int
+	// writer is set while this process holds the exclusive lock.
+	writer bool
+}
+
+// Lock takes the exclusive lock and blocks until it is available.
+// It panics if the lock file cannot be opened.
+func (l *FileLock) Lock() {
+	err := l.acquire(true, -1)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Unlock releases the exclusive lock.
+func (l *FileLock) Unlock() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.writer {
+		panic("lock: unlock of unlocked FileLock")
+	}
+	l.writer = false
+	l.release()
+}
+
+// RLock takes the shared lock and blocks until it is available.
+// It panics if the lock file cannot be opened.
+func (l *FileLock) RLock() {
+	err := l.acquire(false, -1)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RUnlock releases the shared lock.
+func (l *FileLock) RUnlock() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.readers == 0 {
+		panic("lock: runlock of unlocked FileLock")
+	}
+	l.readers--
+	if l.readers == 0 {
+		l.release()
+	}
+}
+
+// LockTimeout takes the exclusive lock. It returns ErrTimeout if the lock
+// is not available within the given duration; a zero timeout tries
+// exactly once.
+func (l *FileLock) LockTimeout(timeout time.Duration) error {
+	return l.acquire(true, timeout)
+}
+
+// RLockTimeout takes the shared lock. It returns ErrTimeout if the lock
+// is not available within the given duration; a zero timeout tries
+// exactly once.
+func (l *FileLock) RLockTimeout(timeout time.Duration) error {
+	return l.acquire(false, timeout)
+}
+
+// acquire takes the lock in the requested mode, waiting up to timeout
+// for it; a negative timeout waits forever.
+func (l *FileLock) acquire(exclusive bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		acquired, err := l.tryAcquire(exclusive)
+		if err != nil || acquired {
+			return err
+		}
+		if timeout >= 0 && !time.Now().Before(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// tryAcquire takes the lock in the requested mode if it is available.
+func (l *FileLock) tryAcquire(exclusive bool) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.writer || (exclusive && l.readers > 0) {
+		return false, nil
+	}
+	if !exclusive && l.readers > 0 {
+		// the shared file lock is held already
+		l.readers++
+		return true, nil
+	}
+
+	if l.file == nil {
+		file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return false, err
+		}
+		l.file = file
+	}
+	acquired, err := lockFile(l.file, exclusive)
+	if err != nil || !acquired {
+		l.file.Close()
+		l.file = nil
+		return false, err
+	}
+	if exclusive {
+		l.writer = true
+	} else {
+		l.readers++
+	}
+	return true, nil
+}
+
+// release gives up the file lock; it has to be called with the mutex
+// being held.
+func (l *FileLock) release() {
+	unlockFile(l.file)
+	l.file.Close()
+	l.file = nil
+}
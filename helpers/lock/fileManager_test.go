@@ -0,0 +1,100 @@
+package lock
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type FileManagerTests struct {
+	manager *FileManager
+	// other acts as the manager of another process; its locks use
+	// their own file handles.
+	other *FileManager
+	dir   string
+}
+
+var _ = Suite(&FileManagerTests{})
+
+func (t *FileManagerTests) SetUpTest(c *C) {
+	t.manager = NewFileManager()
+	t.other = NewFileManager()
+	t.dir = c.MkDir()
+}
+
+func (t *FileManagerTests) TestGetSame(c *C) {
+	locker := t.manager.Get(t.dir, "lock")
+	c.Assert(locker, Equals, t.manager.Get(t.dir, "lock"))
+}
+
+func (t *FileManagerTests) TestDifferentRoles(c *C) {
+	locker := t.manager.GetFileLock(t.dir, "lock")
+	locker.Lock()
+	defer locker.Unlock()
+	err := t.manager.GetFileLock(t.dir, "other_role").LockTimeout(0)
+	c.Assert(err, IsNil)
+}
+
+func (t *FileManagerTests) TestExclusive(c *C) {
+	locker := t.manager.GetFileLock(t.dir, "lock")
+	locker.Lock()
+	c.Assert(locker.LockTimeout(0), Equals, ErrTimeout)
+	c.Assert(locker.RLockTimeout(0), Equals, ErrTimeout)
+	other := t.other.GetFileLock(t.dir, "lock")
+	c.Assert(other.LockTimeout(0), Equals, ErrTimeout)
+	c.Assert(other.RLockTimeout(0), Equals, ErrTimeout)
+
+	locker.Unlock()
+	c.Assert(other.LockTimeout(0), IsNil)
+	other.Unlock()
+}
+
+func (t *FileManagerTests) TestShared(c *C) {
+	locker := t.manager.GetFileLock(t.dir, "lock")
+	locker.RLock()
+	c.Assert(locker.RLockTimeout(0), IsNil)
+	other := t.other.GetFileLock(t.dir, "lock")
+	c.Assert(other.RLockTimeout(0), IsNil)
+	other.RUnlock()
+	c.Assert(other.LockTimeout(0), Equals, ErrTimeout)
+
+	// the file lock is kept until the last reader is gone
+	locker.RUnlock()
+	c.Assert(other.LockTimeout(0), Equals, ErrTimeout)
+	locker.RUnlock()
+	c.Assert(other.LockTimeout(0), IsNil)
+	other.Unlock()
+}
+
+func (t *FileManagerTests) TestTimeout(c *C) {
+	locker := t.manager.GetFileLock(t.dir, "lock")
+	locker.Lock()
+	defer locker.Unlock()
+	started := time.Now()
+	err := t.other.GetFileLock(t.dir, "lock").LockTimeout(50 * time.Millisecond)
+	c.Assert(err, Equals, ErrTimeout)
+	c.Assert(time.Since(started) >= 50*time.Millisecond, Equals, true)
+}
+
+func (t *FileManagerTests) TestWait(c *C) {
+	locker := t.manager.GetFileLock(t.dir, "lock")
+	locker.Lock()
+	released := make(chan time.Time, 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		released <- time.Now()
+		locker.Unlock()
+	}()
+
+	other := t.other.GetFileLock(t.dir, "lock")
+	err := other.LockTimeout(time.Minute)
+	c.Assert(err, IsNil)
+	acquired := time.Now()
+	c.Assert(acquired.After(<-released), Equals, true)
+	other.Unlock()
+}
+
+func (t *FileManagerTests) TestLockDirMissing(c *C) {
+	locker := t.manager.GetFileLock(t.dir+"/missing", "lock")
+	c.Assert(locker.LockTimeout(time.Minute), NotNil)
+}
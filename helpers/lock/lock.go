@@ -1,6 +1,9 @@
 package lock
 
-var currentManager Manager
+var (
+	currentManager     Manager
+	currentFileManager *FileManager
+)
 
 // CurrentManager returns the currently active Lock Manager for
 // this system which can be used to query locks for specific
@@ -11,3 +14,13 @@ func CurrentManager() Manager {
 	}
 	return currentManager
 }
+
+// CurrentFileManager returns the FileManager of this process. Its locks
+// are shared with other processes and can be used to protect data which
+// other processes access as well.
+func CurrentFileManager() *FileManager {
+	if currentFileManager == nil {
+		currentFileManager = NewFileManager()
+	}
+	return currentFileManager
+}
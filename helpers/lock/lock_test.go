@@ -12,3 +12,8 @@ var _ = Suite(&LockTests{})
 func (t *LockTests) TestManagerReturn(c *C) {
 	c.Assert(CurrentManager(), NotNil)
 }
+
+func (t *LockTests) TestFileManagerReturn(c *C) {
+	c.Assert(CurrentFileManager(), NotNil)
+	c.Assert(CurrentFileManager(), Equals, CurrentFileManager())
+}
@@ -32,8 +32,14 @@ func IsBelow(p, below string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if strings.HasPrefix(p, below) {
+	if p == below {
 		return true, nil
 	}
-	return false, nil
+	// compare whole path components only, so that e.g. "foobar" is not
+	// considered to be below "foo".
+	prefix := below
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return strings.HasPrefix(p, prefix), nil
 }
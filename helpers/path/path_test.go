@@ -57,3 +57,20 @@ func (t *PathTests) TestIsBelow(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(is, Equals, true)
 }
+
+func (t *PathTests) TestIsBelowSiblingWithPrefix(c *C) {
+	base := filepath.Join(t.dir, "foo")
+	sibling := filepath.Join(t.dir, "foobar")
+	for _, dir := range []string{base, sibling} {
+		err := os.Mkdir(dir, 0700)
+		c.Assert(err, IsNil)
+	}
+
+	is, err := IsBelow(sibling, base)
+	c.Assert(err, IsNil)
+	c.Assert(is, Equals, false)
+
+	is, err = IsBelow(base, base)
+	c.Assert(err, IsNil)
+	c.Assert(is, Equals, true)
+}
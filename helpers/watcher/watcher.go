@@ -0,0 +1,169 @@
+// Package watcher provides recursive, debounced file system
+// change notifications.
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// maxDelayFactor limits the time a batch may be delayed by
+// continuous events to this multiple of the debounce duration.
+const maxDelayFactor = 10
+
+// SkipFunc returns true for paths which should not be watched or
+// reported.
+type SkipFunc func(absPath string) bool
+
+// Watcher watches a directory tree for changes. Changes are collected
+// until no further event has been seen for the debounce duration and are
+// then delivered as one batch of absolute paths on the Changes channel.
+type Watcher struct {
+	root     string
+	debounce time.Duration
+	skip     SkipFunc
+	fsw      *fsnotify.Watcher
+	done     chan struct{}
+
+	// Changes receives the deduplicated and sorted paths which have
+	// been created, modified, removed or renamed.
+	Changes chan []string
+	// Errors receives errors which occurred while watching.
+	Errors chan error
+}
+
+// New returns a new Watcher for the directory tree below root. It does
+// not deliver any events before Start is called.
+func New(root string, debounce time.Duration, skip SkipFunc) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if skip == nil {
+		skip = func(string) bool { return false }
+	}
+	w := &Watcher{
+		root:     root,
+		debounce: debounce,
+		skip:     skip,
+		fsw:      fsw,
+		done:     make(chan struct{}),
+		Changes:  make(chan []string),
+		Errors:   make(chan error),
+	}
+	return w, nil
+}
+
+// Start registers the directory tree and starts delivering events.
+func (w *Watcher) Start() error {
+	err := w.addTree(w.root)
+	if err != nil {
+		return err
+	}
+	go w.run()
+	return nil
+}
+
+// Close stops watching; no events are delivered afterwards.
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fsw.Close()
+}
+
+// addTree adds watches for the given directory and all directories
+// below it.
+func (w *Watcher) addTree(absPath string) error {
+	return filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if w.skip(path) {
+			return filepath.SkipDir
+		}
+		return w.fsw.Add(path)
+	})
+}
+
+// run collects events and delivers them in batches.
+func (w *Watcher) run() {
+	pending := map[string]bool{}
+	var timer <-chan time.Time
+	var deadline <-chan time.Time
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if w.skip(event.Name) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				w.watchIfDir(event.Name)
+			}
+			pending[event.Name] = true
+			timer = time.After(w.debounce)
+			if deadline == nil {
+				deadline = time.After(w.debounce * maxDelayFactor)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.sendError(err)
+		case <-timer:
+			w.deliver(pending)
+			pending, timer, deadline = map[string]bool{}, nil, nil
+		case <-deadline:
+			w.deliver(pending)
+			pending, timer, deadline = map[string]bool{}, nil, nil
+		}
+	}
+}
+
+// watchIfDir adds watches for newly created directories.
+func (w *Watcher) watchIfDir(absPath string) {
+	stat, err := os.Stat(absPath)
+	if err != nil || !stat.IsDir() {
+		return
+	}
+	err = w.addTree(absPath)
+	if err != nil {
+		w.sendError(err)
+	}
+}
+
+// deliver sends the given paths as one sorted batch.
+func (w *Watcher) deliver(pending map[string]bool) {
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	select {
+	case w.Changes <- paths:
+	case <-w.done:
+	}
+}
+
+// sendError passes the error to the Errors channel unless the watcher
+// has been closed.
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.Errors <- err:
+	case <-w.done:
+	}
+}
@@ -0,0 +1,77 @@
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+const testDebounce = 50 * time.Millisecond
+
+type WatcherTests struct {
+	dir string
+	w   *Watcher
+}
+
+var _ = Suite(&WatcherTests{})
+
+func (t *WatcherTests) SetUpTest(c *C) {
+	t.dir = c.MkDir()
+	err := os.Mkdir(filepath.Join(t.dir, "skipped"), 0700)
+	c.Assert(err, IsNil)
+	t.w, err = New(t.dir, testDebounce, func(path string) bool {
+		return filepath.Base(path) == "skipped"
+	})
+	c.Assert(err, IsNil)
+	err = t.w.Start()
+	c.Assert(err, IsNil)
+}
+
+func (t *WatcherTests) TearDownTest(c *C) {
+	t.w.Close()
+}
+
+func (t *WatcherTests) nextBatch(c *C) []string {
+	select {
+	case paths := <-t.w.Changes:
+		return paths
+	case err := <-t.w.Errors:
+		c.Fatal(err)
+	case <-time.After(5 * time.Second):
+		c.Fatal("no changes received")
+	}
+	return nil
+}
+
+func (t *WatcherTests) TestDebounce(c *C) {
+	path := filepath.Join(t.dir, "foo.txt")
+	for i := 0; i < 5; i++ {
+		err := ioutil.WriteFile(path, []byte("foo"), 0600)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(t.nextBatch(c), DeepEquals, []string{path})
+}
+
+func (t *WatcherTests) TestNewDirectoryIsWatched(c *C) {
+	dir := filepath.Join(t.dir, "sub")
+	err := os.Mkdir(dir, 0700)
+	c.Assert(err, IsNil)
+	c.Assert(t.nextBatch(c), DeepEquals, []string{dir})
+
+	path := filepath.Join(dir, "foo.txt")
+	err = ioutil.WriteFile(path, []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	c.Assert(t.nextBatch(c), DeepEquals, []string{path})
+}
+
+func (t *WatcherTests) TestSkip(c *C) {
+	err := ioutil.WriteFile(filepath.Join(t.dir, "skipped", "foo.txt"), []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+	path := filepath.Join(t.dir, "bar.txt")
+	err = ioutil.WriteFile(path, []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+	c.Assert(t.nextBatch(c), DeepEquals, []string{path})
+}
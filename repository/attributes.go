@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkoutTmpPrefix is the prefix of temporary items written during
+// checkout.
+const checkoutTmpPrefix = ".lara.checkout."
+
+// directoryContent is recorded as the content of directories; revisions
+// without any content mark deleted items.
+var directoryContent = []byte{}
+
+// nodeContent returns the content which is recorded for the symlink or
+// directory at absPath; isFile is true for regular files, whose content
+// has to be read by a chunker.
+func nodeContent(absPath string) (content []byte, isFile bool, err error) {
+	stat, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if stat.IsDir() {
+		return directoryContent, false, nil
+	}
+	target, isSymlink, err := symlinkTarget(absPath)
+	if err != nil || !isSymlink {
+		return nil, !isSymlink, err
+	}
+	return []byte(target), false, nil
+}
+
+// symlinkTarget returns the target of the symlink at absPath; isSymlink
+// is false if the item is no symlink.
+func symlinkTarget(absPath string) (target string, isSymlink bool, err error) {
+	stat, err := os.Lstat(absPath)
+	if err != nil {
+		return "", false, err
+	}
+	if stat.Mode()&os.ModeSymlink == 0 {
+		return "", false, nil
+	}
+	target, err = os.Readlink(absPath)
+	return target, err == nil, err
+}
+
+// itemMetadata returns the metadata describing the work dir item at
+// absPath. Symlinks are not followed.
+func (r *ClientRepository) itemMetadata(absPath string) (*Metadata, error) {
+	relPath, err := r.getRepoRelativePath(absPath)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	m := &Metadata{
+		RepoRelativePath: relPath,
+		Type:             MetadataTypeFile,
+		Mode:             stat.Mode().Perm(),
+		ModTime:          stat.ModTime().UnixNano(),
+	}
+	if stat.IsDir() {
+		// the modification time of directories changes with each of
+		// their entries and is therefore not recorded.
+		m.Type = MetadataTypeDir
+		m.ModTime = 0
+	}
+	if stat.Mode()&os.ModeSymlink != 0 {
+		m.Type = MetadataTypeSymlink
+		m.Mode = 0
+		m.ModTime = 0
+		m.SymlinkTarget, err = os.Readlink(absPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// checkWorkDirPath returns ErrUnsafeWorkDirPath if absPath is not below
+// the work dir or if any of its parents inside the work dir is a symlink;
+// as symlinks are synchronized, writing through them could otherwise
+// modify arbitrary items outside the work dir.
+func (r *ClientRepository) checkWorkDirPath(absPath string) error {
+	relPath, err := filepath.Rel(r.Path, absPath)
+	if err != nil {
+		return err
+	}
+	if relPath == "." || relPath == ".." ||
+		strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return ErrUnsafeWorkDirPath
+	}
+	parent := r.Path
+	for _, name := range strings.Split(filepath.Dir(relPath), string(filepath.Separator)) {
+		if name == "." {
+			continue
+		}
+		parent = filepath.Join(parent, name)
+		stat, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if stat.Mode()&os.ModeSymlink != 0 {
+			return ErrUnsafeWorkDirPath
+		}
+	}
+	return nil
+}
+
+// writeItem writes the item described by the given metadata with the
+// given content to absPath. Symlinks are created as links; files and
+// directories get the recorded permissions and modification time.
+func (r *ClientRepository) writeItem(absPath string, metadata *Metadata, contentIDs []string) error {
+	stat, statErr := os.Lstat(absPath)
+	isDir := statErr == nil && stat.IsDir()
+	switch metadata.Type {
+	case MetadataTypeSymlink:
+		return writeSymlink(absPath, metadata.SymlinkTarget)
+	case MetadataTypeDir:
+		if statErr == nil && !isDir {
+			err := os.Remove(absPath)
+			if err != nil {
+				return err
+			}
+		}
+		err := os.MkdirAll(absPath, defaultDirPerms)
+		if err != nil {
+			return err
+		}
+		return applyAttributes(absPath, metadata)
+	}
+	if isDir {
+		// a file may only replace an empty directory
+		err := removeItem(absPath)
+		if err != nil {
+			return err
+		}
+	}
+	err := r.writeContentIDsTo(absPath, contentIDs)
+	if err != nil {
+		return err
+	}
+	return applyAttributes(absPath, metadata)
+}
+
+// writeSymlink atomically replaces the item at absPath by a symlink to
+// the given target.
+func writeSymlink(absPath string, target string) error {
+	tmpPath := filepath.Join(filepath.Dir(absPath), checkoutTmpPrefix+filepath.Base(absPath))
+	err := os.Remove(tmpPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.Symlink(target, tmpPath)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, absPath)
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// removeItem removes the file, symlink or empty directory at absPath;
+// directories which still have entries are kept.
+func removeItem(absPath string) error {
+	stat, err := os.Lstat(absPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		entries, err := ioutil.ReadDir(absPath)
+		if err != nil || len(entries) > 0 {
+			return err
+		}
+	}
+	return os.Remove(absPath)
+}
+
+// applyAttributes sets the permissions and the modification time of the
+// file at absPath as recorded in the given metadata; unknown attributes
+// are left alone.
+func applyAttributes(absPath string, metadata *Metadata) error {
+	if metadata.Type == MetadataTypeSymlink {
+		return nil
+	}
+	if metadata.Mode != 0 {
+		err := os.Chmod(absPath, metadata.Mode)
+		if err != nil {
+			return err
+		}
+	}
+	if metadata.ModTime != 0 {
+		modTime := time.Unix(0, metadata.ModTime)
+		return os.Chtimes(absPath, time.Now(), modTime)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&AttributesTests{})
+
+type AttributesTests struct {
+	clientPairTests
+}
+
+// transferAndCheckout transfers the NIB for the relative path from mine
+// to theirs and checks it out there.
+func (t *AttributesTests) transferAndCheckout(c *C, relPath string) {
+	err := t.transferNIB(c, t.mine, t.theirs, relPath)
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+}
+
+func (t *AttributesTests) TestModeAndModTime(c *C) {
+	absPath := filepath.Join(t.mine.Path, "run.sh")
+	err := ioutil.WriteFile(absPath, []byte("#!/bin/sh\n"), 0600)
+	c.Assert(err, IsNil)
+	err = os.Chmod(absPath, 0750)
+	c.Assert(err, IsNil)
+	modTime := time.Date(2015, 3, 4, 5, 6, 7, 0, time.UTC)
+	err = os.Chtimes(absPath, modTime, modTime)
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(absPath)
+	c.Assert(err, IsNil)
+
+	t.transferAndCheckout(c, "run.sh")
+	stat, err := os.Stat(filepath.Join(t.theirs.Path, "run.sh"))
+	c.Assert(err, IsNil)
+	c.Assert(stat.Mode().Perm(), Equals, os.FileMode(0750))
+	c.Assert(stat.ModTime().Equal(modTime), Equals, true)
+}
+
+func (t *AttributesTests) TestModeChangeIsRecorded(c *C) {
+	t.writeAndAdd(c, t.mine, "run.sh", []byte("#!/bin/sh\n"))
+	absPath := filepath.Join(t.mine.Path, "run.sh")
+	err := os.Chmod(absPath, 0700)
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(absPath)
+	c.Assert(err, IsNil)
+	n, err := t.mine.nibForPath(absPath)
+	c.Assert(err, IsNil)
+	c.Assert(n.Revisions, HasLen, 2)
+}
+
+func (t *AttributesTests) TestSymlink(c *C) {
+	t.writeAndAdd(c, t.mine, "target.txt", []byte("target"))
+	absPath := filepath.Join(t.mine.Path, "link.txt")
+	err := os.Symlink("target.txt", absPath)
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(absPath)
+	c.Assert(err, IsNil)
+
+	t.transferAndCheckout(c, "link.txt")
+	target, err := os.Readlink(filepath.Join(t.theirs.Path, "link.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "target.txt")
+}
+
+func (t *AttributesTests) TestSymlinkRetarget(c *C) {
+	absPath := filepath.Join(t.mine.Path, "link")
+	err := os.Symlink("first", absPath)
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(absPath)
+	c.Assert(err, IsNil)
+	t.transferAndCheckout(c, "link")
+
+	err = os.Remove(absPath)
+	c.Assert(err, IsNil)
+	err = os.Symlink("second", absPath)
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(absPath)
+	c.Assert(err, IsNil)
+	t.transferAndCheckout(c, "link")
+
+	target, err := os.Readlink(filepath.Join(t.theirs.Path, "link"))
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "second")
+}
+
+func (t *AttributesTests) TestSymlinkToDirIsNotFollowed(c *C) {
+	dir := filepath.Join(t.mine.Path, "dir")
+	err := os.Mkdir(dir, 0700)
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "foo.txt"), []byte("foo"))
+	err = os.Symlink("dir", filepath.Join(t.mine.Path, "link"))
+	c.Assert(err, IsNil)
+
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+	_, err = t.mine.nibForPath(filepath.Join(t.mine.Path, "link", "foo.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = t.mine.nibForPath(filepath.Join(t.mine.Path, "link"))
+	c.Assert(err, IsNil)
+}
+
+func (t *AttributesTests) TestCheckoutThroughSymlinkedParent(c *C) {
+	outside := c.MkDir()
+	err := os.Symlink(outside, filepath.Join(t.mine.Path, "link"))
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(filepath.Join(t.mine.Path, "link"))
+	c.Assert(err, IsNil)
+	// another device may record items below its symlinks
+	t.writeAndAdd(c, t.mine, filepath.Join("link", "file"), []byte("evil"))
+	err = os.Remove(filepath.Join(outside, "file"))
+	c.Assert(err, IsNil)
+
+	t.transferAndCheckout(c, "link")
+	err = t.transferNIB(c, t.mine, t.theirs, filepath.Join("link", "file"))
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutPath(filepath.Join(t.theirs.Path, "link", "file"))
+	c.Assert(err, Equals, ErrUnsafeWorkDirPath)
+	_, err = os.Lstat(filepath.Join(outside, "file"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *AttributesTests) TestCheckWorkDirPath(c *C) {
+	r := t.theirs
+	c.Assert(r.checkWorkDirPath(filepath.Join(r.Path, "a", "b")), IsNil)
+	c.Assert(r.checkWorkDirPath(filepath.Join(r.Path, "..", "b")), Equals, ErrUnsafeWorkDirPath)
+	c.Assert(r.checkWorkDirPath(r.Path), Equals, ErrUnsafeWorkDirPath)
+}
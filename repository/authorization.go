@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 
+	"github.com/hoffie/larasync/repository/chunker"
 	"github.com/hoffie/larasync/repository/odf"
 )
 
@@ -15,6 +16,9 @@ type Authorization struct {
 	SigningKey    [PrivateKeySize]byte
 	EncryptionKey [EncryptionKeySize]byte
 	HashingKey    [HashingKeySize]byte
+	// Chunker is the chunking configuration of the repository; it is nil
+	// for authorizations which have been created without one.
+	Chunker *chunker.Config
 }
 
 // newAuthorizationFromPb returns a new Authorization object
@@ -41,6 +45,16 @@ func (a *Authorization) setFromPb(pbAuthorization *odf.Authorization) {
 	copy(a.SigningKey[:], protoSigningKey[0:PrivateKeySize])
 	copy(a.EncryptionKey[:], protoEncryptionKey[0:EncryptionKeySize])
 	copy(a.HashingKey[:], protoHashingKey[0:HashingKeySize])
+
+	a.Chunker = nil
+	if pbChunker := pbAuthorization.GetChunker(); pbChunker != nil {
+		a.Chunker = &chunker.Config{
+			Algorithm: pbChunker.GetAlgorithm(),
+			MinSize:   pbChunker.GetMinSize(),
+			AvgSize:   pbChunker.GetAvgSize(),
+			MaxSize:   pbChunker.GetMaxSize(),
+		}
+	}
 }
 
 // toPb converts this Authorization to a protobuf Authorization.
@@ -60,6 +74,15 @@ func (a *Authorization) toPb() (*odf.Authorization, error) {
 		HashingKey:    hashingKey,
 	}
 
+	if a.Chunker != nil {
+		protoAuthorization.Chunker = &odf.ChunkerConfig{
+			Algorithm: proto.String(a.Chunker.Algorithm),
+			MinSize:   proto.Uint64(a.Chunker.MinSize),
+			AvgSize:   proto.Uint64(a.Chunker.AvgSize),
+			MaxSize:   proto.Uint64(a.Chunker.MaxSize),
+		}
+	}
+
 	return protoAuthorization, nil
 }
 
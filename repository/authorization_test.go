@@ -6,6 +6,7 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	"github.com/hoffie/larasync/repository/chunker"
 	"github.com/hoffie/larasync/repository/odf"
 )
 
@@ -125,6 +126,22 @@ func (t *AuthorizationTest) TestReadFrom(c *C) {
 	c.Assert(err, IsNil)
 
 	assertEqualAuthorizations(c, authorization, otherAuth)
+	c.Assert(otherAuth.Chunker, IsNil)
+}
+
+func (t *AuthorizationTest) TestReadFromWithChunker(c *C) {
+	authorization := t.getAuthorization()
+	authorization.Chunker = chunker.DefaultConfig()
+	buffer := &bytes.Buffer{}
+	_, err := authorization.WriteTo(buffer)
+	c.Assert(err, IsNil)
+
+	otherAuth := &Authorization{}
+	_, err = otherAuth.ReadFrom(buffer)
+	c.Assert(err, IsNil)
+
+	assertEqualAuthorizations(c, authorization, otherAuth)
+	c.Assert(otherAuth.Chunker, DeepEquals, chunker.DefaultConfig())
 }
 
 func (t *AuthorizationTest) TestReadFromError(c *C) {
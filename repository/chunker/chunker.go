@@ -5,27 +5,56 @@ import (
 	"os"
 )
 
-// Chunker returns the contents of the given file path in chunkSize-sized
-// chunks.
-type Chunker struct {
+// Chunker is implemented by all strategies which split the contents of a
+// file into chunks.
+type Chunker interface {
+	// HasNext returns true if an upcoming Next() call is expected
+	// to return more data.
+	HasNext() bool
+	// Next returns the next part of the file.
+	Next() ([]byte, error)
+	// Close cleans up the chunker after usage.
+	Close()
+}
+
+// NewFromConfig returns a new chunker for the given file path which
+// splits the contents as specified by the given configuration.
+func NewFromConfig(path string, config *Config) (Chunker, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	switch config.Algorithm {
+	case AlgorithmFixed:
+		return New(path, config.AvgSize)
+	case AlgorithmFastCDC:
+		return NewFastCDC(path, config.MinSize, config.AvgSize, config.MaxSize)
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// FixedChunker returns the contents of the given file path in
+// chunkSize-sized chunks.
+type FixedChunker struct {
 	file      *os.File
 	finished  bool
 	chunkSize uint64
 }
 
-// New returns a new chunker instance for the given file path
+// New returns a new fixed-size chunker instance for the given file path
 // and the given chunk size.
-func New(path string, chunkSize uint64) (*Chunker, error) {
+func New(path string, chunkSize uint64) (*FixedChunker, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 
 	if chunkSize < 16 {
+		file.Close()
 		return nil, ErrBadChunkSize
 	}
 
-	c := &Chunker{
+	c := &FixedChunker{
 		file:      file,
 		finished:  false,
 		chunkSize: chunkSize,
@@ -35,12 +64,12 @@ func New(path string, chunkSize uint64) (*Chunker, error) {
 
 // HasNext returns true if an upcoming Next() call is expected
 // to return more data.
-func (c *Chunker) HasNext() bool {
+func (c *FixedChunker) HasNext() bool {
 	return !c.finished
 }
 
 // Next returns the next part of the file, with at most chunkSize bytes.
-func (c *Chunker) Next() ([]byte, error) {
+func (c *FixedChunker) Next() ([]byte, error) {
 	buf := make([]byte, c.chunkSize)
 	numBytes, err := c.file.Read(buf)
 	if uint64(numBytes) < c.chunkSize || err != nil {
@@ -58,6 +87,6 @@ func (c *Chunker) Next() ([]byte, error) {
 // Close cleans up the chunker after usage.
 //
 // Use the c := NewChunker(); defer c.Close() pattern
-func (c *Chunker) Close() {
+func (c *FixedChunker) Close() {
 	c.file.Close()
 }
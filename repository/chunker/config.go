@@ -0,0 +1,63 @@
+package chunker
+
+const (
+	// AlgorithmFixed splits files into blocks of AvgSize bytes.
+	AlgorithmFixed = "fixed"
+	// AlgorithmFastCDC splits files at content-defined boundaries.
+	AlgorithmFastCDC = "fastcdc"
+
+	// minCDCChunkSize is the lowest accepted minimum size for
+	// content-defined chunking.
+	minCDCChunkSize = 64
+)
+
+// Config describes how files are split into chunks. All clients of a
+// repository have to use the same configuration in order to arrive
+// at the same content ids.
+type Config struct {
+	Algorithm string `json:"algorithm"`
+	MinSize   uint64 `json:"min_size,omitempty"`
+	AvgSize   uint64 `json:"avg_size"`
+	MaxSize   uint64 `json:"max_size,omitempty"`
+}
+
+// NewFixedConfig returns a configuration for fixed-size chunks of the
+// given size.
+func NewFixedConfig(chunkSize uint64) *Config {
+	return &Config{
+		Algorithm: AlgorithmFixed,
+		AvgSize:   chunkSize,
+	}
+}
+
+// DefaultConfig returns the configuration which is used for newly created
+// repositories.
+func DefaultConfig() *Config {
+	return &Config{
+		Algorithm: AlgorithmFastCDC,
+		MinSize:   256 * 1024,
+		AvgSize:   1024 * 1024,
+		MaxSize:   4 * 1024 * 1024,
+	}
+}
+
+// Validate checks whether the configuration can be used to create
+// a chunker.
+func (c *Config) Validate() error {
+	switch c.Algorithm {
+	case AlgorithmFixed:
+		if c.AvgSize < 16 {
+			return ErrBadChunkSize
+		}
+	case AlgorithmFastCDC:
+		if c.MinSize < minCDCChunkSize {
+			return ErrBadChunkSize
+		}
+		if c.MinSize >= c.AvgSize || c.AvgSize >= c.MaxSize {
+			return ErrBadChunkSizeRange
+		}
+	default:
+		return ErrUnknownAlgorithm
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package chunker
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type ConfigTests struct {
+	dir string
+}
+
+var _ = Suite(&ConfigTests{})
+
+func (t *ConfigTests) SetUpTest(c *C) {
+	t.dir = c.MkDir()
+}
+
+func (t *ConfigTests) TestDefaultValid(c *C) {
+	c.Assert(DefaultConfig().Validate(), IsNil)
+}
+
+func (t *ConfigTests) TestUnknownAlgorithm(c *C) {
+	config := &Config{Algorithm: "foo", AvgSize: 1024}
+	c.Assert(config.Validate(), Equals, ErrUnknownAlgorithm)
+	_, err := NewFromConfig("test", config)
+	c.Assert(err, Equals, ErrUnknownAlgorithm)
+}
+
+func (t *ConfigTests) TestNewFromConfig(c *C) {
+	path := filepath.Join(t.dir, "test")
+	err := ioutil.WriteFile(path, []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+
+	ch, err := NewFromConfig(path, NewFixedConfig(16))
+	c.Assert(err, IsNil)
+	ch.Close()
+	_, ok := ch.(*FixedChunker)
+	c.Assert(ok, Equals, true)
+
+	ch, err = NewFromConfig(path, DefaultConfig())
+	c.Assert(err, IsNil)
+	ch.Close()
+	_, ok = ch.(*FastCDCChunker)
+	c.Assert(ok, Equals, true)
+}
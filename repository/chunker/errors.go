@@ -8,4 +8,10 @@ var (
 	// ErrBadChunkSize will be thrown if a too little chunk size is requested.
 	// This is used by the Chunker implementation.
 	ErrBadChunkSize = errors.New("bad chunk size (must be >16 bytes)")
+	// ErrBadChunkSizeRange is returned if the min/avg/max sizes of a
+	// content-defined chunker are not in ascending order.
+	ErrBadChunkSizeRange = errors.New("bad chunk size range (min < avg < max required)")
+	// ErrUnknownAlgorithm is returned if a configuration references an
+	// unsupported chunking algorithm.
+	ErrUnknownAlgorithm = errors.New("unknown chunking algorithm")
 )
@@ -0,0 +1,143 @@
+package chunker
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// gearTable maps each byte value to a pseudo-random 64 bit value which is
+// fed into the rolling gear hash. It is generated from a fixed seed as
+// every client has to arrive at the same cut points.
+var gearTable [256]uint64
+
+func init() {
+	state := uint64(0x6c617261)
+	for i := range gearTable {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearTable[i] = z ^ (z >> 31)
+	}
+}
+
+// FastCDCChunker splits the contents of the given file path at
+// content-defined boundaries. Inserting or removing data therefore only
+// affects the chunks around the modification instead of all following
+// chunks.
+//
+// Cut points are searched using a gear hash with normalized chunking as
+// described in the FastCDC paper: below the average size a stricter mask
+// is used, above it a looser one, which keeps the chunk sizes close to
+// the average.
+type FastCDCChunker struct {
+	file     *os.File
+	reader   *bufio.Reader
+	finished bool
+	minSize  uint64
+	avgSize  uint64
+	maxSize  uint64
+	maskS    uint64
+	maskL    uint64
+}
+
+// NewFastCDC returns a new content-defined chunker instance for the given
+// file path. Chunks will be between minSize and maxSize bytes long and
+// approximately avgSize bytes on average.
+func NewFastCDC(path string, minSize, avgSize, maxSize uint64) (*FastCDCChunker, error) {
+	if minSize < minCDCChunkSize {
+		return nil, ErrBadChunkSize
+	}
+	if minSize >= avgSize || avgSize >= maxSize {
+		return nil, ErrBadChunkSizeRange
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bits := uint(0)
+	for (uint64(1) << (bits + 1)) <= avgSize {
+		bits++
+	}
+
+	c := &FastCDCChunker{
+		file:    file,
+		reader:  bufio.NewReader(file),
+		minSize: minSize,
+		avgSize: avgSize,
+		maxSize: maxSize,
+		maskS:   highBitMask(bits + 2),
+		maskL:   highBitMask(bits - 2),
+	}
+	return c, nil
+}
+
+// highBitMask returns a mask with the given number of most significant
+// bits set.
+func highBitMask(bits uint) uint64 {
+	return ^uint64(0) << (64 - bits)
+}
+
+// HasNext returns true if an upcoming Next() call is expected
+// to return more data.
+func (c *FastCDCChunker) HasNext() bool {
+	return !c.finished
+}
+
+// Next returns the next part of the file, with at most maxSize bytes.
+func (c *FastCDCChunker) Next() ([]byte, error) {
+	buf := make([]byte, c.minSize, c.maxSize)
+	numBytes, err := io.ReadFull(c.reader, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		c.finish()
+		return buf[:numBytes], nil
+	}
+	if err != nil {
+		c.finish()
+		return nil, err
+	}
+
+	var hash uint64
+	for uint64(len(buf)) < c.maxSize {
+		b, err := c.reader.ReadByte()
+		if err == io.EOF {
+			c.finish()
+			return buf, nil
+		}
+		if err != nil {
+			c.finish()
+			return nil, err
+		}
+		buf = append(buf, b)
+
+		hash = (hash << 1) + gearTable[b]
+		mask := c.maskS
+		if uint64(len(buf)) >= c.avgSize {
+			mask = c.maskL
+		}
+		if hash&mask == 0 {
+			break
+		}
+	}
+
+	_, err = c.reader.Peek(1)
+	if err == io.EOF {
+		c.finish()
+	}
+	return buf, nil
+}
+
+// finish marks the chunker as exhausted and releases the file.
+func (c *FastCDCChunker) finish() {
+	c.finished = true
+	c.file.Close()
+}
+
+// Close cleans up the chunker after usage.
+func (c *FastCDCChunker) Close() {
+	c.file.Close()
+}
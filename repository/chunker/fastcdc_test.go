@@ -0,0 +1,112 @@
+package chunker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type FastCDCTests struct {
+	dir string
+}
+
+var _ = Suite(&FastCDCTests{})
+
+func (t *FastCDCTests) SetUpTest(c *C) {
+	t.dir = c.MkDir()
+}
+
+// randomData returns size bytes of deterministic pseudo-random data.
+func (t *FastCDCTests) randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(42)).Read(data)
+	return data
+}
+
+// chunks writes the given data to a file and returns the chunks
+// produced by the content-defined chunker.
+func (t *FastCDCTests) chunks(c *C, data []byte) [][]byte {
+	path := filepath.Join(t.dir, "test")
+	err := ioutil.WriteFile(path, data, 0600)
+	c.Assert(err, IsNil)
+
+	ch, err := NewFastCDC(path, 256, 1024, 4096)
+	c.Assert(err, IsNil)
+	defer ch.Close()
+	res := [][]byte{}
+	for ch.HasNext() {
+		chunk, err := ch.Next()
+		c.Assert(err, IsNil)
+		res = append(res, chunk)
+	}
+	return res
+}
+
+func (t *FastCDCTests) TestHandleError(c *C) {
+	_, err := NewFastCDC(filepath.Join(t.dir, "non-existing"), 256, 1024, 4096)
+	c.Assert(err, NotNil)
+}
+
+func (t *FastCDCTests) TestBadSizes(c *C) {
+	_, err := NewFastCDC("test", 16, 1024, 4096)
+	c.Assert(err, Equals, ErrBadChunkSize)
+	_, err = NewFastCDC("test", 1024, 1024, 4096)
+	c.Assert(err, Equals, ErrBadChunkSizeRange)
+	_, err = NewFastCDC("test", 256, 4096, 1024)
+	c.Assert(err, Equals, ErrBadChunkSizeRange)
+}
+
+func (t *FastCDCTests) TestEmpty(c *C) {
+	chunks := t.chunks(c, []byte{})
+	c.Assert(len(chunks), Equals, 1)
+	c.Assert(len(chunks[0]), Equals, 0)
+}
+
+func (t *FastCDCTests) TestSmallerThanMin(c *C) {
+	chunks := t.chunks(c, []byte("foo"))
+	c.Assert(chunks, DeepEquals, [][]byte{[]byte("foo")})
+}
+
+func (t *FastCDCTests) TestSizeBounds(c *C) {
+	data := t.randomData(64 * 1024)
+	chunks := t.chunks(c, data)
+	c.Assert(len(chunks) > 1, Equals, true)
+	for i, chunk := range chunks {
+		c.Assert(len(chunk) <= 4096, Equals, true)
+		if i < len(chunks)-1 {
+			c.Assert(len(chunk) >= 256, Equals, true)
+		}
+	}
+	c.Assert(bytes.Join(chunks, nil), DeepEquals, data)
+}
+
+func (t *FastCDCTests) TestMaxSizeCut(c *C) {
+	data := bytes.Repeat([]byte{0}, 10000)
+	chunks := t.chunks(c, data)
+	c.Assert(bytes.Join(chunks, nil), DeepEquals, data)
+	for _, chunk := range chunks {
+		c.Assert(len(chunk) <= 4096, Equals, true)
+	}
+}
+
+func (t *FastCDCTests) TestInsertionOnlyAffectsLocalChunks(c *C) {
+	data := t.randomData(64 * 1024)
+	before := t.chunks(c, data)
+	modified := append([]byte("inserted"), data...)
+	after := t.chunks(c, modified)
+
+	known := map[string]bool{}
+	for _, chunk := range before {
+		known[string(chunk)] = true
+	}
+	unchanged := 0
+	for _, chunk := range after {
+		if known[string(chunk)] {
+			unchanged++
+		}
+	}
+	c.Assert(unchanged >= len(after)-2, Equals, true)
+}
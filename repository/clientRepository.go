@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/hoffie/larasync/helpers"
 	"github.com/hoffie/larasync/helpers/atomic"
 	"github.com/hoffie/larasync/helpers/crypto"
+	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
 	"github.com/hoffie/larasync/helpers/path"
 	"github.com/hoffie/larasync/repository/chunker"
 	"github.com/hoffie/larasync/repository/nib"
@@ -22,8 +25,10 @@ import (
 // and a work dir (comapred to the base Repository)
 type ClientRepository struct {
 	*Repository
-	stateConfig *StateConfig
-	nibTracker  tracker.NIBTracker
+	stateConfig   *StateConfig
+	repoConfig    *RepositoryConfig
+	nibTracker    tracker.NIBTracker
+	objectFetcher ObjectFetcher
 }
 
 // NewClient returns a new ClientRepository instance
@@ -65,6 +70,62 @@ func (r *ClientRepository) StateConfig() (*StateConfig, error) {
 	return r.stateConfig, nil
 }
 
+// RepositoryConfig returns this repository's config; it holds the settings
+// which all clients of the repository have to agree on.
+func (r *ClientRepository) RepositoryConfig() (*RepositoryConfig, error) {
+	if r.repoConfig != nil {
+		return r.repoConfig, nil
+	}
+	path := r.subPathFor(repoConfigFileName)
+	r.repoConfig = NewRepositoryConfig(path)
+	err := r.repoConfig.Load()
+	if err != nil && !os.IsNotExist(err) {
+		r.repoConfig = nil
+		return nil, err
+	}
+	return r.repoConfig, nil
+}
+
+// ChunkerConfig returns the configuration which is used to split files
+// into chunks. Repositories without a chunking configuration use
+// fixed-size chunks.
+func (r *ClientRepository) ChunkerConfig() (*chunker.Config, error) {
+	rc, err := r.RepositoryConfig()
+	if err != nil {
+		return nil, err
+	}
+	if rc.Chunking == nil {
+		return chunker.NewFixedConfig(chunkSize), nil
+	}
+	return rc.Chunking, nil
+}
+
+// SetChunkerConfig stores the given chunking configuration in the
+// repository config. It has to be set before any file is added as
+// already existing content ids would not be reproducible otherwise.
+func (r *ClientRepository) SetChunkerConfig(config *chunker.Config) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+	rc, err := r.RepositoryConfig()
+	if err != nil {
+		return err
+	}
+	rc.Chunking = config
+	return rc.Save()
+}
+
+// newChunker returns a chunker for the given path which splits the
+// contents as configured for this repository.
+func (r *ClientRepository) newChunker(path string) (chunker.Chunker, error) {
+	config, err := r.ChunkerConfig()
+	if err != nil {
+		return nil, err
+	}
+	return chunker.NewFromConfig(path, config)
+}
+
 // writeFileToChunks takes a file path and saves its contents to the
 // storage in encrypted form with a content-addressing id.
 func (r *ClientRepository) writeFileToChunks(path string) ([]string, error) {
@@ -72,15 +133,38 @@ func (r *ClientRepository) writeFileToChunks(path string) ([]string, error) {
 }
 
 // getFileChunkIDs analyzes the given file and returns its content ids.
-// This function does not write anything to disk.
+// This function does not write any objects; the content ids of tracked
+// files are cached together with their stat data, so unchanged files
+// are not hashed again.
 func (r *ClientRepository) getFileChunkIDs(path string) ([]string, error) {
-	return r.splitFileToChunks(path, func(string, []byte) error { return nil })
+	ids, ok := r.cachedContentIDs(path)
+	if ok {
+		return ids, nil
+	}
+	ids, err := r.splitFileToChunks(path, func(string, []byte) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return ids, r.cacheContentIDs(path, ids)
 }
 
 // splitFileToChunks takes a file path and splits its contents into chunks
-// identified by their content ids.
+// identified by their content ids. The content of a symlink is its target,
+// directories are represented by directoryContent.
 func (r *ClientRepository) splitFileToChunks(path string, handler func(string, []byte) error) ([]string, error) {
-	chunker, err := chunker.New(path, chunkSize)
+	content, isFile, err := nodeContent(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isFile {
+		hexHash, err := r.hashChunk(content)
+		if err != nil {
+			return nil, err
+		}
+		return []string{hexHash}, handler(hexHash, content)
+	}
+
+	chunker, err := r.newChunker(path)
 	if err != nil {
 		return nil, err
 	}
@@ -108,29 +192,31 @@ func (r *ClientRepository) splitFileToChunks(path string, handler func(string, [
 	return ids, nil
 }
 
-// fileToChunkIds returnes te current chunk hashes for the given path.
-func (r *ClientRepository) fileToChunkIds(path string) ([]string, error) {
-	chunker, err := chunker.New(path, chunkSize)
+// AddNIBContent adds NIBData to the repository after verifying it.
+// In contrast to Repository.AddNIBContent, NIBs which cannot be
+// fast-forwarded are merged with the local state instead of being
+// rejected.
+func (r *ClientRepository) AddNIBContent(nibReader io.Reader) error {
+	data, err := ioutil.ReadAll(nibReader)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer chunker.Close()
-	var ids []string
-	for chunker.HasNext() {
-		chunk, err := chunker.Next()
-		if err != nil {
-			return nil, err
-		}
 
-		// hash for content-addressing
-		hexHash, err := r.hashChunk(chunk)
-		if err != nil {
-			return nil, err
-		}
+	err = r.addNIBContent(data, r.RequiredObjectIDs)
+	if err != ErrNIBConflict {
+		return err
+	}
 
-		ids = append(ids, hexHash)
+	otherNIB, err := r.VerifyAndParseNIBBytes(data)
+	if err != nil {
+		return err
 	}
-	return ids, nil
+	return r.resolveNIBConflict(otherNIB)
+}
+
+// fileToChunkIds returnes te current chunk hashes for the given path.
+func (r *ClientRepository) fileToChunkIds(path string) ([]string, error) {
+	return r.getFileChunkIDs(path)
 }
 
 // GetSigningPrivateKey exposes the signing private key as it is required
@@ -156,7 +242,117 @@ func (r *ClientRepository) CreateKeys() error {
 		return err
 	}
 
-	return nil
+	return r.RegisterDevice()
+}
+
+// GetDeviceSigningPrivateKey returns the key this device signs requests
+// with. Repositories without a device key use the repository signing key.
+func (r *ClientRepository) GetDeviceSigningPrivateKey() ([PrivateKeySize]byte, error) {
+	return r.nibStore.signingPrivateKey()
+}
+
+// DeviceID returns the ID of this device or an empty string if this
+// device has no signing key of its own.
+func (r *ClientRepository) DeviceID() (string, error) {
+	key, err := r.keys.DeviceSigningPrivateKey()
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return DeviceIDFromPublicKey(edhelpers.GetPublicKeyFromPrivate(key)), nil
+}
+
+// RegisterDevice creates the signing and encryption keys of this device if
+// it does not have them yet and adds the device to the device registry.
+func (r *ClientRepository) RegisterDevice() error {
+	key, err := r.keys.DeviceSigningPrivateKey()
+	if os.IsNotExist(err) {
+		err = r.keys.CreateDeviceSigningKey()
+		if err != nil {
+			return err
+		}
+		key, err = r.keys.DeviceSigningPrivateKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	boxKey, err := r.keys.DeviceEncryptionPrivateKey()
+	if os.IsNotExist(err) {
+		err = r.keys.CreateDeviceEncryptionKey()
+		if err != nil {
+			return err
+		}
+		boxKey, err = r.keys.DeviceEncryptionPrivateKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	registry, err := r.devices.Get()
+	if err != nil {
+		return err
+	}
+	registry.Add(&Device{
+		PublicKey:           edhelpers.GetPublicKeyFromPrivate(key),
+		EncryptionPublicKey: crypto.BoxPublicKey(boxKey),
+		Name:                localDeviceName(),
+		UTCTimestamp:        time.Now().UTC().Unix(),
+	})
+	return r.devices.Set(registry)
+}
+
+// IsDeviceRegistered returns whether this device is part of the device
+// registry and has published its encryption key.
+func (r *ClientRepository) IsDeviceRegistered() (bool, error) {
+	deviceID, err := r.DeviceID()
+	if err != nil || deviceID == "" {
+		return false, err
+	}
+	registry, err := r.devices.Get()
+	if err != nil {
+		return false, err
+	}
+	device := registry.Get(deviceID)
+	return device != nil && device.HasEncryptionKey(), nil
+}
+
+// MergeDeviceRegistryBytes merges the given signed device registry, e.g.
+// as received from the server, into the local one. data may be nil if
+// there is no such registry yet.
+// It returns whether the given registry lacks any locally known device.
+func (r *ClientRepository) MergeDeviceRegistryBytes(data []byte) (bool, error) {
+	local, err := r.devices.Get()
+	if err != nil {
+		return false, err
+	}
+	other := &DeviceRegistry{}
+	if data != nil {
+		other, err = r.devices.VerifyAndParseBytes(data)
+		if err != nil {
+			return false, err
+		}
+	}
+	incomplete := !other.Contains(local)
+	if local.Merge(other) {
+		err = r.devices.Set(local)
+		if err != nil {
+			return false, err
+		}
+	}
+	return incomplete, nil
+}
+
+// deviceDisplayName returns a human-readable name for the device with the
+// given ID.
+func (r *ClientRepository) deviceDisplayName(deviceID string) string {
+	registry, err := r.devices.Get()
+	if err != nil {
+		registry = &DeviceRegistry{}
+	}
+	return registry.DisplayName(deviceID)
 }
 
 // encryptWithRandomKey takes a piece of data, encrypts it with a random
@@ -204,7 +400,7 @@ func (r *ClientRepository) writeCryptoContainerObject(id string, data []byte) er
 // readEncryptedObject reads the object with the given id and returns its
 // authenticated, unencrypted content.
 func (r *ClientRepository) readEncryptedObject(id string) ([]byte, error) {
-	reader, err := r.objectStorage.Get(id)
+	reader, err := r.getObject(id)
 	if err != nil {
 		return nil, err
 	}
@@ -218,28 +414,74 @@ func (r *ClientRepository) readEncryptedObject(id string) ([]byte, error) {
 
 // decryptContent is the counter-part of encryptWithRandomKey, i.e.
 // it returns the plain text again.
+// Objects which have not been re-wrapped after a key rotation are
+// decrypted with the previous encryption key.
 func (r *ClientRepository) decryptContent(enc []byte) ([]byte, error) {
 	encryptionKey, err := r.keys.EncryptionKey()
 	if err != nil {
 		return nil, err
 	}
 	cryptoBox := crypto.NewBox(encryptionKey)
-	return cryptoBox.DecryptContent(enc)
+	data, err := cryptoBox.DecryptContent(enc)
+	if err == nil {
+		return data, nil
+	}
+	previousKey, prevErr := r.keys.PreviousEncryptionKey()
+	if prevErr != nil {
+		return nil, err
+	}
+	return crypto.NewBox(previousKey).DecryptContent(enc)
+}
+
+// verifyObject checks that the given encrypted object decrypts and that
+// its content hashes to the given ID.
+func (r *ClientRepository) verifyObject(objectID string, enc []byte) error {
+	data, err := r.decryptContent(enc)
+	if err != nil {
+		return ErrObjectVerification
+	}
+	hash, err := r.hashChunk(data)
+	if err != nil {
+		return err
+	}
+	if hash != objectID {
+		return ErrObjectVerification
+	}
+	return nil
+}
+
+// AddVerifiedObject stores the encrypted object with the given ID if it
+// decrypts and if its content hashes to the ID; ErrObjectVerification is
+// returned otherwise. Objects received from the server are stored this
+// way as the server is not trusted.
+func (r *ClientRepository) AddVerifiedObject(objectID string, data io.Reader) error {
+	enc, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	err = r.verifyObject(objectID, enc)
+	if err != nil {
+		return err
+	}
+	return r.AddObject(objectID, bytes.NewReader(enc))
 }
 
 // writeMetadata writes the metadata object for the given path
 // to disk and returns its id.
-func (r *ClientRepository) writeMetadata(absPath string) (string, error) {
-	relPath, err := r.getRepoRelativePath(absPath)
+func (r *ClientRepository) writeMetadata(absPath string, movedFrom string) (string, error) {
+	m, err := r.itemMetadata(absPath)
 	if err != nil {
 		return "", err
 	}
-	m := Metadata{
-		RepoRelativePath: relPath,
-		Type:             MetadataTypeFile,
-	}
+	m.MovedFrom = movedFrom
+	return r.writeMetadataObject(m)
+}
+
+// writeMetadataObject writes the given metadata object to disk and
+// returns its id.
+func (r *ClientRepository) writeMetadataObject(m *Metadata) (string, error) {
 	raw := &bytes.Buffer{}
-	_, err = m.WriteTo(raw)
+	_, err := m.WriteTo(raw)
 	if err != nil {
 		return "", err
 	}
@@ -305,14 +547,76 @@ func (r *ClientRepository) CheckoutPath(absPath string) error {
 }
 
 // CheckoutAllPaths checks out all tracked paths.
+// Work dir files with changes unknown to the repository are preserved as
+// conflict copies.
 func (r *ClientRepository) CheckoutAllPaths() error {
 	nibStore := r.nibStore
-	nibs, err := nibStore.GetAll()
+	nibChannel, err := nibStore.GetAll()
 	if err != nil {
 		return err
 	}
-	for nib := range nibs {
-		err = r.checkoutNIB(nib)
+	nibs := []*nib.NIB{}
+	for n := range nibChannel {
+		nibs = append(nibs, n)
+	}
+	return r.checkoutNIBs(nibs)
+}
+
+// CheckoutNIBs writes the latest state of the NIBs with the given ids to
+// the working directory.
+func (r *ClientRepository) CheckoutNIBs(nibIDs []string) error {
+	nibs := []*nib.NIB{}
+	for _, nibID := range nibIDs {
+		n, err := r.nibStore.Get(nibID)
+		if err != nil {
+			return err
+		}
+		nibs = append(nibs, n)
+	}
+	return r.checkoutNIBs(nibs)
+}
+
+// pendingDeletion is a deleted item whose removal from the work dir has
+// been deferred.
+type pendingDeletion struct {
+	relPath string
+	nib     *nib.NIB
+}
+
+// deletionsDeepestFirst implements sort.Interface for pendingDeletion
+// slices; entries of a directory are sorted before the directory.
+type deletionsDeepestFirst []*pendingDeletion
+
+func (d deletionsDeepestFirst) Len() int           { return len(d) }
+func (d deletionsDeepestFirst) Less(i, j int) bool { return d[i].relPath > d[j].relPath }
+func (d deletionsDeepestFirst) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// checkoutNIBs checks out the given NIBs. Deletions are checked out last,
+// entries before their directories, so that deleted directories are
+// empty when they are removed.
+func (r *ClientRepository) checkoutNIBs(nibs []*nib.NIB) error {
+	deletions := deletionsDeepestFirst{}
+	for _, n := range nibs {
+		rev, err := n.LatestRevision()
+		if err == nil && rev.IsDeletion() {
+			metadata, err := r.metadataByID(rev.MetadataID)
+			if err != nil {
+				return err
+			}
+			deletions = append(deletions, &pendingDeletion{
+				relPath: metadata.RepoRelativePath,
+				nib:     n,
+			})
+			continue
+		}
+		err = r.checkoutNIBResolvingConflicts(n)
+		if err != nil {
+			return err
+		}
+	}
+	sort.Sort(deletions)
+	for _, deletion := range deletions {
+		err := r.checkoutNIBResolvingConflicts(deletion.nib)
 		if err != nil {
 			return err
 		}
@@ -320,19 +624,47 @@ func (r *ClientRepository) CheckoutAllPaths() error {
 	return nil
 }
 
-// pathHasConflictingChanges checks whether the item pointed to by absPath has any
-// changes not resolvable to a revision in the given NIB.
-func (r *ClientRepository) pathHasConflictingChanges(nib *nib.NIB, absPath string) (bool, error) {
-	workdirContentIDs, err := r.getFileChunkIDs(absPath)
-	if os.IsNotExist(err) {
-		return false, nil
+// checkoutNIBResolvingConflicts checks out the given NIB; conflicting
+// changes in the working directory are moved to a conflict copy.
+// NIBs which are not selected for checkout are skipped.
+func (r *ClientRepository) checkoutNIBResolvingConflicts(nib *nib.NIB) error {
+	selected, err := r.nibSelected(nib)
+	if err != nil || !selected {
+		return err
+	}
+	err = r.checkoutNIB(nib)
+	if err == ErrWorkDirConflict {
+		err = r.checkoutNIBPreservingWorkDir(nib)
 	}
+	return err
+}
+
+// checkoutNIBPreservingWorkDir moves the conflicting work dir file of the
+// given NIB to a conflict copy and checks out the NIB afterwards.
+func (r *ClientRepository) checkoutNIBPreservingWorkDir(nib *nib.NIB) error {
+	rev, err := nib.LatestRevision()
 	if err != nil {
-		return false, err
+		return err
 	}
+	metadata, err := r.metadataByID(rev.MetadataID)
+	if err != nil {
+		return err
+	}
+	err = r.preserveConflictingFile(filepath.Join(r.Path, metadata.RepoRelativePath))
+	if err != nil {
+		return err
+	}
+	return r.checkoutRevision(nib, rev)
+}
 
-	_, err = nib.LatestRevisionWithContent(workdirContentIDs)
-	return err != nil, nil
+// workDirContentIDs returns the content ids of the file pointed to by
+// absPath or nil if it does not exist.
+func (r *ClientRepository) workDirContentIDs(absPath string) ([]string, error) {
+	workdirContentIDs, err := r.getFileChunkIDs(absPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return workdirContentIDs, err
 }
 
 // checkoutNIB checks out the provided NIB's latest revision into the working directory.
@@ -357,6 +689,10 @@ func (r *ClientRepository) checkoutRevision(nib *nib.NIB, rev *nib.Revision) err
 		return errors.New("metadata lacks path")
 	}
 	absPath := filepath.Join(r.Path, relPath)
+	err = r.checkWorkDirPath(absPath)
+	if err != nil {
+		return err
+	}
 
 	targetDir := filepath.Dir(absPath)
 
@@ -364,44 +700,83 @@ func (r *ClientRepository) checkoutRevision(nib *nib.NIB, rev *nib.Revision) err
 	if err != nil && !os.IsExist(err) {
 		return err
 	}
-	err = nil
 
 	if len(rev.ContentIDs) > 0 {
-		writer, err := atomic.NewWriter(absPath, ".lara.checkout.", defaultFilePerms)
-		defer writer.Close()
+		workdirContentIDs, err := r.workDirContentIDs(absPath)
 		if err != nil {
-			writer.Abort()
 			return err
 		}
-
-		for _, contentID := range rev.ContentIDs {
-			content, err := r.readEncryptedObject(contentID)
-			_, err = writer.Write(content)
+		upToDate := helpers.StringsEqual(workdirContentIDs, rev.ContentIDs)
+		if workdirContentIDs != nil && !upToDate {
+			_, err = nib.LatestRevisionWithContent(workdirContentIDs)
+			if err != nil {
+				return ErrWorkDirConflict
+			}
+		}
+		if workdirContentIDs == nil {
+			upToDate, err = r.renameMovedItem(absPath, metadata, rev)
 			if err != nil {
-				writer.Abort()
 				return err
 			}
 		}
-
-		hasChanges, err := r.pathHasConflictingChanges(nib, absPath)
+		if !upToDate {
+			// only write on changes to avoid touching the file
+			err = r.writeItem(absPath, metadata, rev.ContentIDs)
+		} else {
+			err = applyAttributes(absPath, metadata)
+		}
 		if err != nil {
-			writer.Abort()
 			return err
 		}
-		if hasChanges {
-			writer.Abort()
-			return ErrWorkDirConflict
+		err = r.notifyNIBTracker(nib.ID, relPath)
+		if err != nil {
+			return err
 		}
-	} else if _, errExistCheck := os.Stat(absPath); errExistCheck == nil {
-		err = os.Remove(absPath)
+		return r.cacheContentIDs(absPath, rev.ContentIDs)
 	}
+	// directories which still contain untracked items are kept
+	return removeItem(absPath)
+}
 
-	return err
+// writeContentIDsTo decrypts the objects with the given content ids and
+// atomically writes their concatenated content to absPath.
+func (r *ClientRepository) writeContentIDsTo(absPath string, contentIDs []string) error {
+	writer, err := atomic.NewWriter(absPath, checkoutTmpPrefix, defaultFilePerms)
+	if err != nil {
+		return err
+	}
+
+	for _, contentID := range contentIDs {
+		content, err := r.readEncryptedObject(contentID)
+		if err == nil {
+			_, err = writer.Write(content)
+		}
+		if err != nil {
+			writer.Abort()
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
 }
 
-// AddItem adds a new file or directory to the repository.
+// AddItem adds a new file or directory to the repository. Ignored items
+// are skipped when adding a directory; adding an ignored item directly
+// returns ErrPathIgnored.
 func (r *ClientRepository) AddItem(absPath string) error {
-	stat, err := os.Stat(absPath)
+	ignores, err := r.newIgnoreMatcher()
+	if err != nil {
+		return err
+	}
+	return r.addItem(absPath, ignores, r.newMoveDetector())
+}
+
+// addItem adds the given file or directory unless it is ignored by the
+// given matcher. New items are recorded as moves if moves finds their
+// previous location.
+func (r *ClientRepository) addItem(absPath string, ignores *ignoreMatcher, moves *moveDetector) error {
+	// symlinks are added as links and not followed
+	stat, err := os.Lstat(absPath)
 	if err != nil {
 		return err
 	}
@@ -412,62 +787,142 @@ func (r *ClientRepository) AddItem(absPath string) error {
 	if isBelow {
 		return ErrRefusingWorkOnDotLara
 	}
-	if stat.IsDir() {
-		return r.addDirectory(absPath)
+	if absPath != r.Path {
+		relPath, err := r.getRepoRelativePath(absPath)
+		if err != nil {
+			return err
+		}
+		ignored, err := ignores.isIgnored(relPath, stat.IsDir())
+		if err != nil {
+			return err
+		}
+		if ignored {
+			return ErrPathIgnored
+		}
+	}
+	if !stat.IsDir() {
+		return r.addNode(absPath, moves)
 	}
-	return r.addFile(absPath)
+	if absPath != r.Path {
+		err = r.addNode(absPath, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return r.addDirectory(absPath, ignores, moves)
 }
 
-// addFile adds the given file from the working directory
-// to the repository
-func (r *ClientRepository) addFile(absPath string) error {
-	metadataID, err := r.writeMetadata(absPath)
+// addNode records the current state of the given file, symlink or
+// directory from the working directory in the repository; the entries
+// of directories are not added. If moves is not nil and the item is new,
+// it is recorded as a move from a vanished item with the same content.
+func (r *ClientRepository) addNode(absPath string, moves *moveDetector) error {
+	var err error
+	// files which did not change since they were hashed last are skipped
+	contentIDs, cached := r.cachedContentIDs(absPath)
+	if !cached {
+		contentIDs, err = r.writeFileToChunks(absPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	relPath, err := r.getRepoRelativePath(absPath)
 	if err != nil {
 		return err
 	}
-
-	contentIDs, err := r.writeFileToChunks(absPath)
+	n, err := r.nibForRelPath(relPath)
 	if err != nil {
 		return err
 	}
 
-	relPath, err := r.getRepoRelativePath(absPath)
+	var source *moveSource
+	movedFrom := ""
+	if moves != nil && isNewNIB(n) {
+		source, err = moves.find(relPath, contentIDs)
+		if err != nil {
+			return err
+		}
+		if source != nil {
+			movedFrom = source.relPath
+		}
+	}
+
+	changed, err := r.appendNodeRevision(n, absPath, contentIDs, movedFrom)
 	if err != nil {
 		return err
 	}
+	if !cached {
+		err = r.cacheContentIDs(absPath, contentIDs)
+		if err != nil {
+			return err
+		}
+	}
+	if !changed {
+		// do not record a new transaction for an unmodified file
+		return nil
+	}
+	if source != nil && source.live {
+		return r.recordMoves([]*nib.NIB{n}, []*nib.NIB{source.nib})
+	}
+	return r.nibStore.Add(n)
+}
+
+// nibForRelPath returns the stored NIB for the given repository relative
+// path or a new NIB without revisions if none is stored yet.
+func (r *ClientRepository) nibForRelPath(relPath string) (*nib.NIB, error) {
 	nibID, err := r.pathToNIBID(relPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if !r.nibStore.Exists(nibID) {
+		return &nib.NIB{ID: nibID}, nil
+	}
+	return r.nibStore.Get(nibID)
+}
 
-	nibStore := r.nibStore
+// appendNodeRevision writes the metadata of the item at absPath and
+// appends a revision with it and the given content ids to n unless the
+// latest revision is equal. It returns whether a revision was appended.
+// movedFrom is the previous path of a moved item.
+func (r *ClientRepository) appendNodeRevision(n *nib.NIB, absPath string, contentIDs []string, movedFrom string) (bool, error) {
+	metadata, err := r.itemMetadata(absPath)
+	if err != nil {
+		return false, err
+	}
+	metadata.MovedFrom = movedFrom
+	metadataID, err := r.writeMetadataObject(metadata)
+	if err != nil {
+		return false, err
+	}
+	relPath := metadata.RepoRelativePath
 
-	n := &nib.NIB{ID: nibID}
-	if nibStore.Exists(nibID) {
-		n, err = nibStore.Get(nibID)
-		if err != nil {
-			return err
-		}
+	deviceID, err := r.DeviceID()
+	if err != nil {
+		return false, err
 	}
 
 	rev := &nib.Revision{}
 	rev.MetadataID = metadataID
 	rev.ContentIDs = contentIDs
 	rev.UTCTimestamp = time.Now().UTC().Unix()
-	//FIXME: deviceID etc.
+	rev.DeviceID = deviceID
 	latestRev, err := n.LatestRevision()
 	if err != nil && err != nib.ErrNoRevision {
-		return err
+		return false, err
 	}
-	if err == nib.ErrNoRevision || !latestRev.HasSameContent(rev) {
-		n.AppendRevision(rev)
+	unchanged := err == nil && latestRev.HasSameContent(rev)
+	if err == nil && !unchanged && helpers.StringsEqual(latestRev.ContentIDs, contentIDs) {
+		unchanged, err = r.onlyRecordsMove(latestRev.MetadataID, metadata)
+		if err != nil {
+			return false, err
+		}
 	}
-	err = r.notifyNIBTracker(nibID, relPath)
-	if err != nil {
-		return err
+	if !unchanged {
+		n.AppendRevision(rev)
 	}
-
-	return nibStore.Add(n)
+	err = r.notifyNIBTracker(n.ID, relPath)
+	return !unchanged, err
 }
 
 // notifyNIBTracker adds the passed relative path to the NIBTracker of
@@ -480,16 +935,29 @@ func (r *ClientRepository) notifyNIBTracker(nibID string, relPath string) error
 	return tracker.Add(relPath, nibID)
 }
 
-// addDirectory walks the given directory and calls AddItem on each entry
-func (r *ClientRepository) addDirectory(absPath string) error {
+// addDirectory walks the given directory and calls addItem on each entry
+func (r *ClientRepository) addDirectory(absPath string, ignores *ignoreMatcher, moves *moveDetector) error {
 	files, err := ioutil.ReadDir(absPath)
 	if err != nil {
 		return err
 	}
 	for _, file := range files {
 		path := filepath.Join(absPath, file.Name())
-		err = r.AddItem(path)
-		if err == ErrRefusingWorkOnDotLara {
+		if !file.IsDir() {
+			relPath, err := r.getRepoRelativePath(path)
+			if err != nil {
+				return err
+			}
+			selected, err := r.isSelected(relPath)
+			if err != nil {
+				return err
+			}
+			if !selected {
+				continue
+			}
+		}
+		err = r.addItem(path, ignores, moves)
+		if err == ErrRefusingWorkOnDotLara || err == ErrPathIgnored {
 			continue
 		} else if err != nil {
 			return err
@@ -499,6 +967,7 @@ func (r *ClientRepository) addDirectory(absPath string) error {
 }
 
 // DeleteItem removes the given item with the passed absolute path.
+// Directories are removed recursively.
 func (r *ClientRepository) DeleteItem(absPath string) error {
 	relPath, err := r.getRepoRelativePath(absPath)
 	if err != nil {
@@ -520,37 +989,116 @@ func (r *ClientRepository) DeleteItem(absPath string) error {
 	if err != nil {
 		return err
 	}
+	if rev.IsDeletion() {
+		return r.deleteDirectory(absPath)
+	}
 
-	if !rev.IsDeletion() {
-		err = r.deleteFile(absPath)
-	} else {
-		err = r.deleteDirectory(absPath)
+	metadata, err := r.metadataByID(rev.MetadataID)
+	if err != nil {
+		return err
 	}
-	return err
+	if metadata.Type == MetadataTypeDir {
+		return r.deleteDirectory(absPath)
+	}
+	return r.deleteFile(absPath)
 }
 
-// deleteDirectory checks the ClientRepositories path lookup and removes all
-// files and directories in the given path.
+// deleteDirectory records the deletion of the directory at absPath and of
+// all tracked items below it in a single transaction. Work dir files are
+// only removed if they are unchanged; empty directories are removed.
 func (r *ClientRepository) deleteDirectory(absPath string) error {
 	relPath, err := r.getRepoRelativePath(absPath)
 	if err != nil {
 		return err
 	}
-	paths, err := r.nibTracker.SearchPrefix(relPath)
+	tracker, err := r.NIBTracker()
+	if err != nil {
+		return err
+	}
+	found, err := tracker.SearchPrefix(relPath)
 	if err != nil {
 		return err
 	}
 
-	for _, path := range paths {
-		err = r.DeleteItem(path.AbsPath())
+	deleted := []*nib.NIB{}
+	latestRevisions := map[string]*nib.Revision{}
+	for _, item := range found {
+		n, err := r.nibStore.Get(item.NIBID)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		latest, err := n.LatestRevision()
+		if err == nib.ErrNoRevision || (err == nil && latest.IsDeletion()) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		rev, err := r.deletionRevision(latest)
+		if err != nil {
+			return err
+		}
+		n.AppendRevision(rev)
+		deleted = append(deleted, n)
+		latestRevisions[filepath.Join(r.Path, item.Path)] = latest
+	}
+	if len(deleted) > 0 {
+		err = r.nibStore.AddAll(deleted)
 		if err != nil {
 			return err
 		}
 	}
 
+	for itemPath, latest := range latestRevisions {
+		err = r.removeUnchangedFile(itemPath, latest)
+		if err != nil {
+			return err
+		}
+	}
 	return path.CleanUpEmptyDirs(absPath)
 }
 
+// deletionRevision returns a new revision which marks the item of the
+// given latest revision as deleted by this device.
+func (r *ClientRepository) deletionRevision(latest *nib.Revision) (*nib.Revision, error) {
+	deviceID, err := r.DeviceID()
+	if err != nil {
+		return nil, err
+	}
+	deleteRevision := latest.Clone()
+	deleteRevision.ContentIDs = []string{}
+	deleteRevision.UTCTimestamp = time.Now().UTC().Unix()
+	deleteRevision.DeviceID = deviceID
+	return deleteRevision, nil
+}
+
+// removeUnchangedFile removes the work dir file at absPath if its content
+// matches the given revision.
+func (r *ClientRepository) removeUnchangedFile(absPath string, rev *nib.Revision) error {
+	if r.revisionIsFile(absPath, rev) && !rev.IsDeletion() {
+		os.Remove(absPath)
+	}
+
+	stat, fileErr := os.Lstat(absPath)
+	if fileErr != nil {
+		return nil
+	}
+
+	if !stat.IsDir() && rev != nil {
+		ids, err := r.fileToChunkIds(absPath)
+		if err != nil {
+			return err
+		}
+		if helpers.StringsEqual(ids, rev.ContentIDs) {
+			return os.Remove(absPath)
+		}
+	}
+	return nil
+}
+
 // deleteFile removes the specific file from the repository. Returns an error
 // if the file does not exist in the repository.
 func (r *ClientRepository) deleteFile(absPath string) error {
@@ -574,34 +1122,14 @@ func (r *ClientRepository) deleteFile(absPath string) error {
 		return err
 	}
 
-	deleteFileIfExisting := func() error {
-		if r.revisionIsFile(absPath, latestRevision) && !latestRevision.IsDeletion() {
-			os.Remove(absPath)
-		}
-
-		stat, fileErr := os.Stat(absPath)
-		if fileErr != nil {
-			return nil
-		}
-
-		if !stat.IsDir() && latestRevision != nil {
-			ids, err := r.fileToChunkIds(absPath)
-			if err != nil {
-				return err
-			}
-			if helpers.StringsEqual(ids, latestRevision.ContentIDs) {
-				return os.Remove(absPath)
-			}
-		}
-		return nil
-	}
-
 	if err == nil && latestRevision != nil {
 		if latestRevision.IsDeletion() {
-			return deleteFileIfExisting()
+			return r.removeUnchangedFile(absPath, latestRevision)
+		}
+		deleteRevision, err := r.deletionRevision(latestRevision)
+		if err != nil {
+			return err
 		}
-		deleteRevision := latestRevision.Clone()
-		deleteRevision.ContentIDs = []string{}
 		nibItem.AppendRevision(deleteRevision)
 		err = r.nibStore.Add(nibItem)
 		if err != nil {
@@ -609,12 +1137,12 @@ func (r *ClientRepository) deleteFile(absPath string) error {
 		}
 	}
 
-	return deleteFileIfExisting()
+	return r.removeUnchangedFile(absPath, latestRevision)
 }
 
 // revisionIsFile returns if the given revision is represented by the passed revision.
 func (r *ClientRepository) revisionIsFile(absPath string, rev *nib.Revision) bool {
-	stat, err := os.Stat(absPath)
+	stat, err := os.Lstat(absPath)
 	if rev == nil || (err == nil && stat.IsDir()) {
 		return false
 	}
@@ -663,10 +1191,16 @@ func (r *ClientRepository) NewAuthorization() (*Authorization, error) {
 		return nil, errors.New("Could not load private signing key.")
 	}
 
+	chunkerConfig, err := r.ChunkerConfig()
+	if err != nil {
+		return nil, errors.New("Could not load chunker configuration.")
+	}
+
 	auth := &Authorization{
 		EncryptionKey: encryptionKey,
 		HashingKey:    hashingKey,
 		SigningKey:    signatureKey,
+		Chunker:       chunkerConfig,
 	}
 
 	return auth, nil
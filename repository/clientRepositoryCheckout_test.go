@@ -42,7 +42,7 @@ func (t *RepositoryCheckoutTests) TestRemoveFile(c *C) {
 	t.addTestFile(c)
 	fullPath := t.fullPath
 
-	metadataID, err := t.r.writeMetadata(fullPath)
+	metadataID, err := t.r.writeMetadata(fullPath, "")
 	c.Assert(err, IsNil)
 
 	n := &nib.NIB{
@@ -100,3 +100,54 @@ func (t *RepositoryCheckoutTests) TestModifyFileWorkdirConflict(c *C) {
 	err = t.r.CheckoutPath(t.fullPath)
 	c.Assert(err, Equals, ErrWorkDirConflict)
 }
+
+func (t *RepositoryCheckoutTests) TestCheckoutAllPathsWorkdirConflict(c *C) {
+	t.addTestFile(c)
+
+	err := t.r.AddItem(t.fullPath)
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(t.fullPath, []byte("overwrittenstuff"), 0600)
+	c.Assert(err, IsNil)
+
+	err = t.r.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	readData, err := ioutil.ReadFile(t.fullPath)
+	c.Assert(err, IsNil)
+	c.Assert(readData, DeepEquals, t.testData)
+
+	copies, err := filepath.Glob(filepath.Join(t.dir, "foo (conflict copy from *).txt"))
+	c.Assert(err, IsNil)
+	c.Assert(len(copies), Equals, 1)
+	readData, err = ioutil.ReadFile(copies[0])
+	c.Assert(err, IsNil)
+	c.Assert(readData, DeepEquals, []byte("overwrittenstuff"))
+}
+
+func (t *RepositoryCheckoutTests) TestCheckoutNIBs(c *C) {
+	t.addTestFile(c)
+	err := t.r.AddItem(t.fullPath)
+	c.Assert(err, IsNil)
+	otherPath := filepath.Join(t.dir, "bar.txt")
+	err = ioutil.WriteFile(otherPath, []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+	err = t.r.AddItem(otherPath)
+	c.Assert(err, IsNil)
+
+	err = os.Remove(t.fullPath)
+	c.Assert(err, IsNil)
+	err = os.Remove(otherPath)
+	c.Assert(err, IsNil)
+
+	nibID, err := t.r.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	err = t.r.CheckoutNIBs([]string{nibID})
+	c.Assert(err, IsNil)
+
+	readData, err := ioutil.ReadFile(t.fullPath)
+	c.Assert(err, IsNil)
+	c.Assert(readData, DeepEquals, t.testData)
+	_, err = os.Stat(otherPath)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
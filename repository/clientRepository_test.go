@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository/chunker"
 )
 
 type ClientRepositoryTests struct {
@@ -31,6 +33,51 @@ func (t *ClientRepositoryTests) TestStateConfig(c *C) {
 	c.Assert(sc2.DefaultServer.URL, Equals, exp)
 }
 
+func (t *ClientRepositoryTests) TestChunkerConfigDefaultsToFixed(c *C) {
+	r := NewClient(t.dir)
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+
+	config, err := r.ChunkerConfig()
+	c.Assert(err, IsNil)
+	c.Assert(config, DeepEquals, chunker.NewFixedConfig(chunkSize))
+}
+
+func (t *ClientRepositoryTests) TestSetChunkerConfig(c *C) {
+	r := NewClient(t.dir)
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = r.SetChunkerConfig(chunker.DefaultConfig())
+	c.Assert(err, IsNil)
+
+	r2 := NewClient(t.dir)
+	config, err := r2.ChunkerConfig()
+	c.Assert(err, IsNil)
+	c.Assert(config, DeepEquals, chunker.DefaultConfig())
+}
+
+func (t *ClientRepositoryTests) TestSetChunkerConfigInvalid(c *C) {
+	r := NewClient(t.dir)
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = r.SetChunkerConfig(&chunker.Config{Algorithm: "foo"})
+	c.Assert(err, Equals, chunker.ErrUnknownAlgorithm)
+}
+
+func (t *ClientRepositoryTests) TestAuthorizationCarriesChunkerConfig(c *C) {
+	r := NewClient(t.dir)
+	err := r.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = r.CreateKeys()
+	c.Assert(err, IsNil)
+	err = r.SetChunkerConfig(chunker.DefaultConfig())
+	c.Assert(err, IsNil)
+
+	auth, err := r.NewAuthorization()
+	c.Assert(err, IsNil)
+	c.Assert(auth.Chunker, DeepEquals, chunker.DefaultConfig())
+}
+
 func (t *RepositoryTests) TestPathToNIBID(c *C) {
 	r := NewClient(t.dir)
 	err := r.CreateManagementDir()
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+const (
+	// conflictCopyTimeFormat is used when naming conflict copies.
+	conflictCopyTimeFormat = "2006-01-02 150405"
+)
+
+// localDeviceName returns the name of this device as it is used
+// in conflict copy names.
+func localDeviceName() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "unknown device"
+	}
+	return name
+}
+
+// conflictCopyPath returns the path of the sibling conflict copy for the
+// given absolute path, the device which produced the conflicting state
+// and its timestamp.
+func conflictCopyPath(absPath string, device string, utcTimestamp int64) string {
+	device = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == filepath.Separator {
+			return '_'
+		}
+		return r
+	}, device)
+	timestamp := time.Unix(utcTimestamp, 0).UTC().Format(conflictCopyTimeFormat)
+
+	ext := filepath.Ext(absPath)
+	base := strings.TrimSuffix(absPath, ext)
+	return fmt.Sprintf("%s (conflict copy from %s %s)%s", base, device, timestamp, ext)
+}
+
+// resolveNIBConflict merges the passed NIB, which could not be
+// fast-forwarded, with the local NIB of the same ID.
+//
+// Both histories are compared to their common ancestor. If only the local
+// side changed since then, the local revisions are replayed on top of the
+// other history. Otherwise the other side wins; the local state is written
+// to a conflict copy next to the original path and a merge revision is
+// recorded on top of both histories.
+// The resulting NIB is a descendant of the passed NIB and can therefore be
+// imported by every other device.
+func (r *ClientRepository) resolveNIBConflict(theirs *nib.NIB) error {
+	mine, err := r.GetNIB(theirs.ID)
+	if err != nil {
+		return err
+	}
+	myLatest, err := mine.LatestRevision()
+	if err != nil {
+		return err
+	}
+	theirLatest, err := theirs.LatestRevision()
+	if err != nil {
+		return err
+	}
+	base, err := mine.CommonAncestor(theirs)
+	if err != nil && err != nib.ErrNoRevision {
+		return err
+	}
+
+	merged := &nib.NIB{
+		ID:                   theirs.ID,
+		HistoryOffset:        theirs.HistoryOffset,
+		PrunedRevisionHashes: theirs.PrunedRevisionHashes,
+		Revisions:            append([]*nib.Revision{}, theirs.Revisions...),
+	}
+	localRevisions := mine.RevisionsAfter(base)
+
+	switch {
+	case myLatest.HasSameContent(theirLatest):
+		// both sides made the same change; nothing to merge.
+	case base != nil && theirLatest.HasSameContent(base):
+		merged.Revisions = append(merged.Revisions, localRevisions...)
+	default:
+		Log.Info("resolving NIB conflict", "nibID", theirs.ID)
+		err = r.writeConflictCopy(myLatest)
+		if err != nil {
+			return err
+		}
+		mergeRevision := theirLatest.Clone()
+		mergeRevision.UTCTimestamp = time.Now().UTC().Unix()
+		mergeRevision.DeviceID, err = r.DeviceID()
+		if err != nil {
+			return err
+		}
+		merged.Revisions = append(merged.Revisions, localRevisions...)
+		merged.AppendRevision(mergeRevision)
+	}
+
+	return r.nibStore.Add(merged)
+}
+
+// writeConflictCopy writes the content of the given losing revision to a
+// conflict copy next to its original path and adds it to the repository.
+func (r *ClientRepository) writeConflictCopy(rev *nib.Revision) error {
+	if rev.IsDeletion() {
+		return nil
+	}
+	metadata, err := r.metadataByID(rev.MetadataID)
+	if err != nil {
+		return err
+	}
+	if metadata.Type == MetadataTypeDir {
+		// directories have no content which could get lost.
+		return nil
+	}
+	device := localDeviceName()
+	if rev.DeviceID != "" {
+		device = r.deviceDisplayName(rev.DeviceID)
+	}
+	absPath := filepath.Join(r.Path, metadata.RepoRelativePath)
+	copyPath := conflictCopyPath(absPath, device, rev.UTCTimestamp)
+	err = r.checkWorkDirPath(copyPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(copyPath), defaultDirPerms)
+	if err != nil {
+		return err
+	}
+	err = r.writeItem(copyPath, metadata, rev.ContentIDs)
+	if err != nil {
+		return err
+	}
+	return r.AddItem(copyPath)
+}
+
+// preserveConflictingFile moves the work dir file at absPath, which has
+// changes unknown to the repository, to a conflict copy and adds it to
+// the repository.
+func (r *ClientRepository) preserveConflictingFile(absPath string) error {
+	copyPath := conflictCopyPath(absPath, localDeviceName(), time.Now().UTC().Unix())
+	Log.Info("preserving conflicting work dir file", "path", absPath, "copy", copyPath)
+	err := os.Rename(absPath, copyPath)
+	if err != nil {
+		return err
+	}
+	return r.AddItem(copyPath)
+}
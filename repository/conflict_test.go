@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ConflictTests{})
+
+// clientPairTests provides two client repositories sharing the same keys.
+type clientPairTests struct {
+	mine   *ClientRepository
+	theirs *ClientRepository
+}
+
+type ConflictTests struct {
+	clientPairTests
+}
+
+func (t *clientPairTests) SetUpTest(c *C) {
+	t.mine = NewClient(c.MkDir())
+	err := t.mine.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = t.mine.CreateKeys()
+	c.Assert(err, IsNil)
+
+	t.theirs = NewClient(c.MkDir())
+	err = t.theirs.CreateManagementDir()
+	c.Assert(err, IsNil)
+	auth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	err = t.theirs.SetKeysFromAuth(auth)
+	c.Assert(err, IsNil)
+	err = t.theirs.RegisterDevice()
+	c.Assert(err, IsNil)
+	t.shareDeviceRegistries(c)
+}
+
+// shareDeviceRegistries exchanges the device registries of both
+// repositories as it would happen through the server.
+func (t *clientPairTests) shareDeviceRegistries(c *C) {
+	data, err := t.theirs.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+	_, err = t.mine.MergeDeviceRegistryBytes(data)
+	c.Assert(err, IsNil)
+	data, err = t.mine.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+	_, err = t.theirs.MergeDeviceRegistryBytes(data)
+	c.Assert(err, IsNil)
+}
+
+// writeAndAdd writes the given content to the relative path in the
+// repository and adds it.
+func (t *clientPairTests) writeAndAdd(c *C, r *ClientRepository, relPath string, content []byte) {
+	absPath := filepath.Join(r.Path, relPath)
+	err := ioutil.WriteFile(absPath, content, 0600)
+	c.Assert(err, IsNil)
+	err = r.AddItem(absPath)
+	c.Assert(err, IsNil)
+}
+
+// setTimestamps rewrites the revision timestamps of the NIB for the given
+// relative path, oldest first.
+func (t *clientPairTests) setTimestamps(c *C, relPath string, timestamps ...int64) {
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, relPath))
+	c.Assert(err, IsNil)
+	c.Assert(n.Revisions, HasLen, len(timestamps))
+	for i, timestamp := range timestamps {
+		n.Revisions[i].UTCTimestamp = timestamp
+	}
+	err = t.mine.nibStore.Add(n)
+	c.Assert(err, IsNil)
+}
+
+// copyNIBData copies all objects of the NIB for the given relative path
+// from one repository to the other one and returns the signed NIB data.
+func (t *clientPairTests) copyNIBData(c *C, from, to *ClientRepository, relPath string) []byte {
+	nibID, err := from.pathToNIBID(relPath)
+	c.Assert(err, IsNil)
+	n, err := from.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	for _, objectID := range n.AllObjectIDs() {
+		reader, err := from.GetObjectData(objectID)
+		c.Assert(err, IsNil)
+		err = to.AddObject(objectID, reader)
+		reader.Close()
+		c.Assert(err, IsNil)
+	}
+	data, err := from.nibStore.GetBytes(nibID)
+	c.Assert(err, IsNil)
+	return data
+}
+
+// transferNIB copies the NIB for the given relative path including all
+// its objects from one repository to the other one.
+func (t *clientPairTests) transferNIB(c *C, from, to *ClientRepository, relPath string) error {
+	data := t.copyNIBData(c, from, to, relPath)
+	return to.AddNIBContent(bytes.NewReader(data))
+}
+
+// diverge creates a shared file and modifies it in both repositories.
+func (t *clientPairTests) diverge(c *C, mine, theirs []byte) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("base"))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	t.writeAndAdd(c, t.mine, "foo.txt", mine)
+	t.writeAndAdd(c, t.theirs, "foo.txt", theirs)
+}
+
+func (t *ConflictTests) conflictCopies(c *C) []string {
+	copies, err := filepath.Glob(filepath.Join(t.mine.Path, "foo (conflict copy from *).txt"))
+	c.Assert(err, IsNil)
+	return copies
+}
+
+func (t *ConflictTests) TestServerSideStillRejects(c *C) {
+	t.diverge(c, []byte("mine"), []byte("theirs"))
+	data := t.copyNIBData(c, t.theirs, t.mine, "foo.txt")
+	err := t.mine.Repository.AddNIBContent(bytes.NewReader(data))
+	c.Assert(err, Equals, ErrNIBConflict)
+}
+
+func (t *ConflictTests) TestBothChanged(c *C) {
+	t.diverge(c, []byte("mine"), []byte("theirs"))
+	err := t.transferNIB(c, t.theirs, t.mine, "foo.txt")
+	c.Assert(err, IsNil)
+
+	copies := t.conflictCopies(c)
+	c.Assert(len(copies), Equals, 1)
+	data, err := ioutil.ReadFile(copies[0])
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte("mine"))
+
+	err = t.mine.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	data, err = ioutil.ReadFile(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte("theirs"))
+
+	// the merged NIB has to be accepted by the other side again
+	err = t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+}
+
+func (t *ConflictTests) TestMergedNIBIsDescendant(c *C) {
+	t.diverge(c, []byte("mine"), []byte("theirs"))
+	err := t.transferNIB(c, t.theirs, t.mine, "foo.txt")
+	c.Assert(err, IsNil)
+
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	merged, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	theirs, err := t.theirs.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	c.Assert(theirs.IsParentOf(merged), Equals, true)
+}
+
+func (t *ConflictTests) TestOnlyLocalChanged(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("base"))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("mine"))
+
+	err = t.transferNIB(c, t.theirs, t.mine, "foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(len(t.conflictCopies(c)), Equals, 0)
+
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	merged, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	latest, err := merged.LatestRevision()
+	c.Assert(err, IsNil)
+	ids, err := t.mine.getFileChunkIDs(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(latest.ContentIDs, DeepEquals, ids)
+}
+
+func (t *ConflictTests) TestBothChangedAfterLocalRevert(c *C) {
+	modTime := time.Now().Add(-time.Hour)
+	writeAndAdd := func(r *ClientRepository, content string) {
+		absPath := filepath.Join(r.Path, "foo.txt")
+		err := ioutil.WriteFile(absPath, []byte(content), 0600)
+		c.Assert(err, IsNil)
+		// the same content yields the same metadata again
+		err = os.Chtimes(absPath, modTime, modTime)
+		c.Assert(err, IsNil)
+		err = r.AddItem(absPath)
+		c.Assert(err, IsNil)
+	}
+	writeAndAdd(t.mine, "base")
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	writeAndAdd(t.mine, "changed")
+	writeAndAdd(t.mine, "base")
+	writeAndAdd(t.theirs, "theirs")
+
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	mine, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	c.Assert(mine.Revisions[2].HasSameContent(mine.Revisions[0]), Equals, true)
+
+	err = t.transferNIB(c, t.theirs, t.mine, "foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(len(t.conflictCopies(c)), Equals, 1)
+
+	// the local revisions after the shared one are kept once
+	merged, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	c.Assert(merged.Revisions, HasLen, 5)
+	c.Assert(merged.Revisions[2].HasSameContent(mine.Revisions[1]), Equals, true)
+	c.Assert(merged.Revisions[3].HasSameContent(mine.Revisions[2]), Equals, true)
+}
+
+func (t *ConflictTests) TestLocalDeletionLoses(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("base"))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	err = t.mine.DeleteItem(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.theirs, "foo.txt", []byte("theirs"))
+
+	err = t.transferNIB(c, t.theirs, t.mine, "foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(len(t.conflictCopies(c)), Equals, 0)
+
+	err = t.mine.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadFile(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte("theirs"))
+}
+
+func (t *ConflictTests) TestConflictCopyPath(c *C) {
+	path := conflictCopyPath(filepath.Join("dir", "foo.txt"), "my/host", 0)
+	c.Assert(path, Equals,
+		filepath.Join("dir", "foo (conflict copy from my_host 1970-01-01 000000).txt"))
+}
+
+func (t *ConflictTests) TestConflictCopyPathNoExtension(c *C) {
+	path := conflictCopyPath("foo", "host", 0)
+	c.Assert(path, Equals, "foo (conflict copy from host 1970-01-01 000000)")
+}
@@ -3,6 +3,7 @@ package content
 import (
 	"errors"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -49,10 +50,10 @@ func (f *FileStorage) CreateDir() error {
 func (f *FileStorage) storagePathFor(contentID string) (string, error) {
 	p := path.Join(f.path, contentID)
 	p = filepath.Clean(p)
-	root := f.path
-	/*if len(root) > 1 && root[len(root)-1] != filepath.Separator {
-		root += filepath.Separator
-	}*/
+	root := filepath.Clean(f.path)
+	if !strings.HasSuffix(root, string(filepath.Separator)) {
+		root += string(filepath.Separator)
+	}
 	if !strings.HasPrefix(p, root) {
 		return "", ErrInvalidPath
 	}
@@ -123,3 +124,20 @@ func (f *FileStorage) Delete(contentID string) error {
 	}
 	return os.Remove(p)
 }
+
+// List returns information about all stored entries. Temporary files of
+// writes which are still in progress are skipped.
+func (f *FileStorage) List() ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(f.path)
+	if err != nil {
+		return nil, err
+	}
+	res := []os.FileInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		res = append(res, entry)
+	}
+	return res, nil
+}
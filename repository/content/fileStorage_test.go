@@ -53,6 +53,20 @@ func (t *FileStorageTests) TestSetDangerousName(c *C) {
 	}
 }
 
+func (t *FileStorageTests) TestSetSiblingDirectory(c *C) {
+	base := c.MkDir()
+	storage := NewFileStorage(path.Join(base, "objects"))
+	err := storage.CreateDir()
+	c.Assert(err, IsNil)
+	err = os.Mkdir(path.Join(base, "objectsX"), 0700)
+	c.Assert(err, IsNil)
+
+	err = storage.Set("../objectsX/foo", t.testReader())
+	c.Assert(err, Equals, ErrInvalidPath)
+	_, err = os.Stat(path.Join(base, "objectsX", "foo"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
 func (t *FileStorageTests) TestSet(c *C) {
 	err := t.setData()
 	c.Assert(err, IsNil)
@@ -121,3 +135,23 @@ func (t *FileStorageTests) TestDeleteError(c *C) {
 	err := t.storage.Delete(t.blobID())
 	c.Assert(err, NotNil)
 }
+
+func (t *FileStorageTests) TestList(c *C) {
+	t.setData()
+	err := ioutil.WriteFile(path.Join(t.dir, ".lara.tmp"), t.data, 0600)
+	c.Assert(err, IsNil)
+	err = os.Mkdir(path.Join(t.dir, "subdir"), 0700)
+	c.Assert(err, IsNil)
+
+	entries, err := t.storage.List()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].Name(), Equals, t.blobID())
+	c.Assert(entries[0].Size(), Equals, int64(len(t.data)))
+}
+
+func (t *FileStorageTests) TestListError(c *C) {
+	os.RemoveAll(t.dir)
+	_, err := t.storage.List()
+	c.Assert(err, NotNil)
+}
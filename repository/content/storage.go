@@ -2,6 +2,7 @@ package content
 
 import (
 	"io"
+	"os"
 )
 
 // Storage is the generic interface for implementations of
@@ -18,3 +19,11 @@ type Storage interface {
 	// Delete removes the data with the given contentID from the store.
 	Delete(contentID string) error
 }
+
+// ListableStorage is a Storage which is able to enumerate its entries.
+type ListableStorage interface {
+	Storage
+	// List returns information about all stored entries; the name of
+	// each entry is its contentID.
+	List() ([]os.FileInfo, error)
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hoffie/larasync/repository/odf"
+)
+
+// deviceIDDisplayLength is the number of characters of a device ID which
+// are used if no device name is known.
+const deviceIDDisplayLength = 8
+
+// Device describes a device which may sign requests and NIBs on behalf of
+// the repository with its own key.
+type Device struct {
+	PublicKey [PublicKeySize]byte
+	// EncryptionPublicKey is used to hand new repository keys to the
+	// device; it is all zeroes for devices which did not publish one.
+	EncryptionPublicKey [BoxKeySize]byte
+	Name                string
+	// UTCTimestamp is the time of the latest registration of the device.
+	UTCTimestamp int64
+}
+
+// DeviceIDFromPublicKey returns the device ID which belongs to the given
+// device signing public key.
+func DeviceIDFromPublicKey(pubKey [PublicKeySize]byte) string {
+	return hex.EncodeToString(pubKey[:])
+}
+
+// ID returns the identifier of this device as it is used in revisions.
+func (d *Device) ID() string {
+	return DeviceIDFromPublicKey(d.PublicKey)
+}
+
+// HasEncryptionKey returns whether the device has published a key which
+// can be used to encrypt data for it.
+func (d *Device) HasEncryptionKey() bool {
+	return d.EncryptionPublicKey != [BoxKeySize]byte{}
+}
+
+// DeviceRegistry lists all devices of a repository. It is signed by the
+// repository signing key.
+type DeviceRegistry struct {
+	Devices []*Device
+}
+
+// Add adds the given device to the registry, replacing any entry with the
+// same public key.
+func (dr *DeviceRegistry) Add(device *Device) {
+	for i, existing := range dr.Devices {
+		if existing.PublicKey == device.PublicKey {
+			dr.Devices[i] = device
+			return
+		}
+	}
+	dr.Devices = append(dr.Devices, device)
+}
+
+// Get returns the device with the given ID or nil if it is not registered.
+func (dr *DeviceRegistry) Get(id string) *Device {
+	for _, device := range dr.Devices {
+		if device.ID() == id {
+			return device
+		}
+	}
+	return nil
+}
+
+// Remove removes the device with the given ID from the registry. It
+// returns whether the device has been registered.
+func (dr *DeviceRegistry) Remove(id string) bool {
+	for i, device := range dr.Devices {
+		if device.ID() == id {
+			dr.Devices = append(dr.Devices[:i], dr.Devices[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds all devices of the other registry which are not part of this
+// registry yet and replaces devices which have been registered again in
+// the other registry. It returns whether anything has changed.
+func (dr *DeviceRegistry) Merge(other *DeviceRegistry) bool {
+	changed := false
+	for _, device := range other.Devices {
+		existing := dr.Get(device.ID())
+		if existing == nil || existing.UTCTimestamp < device.UTCTimestamp {
+			dr.Add(device)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Contains returns whether all devices of the other registry are part of
+// this registry in at least the same version.
+func (dr *DeviceRegistry) Contains(other *DeviceRegistry) bool {
+	for _, device := range other.Devices {
+		existing := dr.Get(device.ID())
+		if existing == nil || existing.UTCTimestamp < device.UTCTimestamp {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicKeys returns the signing public keys of all registered devices.
+func (dr *DeviceRegistry) PublicKeys() [][PublicKeySize]byte {
+	keys := [][PublicKeySize]byte{}
+	for _, device := range dr.Devices {
+		keys = append(keys, device.PublicKey)
+	}
+	return keys
+}
+
+// DisplayName returns a short human-readable name for the device with the
+// given ID.
+func (dr *DeviceRegistry) DisplayName(id string) string {
+	device := dr.Get(id)
+	if device != nil && device.Name != "" {
+		return device.Name
+	}
+	if len(id) > deviceIDDisplayLength {
+		return id[:deviceIDDisplayLength]
+	}
+	return id
+}
+
+// toPb converts this DeviceRegistry to a protobuf DeviceRegistry.
+func (dr *DeviceRegistry) toPb() *odf.DeviceRegistry {
+	pb := &odf.DeviceRegistry{}
+	for _, device := range dr.Devices {
+		pubKey := make([]byte, PublicKeySize)
+		copy(pubKey, device.PublicKey[:])
+		pbDevice := &odf.Device{
+			PublicKey:    pubKey,
+			Name:         proto.String(device.Name),
+			UTCTimestamp: proto.Int64(device.UTCTimestamp),
+		}
+		if device.HasEncryptionKey() {
+			encryptionKey := make([]byte, BoxKeySize)
+			copy(encryptionKey, device.EncryptionPublicKey[:])
+			pbDevice.EncryptionPublicKey = encryptionKey
+		}
+		pb.Devices = append(pb.Devices, pbDevice)
+	}
+	return pb
+}
+
+// ReadFrom fills this DeviceRegistry's data with the contents supplied by
+// the binary representation available through the given reader.
+func (dr *DeviceRegistry) ReadFrom(r io.Reader) (int64, error) {
+	buf := &bytes.Buffer{}
+	read, err := io.Copy(buf, r)
+	if err != nil {
+		return read, err
+	}
+	pb := &odf.DeviceRegistry{}
+	err = proto.Unmarshal(buf.Bytes(), pb)
+	if err != nil {
+		return read, err
+	}
+
+	dr.Devices = []*Device{}
+	for _, pbDevice := range pb.GetDevices() {
+		if len(pbDevice.GetPublicKey()) != PublicKeySize {
+			return read, ErrInvalidPublicKeySize
+		}
+		device := &Device{
+			Name:         pbDevice.GetName(),
+			UTCTimestamp: pbDevice.GetUTCTimestamp(),
+		}
+		copy(device.PublicKey[:], pbDevice.GetPublicKey())
+		encryptionKey := pbDevice.GetEncryptionPublicKey()
+		if encryptionKey != nil {
+			if len(encryptionKey) != BoxKeySize {
+				return read, ErrInvalidPublicKeySize
+			}
+			copy(device.EncryptionPublicKey[:], encryptionKey)
+		}
+		dr.Devices = append(dr.Devices, device)
+	}
+	return read, nil
+}
+
+// WriteTo encodes this DeviceRegistry to the supplied Writer in binary form.
+// Returns the number of bytes written and an error if applicable.
+func (dr *DeviceRegistry) WriteTo(w io.Writer) (int64, error) {
+	buf, err := proto.Marshal(dr.toPb())
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(w, bytes.NewBuffer(buf))
+	return written, err
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/hoffie/larasync/helpers/crypto"
+	"github.com/hoffie/larasync/repository/content"
+)
+
+const (
+	// id of the signed device registry in the storage
+	deviceRegistryName = "devices"
+)
+
+// DeviceStore handles the storage of the signed device registry.
+type DeviceStore struct {
+	storage *content.ByteStorage
+	keys    *KeyStore
+}
+
+// newDeviceStore returns a new DeviceStore which stores its data in the
+// given storage and verifies it with the repository signing key.
+func newDeviceStore(storage content.Storage, keys *KeyStore) *DeviceStore {
+	return &DeviceStore{
+		storage: content.NewByteStorage(storage),
+		keys:    keys,
+	}
+}
+
+// Get returns the verified device registry. An empty registry is
+// returned if none has been stored yet.
+func (s *DeviceStore) Get() (*DeviceRegistry, error) {
+	data, err := s.GetBytes()
+	if os.IsNotExist(err) {
+		return &DeviceRegistry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.VerifyAndParseBytes(data)
+}
+
+// GetBytes returns the signed representation of the device registry.
+func (s *DeviceStore) GetBytes() ([]byte, error) {
+	return s.storage.GetBytes(deviceRegistryName)
+}
+
+// VerifyAndParseBytes verifies the given signed device registry data
+// against the repository signing key and returns the parsed registry.
+func (s *DeviceStore) VerifyAndParseBytes(data []byte) (*DeviceRegistry, error) {
+	pubKey, err := s.keys.SigningPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return s.verifyAndParseBytesWith(pubKey, data)
+}
+
+// verifyAndParseBytesWith verifies the given signed device registry data
+// against the given public key and returns the parsed registry.
+func (s *DeviceStore) verifyAndParseBytesWith(pubKey [PublicKeySize]byte, data []byte) (*DeviceRegistry, error) {
+	signed, ok := crypto.VerifySignedData([][PublicKeySize]byte{pubKey}, data)
+	if !ok {
+		return nil, ErrSignatureVerification
+	}
+	registry := &DeviceRegistry{}
+	_, err := registry.ReadFrom(bytes.NewReader(signed))
+	if err != nil {
+		return nil, ErrUnMarshalling
+	}
+	return registry, nil
+}
+
+// SetBytes stores the given signed device registry after verifying it.
+func (s *DeviceStore) SetBytes(data []byte) error {
+	_, err := s.VerifyAndParseBytes(data)
+	if err != nil {
+		return err
+	}
+	return s.storage.SetBytes(deviceRegistryName, data)
+}
+
+// Set signs the given registry with the repository signing key and
+// stores it.
+func (s *DeviceStore) Set(registry *DeviceRegistry) error {
+	key, err := s.keys.SigningPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	sw := crypto.NewSigningWriter(key, buf)
+	_, err = registry.WriteTo(sw)
+	if err != nil {
+		return err
+	}
+	err = sw.Finalize()
+	if err != nil {
+		return err
+	}
+	return s.storage.SetBytes(deviceRegistryName, buf.Bytes())
+}
+
+// AcceptedPublicKeys returns all public keys whose signatures are accepted
+// for this repository: the repository signing key and the keys of all
+// registered devices.
+func (s *DeviceStore) AcceptedPublicKeys() ([][PublicKeySize]byte, error) {
+	pubKey, err := s.keys.SigningPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+	return append([][PublicKeySize]byte{pubKey}, registry.PublicKeys()...), nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DeviceStoreTests{})
+
+type DeviceStoreTests struct {
+	clientPairTests
+}
+
+func (t *DeviceStoreTests) TestRegisterDevice(c *C) {
+	registry, err := t.mine.DeviceRegistry()
+	c.Assert(err, IsNil)
+	c.Assert(registry.Devices, HasLen, 2)
+
+	deviceID, err := t.mine.DeviceID()
+	c.Assert(err, IsNil)
+	device := registry.Get(deviceID)
+	c.Assert(device, NotNil)
+	c.Assert(device.Name, Equals, localDeviceName())
+	c.Assert(device.HasEncryptionKey(), Equals, true)
+
+	registered, err := t.mine.IsDeviceRegistered()
+	c.Assert(err, IsNil)
+	c.Assert(registered, Equals, true)
+}
+
+func (t *DeviceStoreTests) TestAcceptedPublicKeys(c *C) {
+	keys, err := t.mine.AcceptedSigningPublicKeys()
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 3)
+	repositoryKey, err := t.mine.GetSigningPublicKey()
+	c.Assert(err, IsNil)
+	c.Assert(keys[0], Equals, repositoryKey)
+}
+
+func (t *DeviceStoreTests) TestRejectsForeignRegistry(c *C) {
+	other := NewClient(c.MkDir())
+	err := other.CreateManagementDir()
+	c.Assert(err, IsNil)
+	err = other.CreateKeys()
+	c.Assert(err, IsNil)
+	data, err := other.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+
+	err = t.mine.SetDeviceRegistryBytes(data)
+	c.Assert(err, Equals, ErrSignatureVerification)
+	_, err = t.mine.MergeDeviceRegistryBytes(data)
+	c.Assert(err, Equals, ErrSignatureVerification)
+}
+
+func (t *DeviceStoreTests) TestMergeDeviceRegistryBytes(c *C) {
+	incomplete, err := t.mine.MergeDeviceRegistryBytes(nil)
+	c.Assert(err, IsNil)
+	c.Assert(incomplete, Equals, true)
+
+	data, err := t.mine.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+	incomplete, err = t.theirs.MergeDeviceRegistryBytes(data)
+	c.Assert(err, IsNil)
+	c.Assert(incomplete, Equals, false)
+}
+
+func (t *DeviceStoreTests) TestRevisionDeviceID(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	n, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+	rev, err := n.LatestRevision()
+	c.Assert(err, IsNil)
+	deviceID, err := t.mine.DeviceID()
+	c.Assert(err, IsNil)
+	c.Assert(deviceID, Not(Equals), "")
+	c.Assert(rev.DeviceID, Equals, deviceID)
+}
+
+func (t *DeviceStoreTests) TestNIBFromUnregisteredDevice(c *C) {
+	third := NewClient(c.MkDir())
+	err := third.CreateManagementDir()
+	c.Assert(err, IsNil)
+	auth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	err = third.SetKeysFromAuth(auth)
+	c.Assert(err, IsNil)
+	err = third.RegisterDevice()
+	c.Assert(err, IsNil)
+
+	t.writeAndAdd(c, third, "foo.txt", []byte("foo"))
+	data := t.copyNIBData(c, third, t.mine, "foo.txt")
+	err = t.mine.AddNIBContent(bytes.NewReader(data))
+	c.Assert(err, Equals, ErrSignatureVerification)
+
+	registry, err := third.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+	_, err = t.mine.MergeDeviceRegistryBytes(registry)
+	c.Assert(err, IsNil)
+	err = t.mine.AddNIBContent(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DeviceRegistryTests{})
+
+type DeviceRegistryTests struct{}
+
+func (t *DeviceRegistryTests) device(b byte, name string) *Device {
+	d := &Device{Name: name, UTCTimestamp: 1}
+	d.PublicKey[0] = b
+	return d
+}
+
+func (t *DeviceRegistryTests) TestAddReplaces(c *C) {
+	dr := &DeviceRegistry{}
+	dr.Add(t.device(1, "first"))
+	dr.Add(t.device(2, "second"))
+	dr.Add(t.device(1, "renamed"))
+	c.Assert(dr.Devices, HasLen, 2)
+	c.Assert(dr.Get(t.device(1, "").ID()).Name, Equals, "renamed")
+}
+
+func (t *DeviceRegistryTests) TestMergeAndContains(c *C) {
+	dr := &DeviceRegistry{}
+	dr.Add(t.device(1, "first"))
+	other := &DeviceRegistry{}
+	other.Add(t.device(2, "second"))
+
+	c.Assert(dr.Contains(other), Equals, false)
+	c.Assert(dr.Merge(other), Equals, true)
+	c.Assert(dr.Contains(other), Equals, true)
+	c.Assert(dr.Merge(other), Equals, false)
+	c.Assert(dr.Devices, HasLen, 2)
+}
+
+func (t *DeviceRegistryTests) TestMergeReRegistered(c *C) {
+	dr := &DeviceRegistry{}
+	dr.Add(t.device(1, "old"))
+	other := &DeviceRegistry{}
+	updated := t.device(1, "new")
+	updated.UTCTimestamp = 2
+	other.Add(updated)
+
+	c.Assert(dr.Contains(other), Equals, false)
+	c.Assert(other.Contains(dr), Equals, true)
+	c.Assert(dr.Merge(other), Equals, true)
+	c.Assert(dr.Devices, HasLen, 1)
+	c.Assert(dr.Devices[0].Name, Equals, "new")
+	c.Assert(other.Merge(dr), Equals, false)
+}
+
+func (t *DeviceRegistryTests) TestRemove(c *C) {
+	dr := &DeviceRegistry{}
+	dr.Add(t.device(1, "first"))
+	dr.Add(t.device(2, "second"))
+	c.Assert(dr.Remove(t.device(1, "").ID()), Equals, true)
+	c.Assert(dr.Remove(t.device(1, "").ID()), Equals, false)
+	c.Assert(dr.Devices, HasLen, 1)
+	c.Assert(dr.Devices[0].Name, Equals, "second")
+}
+
+func (t *DeviceRegistryTests) TestDisplayName(c *C) {
+	dr := &DeviceRegistry{}
+	named := t.device(1, "laptop")
+	unnamed := t.device(2, "")
+	dr.Add(named)
+	dr.Add(unnamed)
+	c.Assert(dr.DisplayName(named.ID()), Equals, "laptop")
+	c.Assert(dr.DisplayName(unnamed.ID()), Equals, unnamed.ID()[:deviceIDDisplayLength])
+	c.Assert(dr.DisplayName("abc"), Equals, "abc")
+}
+
+func (t *DeviceRegistryTests) TestSerialization(c *C) {
+	dr := &DeviceRegistry{}
+	dr.Add(t.device(1, "first"))
+	withKey := t.device(2, "second")
+	withKey.EncryptionPublicKey[0] = 3
+	dr.Add(withKey)
+	buf := &bytes.Buffer{}
+	_, err := dr.WriteTo(buf)
+	c.Assert(err, IsNil)
+
+	read := &DeviceRegistry{}
+	_, err = read.ReadFrom(buf)
+	c.Assert(err, IsNil)
+	c.Assert(read, DeepEquals, dr)
+}
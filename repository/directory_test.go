@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DirectoryTests{})
+
+type DirectoryTests struct {
+	clientPairTests
+}
+
+func (t *DirectoryTests) mkdirAndAdd(c *C, r *ClientRepository, relPath string) {
+	absPath := filepath.Join(r.Path, relPath)
+	err := os.MkdirAll(absPath, 0700)
+	c.Assert(err, IsNil)
+	err = r.AddItem(absPath)
+	c.Assert(err, IsNil)
+}
+
+func (t *DirectoryTests) transferAndCheckout(c *C, relPaths ...string) {
+	for _, relPath := range relPaths {
+		err := t.transferNIB(c, t.mine, t.theirs, relPath)
+		c.Assert(err, IsNil)
+	}
+	err := t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+}
+
+func (t *DirectoryTests) theirsExists(relPath string) bool {
+	_, err := os.Lstat(filepath.Join(t.theirs.Path, relPath))
+	return err == nil
+}
+
+func (t *DirectoryTests) TestEmptyDirectory(c *C) {
+	t.mkdirAndAdd(c, t.mine, "empty")
+	t.transferAndCheckout(c, "empty")
+
+	stat, err := os.Stat(filepath.Join(t.theirs.Path, "empty"))
+	c.Assert(err, IsNil)
+	c.Assert(stat.IsDir(), Equals, true)
+}
+
+func (t *DirectoryTests) TestDeleteEmptyDirectory(c *C) {
+	t.mkdirAndAdd(c, t.mine, "empty")
+	t.transferAndCheckout(c, "empty")
+
+	err := t.mine.DeleteItem(filepath.Join(t.mine.Path, "empty"))
+	c.Assert(err, IsNil)
+	t.transferAndCheckout(c, "empty")
+
+	c.Assert(t.theirsExists("empty"), Equals, false)
+}
+
+func (t *DirectoryTests) TestMoveEmptyDirectory(c *C) {
+	t.mkdirAndAdd(c, t.mine, "old")
+	t.transferAndCheckout(c, "old")
+
+	oldPath := filepath.Join(t.mine.Path, "old")
+	newPath := filepath.Join(t.mine.Path, "new")
+	err := os.Rename(oldPath, newPath)
+	c.Assert(err, IsNil)
+	err = t.mine.AddItem(newPath)
+	c.Assert(err, IsNil)
+	err = t.mine.DeleteItem(oldPath)
+	c.Assert(err, IsNil)
+	t.transferAndCheckout(c, "old", "new")
+
+	c.Assert(t.theirsExists("old"), Equals, false)
+	c.Assert(t.theirsExists("new"), Equals, true)
+}
+
+func (t *DirectoryTests) TestDeleteTreeIsOneTransaction(c *C) {
+	t.mkdirAndAdd(c, t.mine, "dir")
+	t.mkdirAndAdd(c, t.mine, filepath.Join("dir", "sub"))
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "a.txt"), []byte("a"))
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "sub", "b.txt"), []byte("b"))
+	before, err := t.mine.transactionManager.All()
+	c.Assert(err, IsNil)
+
+	err = t.mine.DeleteItem(filepath.Join(t.mine.Path, "dir"))
+	c.Assert(err, IsNil)
+
+	after, err := t.mine.transactionManager.All()
+	c.Assert(err, IsNil)
+	c.Assert(after, HasLen, len(before)+1)
+	c.Assert(after[len(after)-1].NIBIDs, HasLen, 4)
+	for _, relPath := range []string{"dir", filepath.Join("dir", "sub"),
+		filepath.Join("dir", "a.txt"), filepath.Join("dir", "sub", "b.txt")} {
+		n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, relPath))
+		c.Assert(err, IsNil)
+		rev, err := n.LatestRevision()
+		c.Assert(err, IsNil)
+		c.Assert(rev.IsDeletion(), Equals, true)
+	}
+	_, err = os.Lstat(filepath.Join(t.mine.Path, "dir"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *DirectoryTests) TestDeletedTreeCheckedOut(c *C) {
+	t.mkdirAndAdd(c, t.mine, "dir")
+	t.mkdirAndAdd(c, t.mine, filepath.Join("dir", "sub"))
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "sub", "b.txt"), []byte("b"))
+	relPaths := []string{"dir", filepath.Join("dir", "sub"), filepath.Join("dir", "sub", "b.txt")}
+	t.transferAndCheckout(c, relPaths...)
+	c.Assert(t.theirsExists(filepath.Join("dir", "sub", "b.txt")), Equals, true)
+
+	err := t.mine.DeleteItem(filepath.Join(t.mine.Path, "dir"))
+	c.Assert(err, IsNil)
+	t.transferAndCheckout(c, relPaths...)
+
+	c.Assert(t.theirsExists("dir"), Equals, false)
+}
+
+func (t *DirectoryTests) TestDirectoryStatusClean(c *C) {
+	t.mkdirAndAdd(c, t.mine, "empty")
+
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.Files, HasLen, 1)
+	c.Assert(status.Files[0].Path, Equals, "empty")
+	c.Assert(status.Files[0].State, Equals, FileClean)
+}
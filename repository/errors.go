@@ -28,6 +28,45 @@ var (
 	ErrRefusingWorkOnDotLara = errors.New("will not work on .lara")
 	// ErrWorkDirConflict is being returned if a checkout path has changed data.
 	ErrWorkDirConflict = errors.New("workdir conflict")
+	// ErrRevisionNotFound is returned if a requested revision of an item
+	// does not exist.
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrRevisionDeleted is returned when trying to restore a revision
+	// which marks its item as deleted to a new path.
+	ErrRevisionDeleted = errors.New("revision marks the item as deleted")
+	// ErrDeviceNotFound is returned if a device is not part of the device
+	// registry.
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrRevokingOwnDevice is returned when trying to revoke the device
+	// which performs the revocation.
+	ErrRevokingOwnDevice = errors.New("refusing to revoke this device")
+	// ErrInvalidRetentionPolicy is returned if a retention policy contains
+	// negative values.
+	ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+	// ErrNotThinClient is returned when trying to evict objects of a
+	// repository which keeps all objects locally.
+	ErrNotThinClient = errors.New("repository is not a thin client")
+	// ErrInvalidCacheSize is returned if a negative object cache size is
+	// configured.
+	ErrInvalidCacheSize = errors.New("invalid cache size")
+	// ErrPathIgnored is returned when trying to add an item which is
+	// excluded by an ignore file.
+	ErrPathIgnored = errors.New("path is ignored")
+	// ErrMoveTargetExists is returned when trying to move an item to a
+	// path which already exists.
+	ErrMoveTargetExists = errors.New("move target exists")
+	// ErrDuplicateNIB is returned if a NIB occurs more than once in
+	// a set of NIBs which is added at once.
+	ErrDuplicateNIB = errors.New("NIB occurs more than once")
+	// ErrObjectVerification is returned if an object cannot be decrypted
+	// or if its content does not hash to its ID.
+	ErrObjectVerification = errors.New("object does not match its ID")
+	// ErrNoObjectFetcher is returned if objects have to be fetched but
+	// no fetcher has been configured.
+	ErrNoObjectFetcher = errors.New("no object fetcher configured")
+	// ErrUnsafeWorkDirPath is returned when trying to write an item whose
+	// path leaves the work dir or passes a symlink.
+	ErrUnsafeWorkDirPath = errors.New("path leaves the work dir")
 )
 
 // NewErrNIBContentMissing returns a new ErrNIBContentMissing Error with the passed
@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// FsckProblemKind classifies the problems which are found by the
+// integrity check.
+type FsckProblemKind string
+
+const (
+	// FsckTransactionChain marks a break in the chain of transaction
+	// containers or transactions.
+	FsckTransactionChain FsckProblemKind = "transaction"
+	// FsckBadNIB marks a NIB which cannot be read or verified.
+	FsckBadNIB FsckProblemKind = "nib"
+	// FsckMissingObject marks a referenced object which is not stored.
+	FsckMissingObject FsckProblemKind = "missing object"
+	// FsckBadObject marks a stored object which cannot be decrypted or
+	// which does not hash to its ID.
+	FsckBadObject FsckProblemKind = "bad object"
+	// FsckTracker marks a tracked path which does not match the NIB store.
+	FsckTracker FsckProblemKind = "tracker"
+)
+
+// FsckProblem describes an inconsistency which has been found by the
+// integrity check.
+type FsckProblem struct {
+	Kind FsckProblemKind
+	// ID identifies the affected transaction container, transaction,
+	// NIB or object; tracker problems name the tracked path.
+	ID      string
+	Message string
+	// Repaired is set once the problem has been fixed.
+	Repaired bool
+}
+
+// FsckResult describes the outcome of an integrity check.
+type FsckResult struct {
+	// Transactions is the number of checked transactions.
+	Transactions int
+	// NIBs is the number of checked NIBs.
+	NIBs int
+	// Objects is the number of checked objects; for server repositories
+	// these are the stored objects which are referenced by NIBs.
+	Objects  int
+	Problems []*FsckProblem
+}
+
+// add records a problem of the given kind.
+func (res *FsckResult) add(kind FsckProblemKind, id string, format string, args ...interface{}) {
+	res.Problems = append(res.Problems, &FsckProblem{
+		Kind:    kind,
+		ID:      id,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Unrepaired returns the number of problems which have not been fixed.
+func (res *FsckResult) Unrepaired() int {
+	count := 0
+	for _, problem := range res.Problems {
+		if !problem.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+// Fsck checks the integrity of the repository: the transaction containers
+// have to form an unbroken chain, all NIBs have to verify against the
+// accepted signing keys and all objects they reference have to be stored.
+// The storage lock is held exclusively, as uploads and commits of a running
+// server hold it shared and would otherwise be seen half written; it
+// returns lock.ErrTimeout if they hold it for too long.
+func (r *Repository) Fsck() (*FsckResult, error) {
+	l := r.StorageLock()
+	err := l.LockTimeout(gcLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	result, _, err := r.fsck(func(n *nib.NIB) ([]string, error) {
+		return n.AllObjectIDs(), nil
+	})
+	return result, err
+}
+
+// fsck runs the checks which apply to all repositories and returns the
+// NIBs which could be read; requiredObjectIDs returns the objects of a
+// NIB which have to be stored.
+func (r *Repository) fsck(requiredObjectIDs func(*nib.NIB) ([]string, error)) (*FsckResult, []*nib.NIB, error) {
+	result := &FsckResult{Problems: []*FsckProblem{}}
+	transactions, err := r.checkTransactionChain(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	nibs, err := r.checkNIBs(transactions, result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	required := make(map[string]bool)
+	for _, n := range nibs {
+		objectIDs, err := requiredObjectIDs(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, objectID := range objectIDs {
+			required[objectID] = true
+		}
+	}
+	for _, objectID := range sortedIDs(required) {
+		if !r.objectStorage.Exists(objectID) {
+			result.add(FsckMissingObject, objectID, "referenced object is not stored")
+			continue
+		}
+		result.Objects++
+	}
+	return result, nibs, nil
+}
+
+// checkTransactionChain walks the transaction containers from the current
+// one back to the first one and returns their transactions in order.
+// Every transaction has to refer to the one before it.
+func (r *Repository) checkTransactionChain(result *FsckResult) ([]*Transaction, error) {
+	manager := r.transactionManager.manager
+	uuid, err := manager.currentTransactionContainerUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []*TransactionContainer{}
+	visited := make(map[string]bool)
+	complete := true
+	for uuid != "" {
+		if visited[uuid] {
+			result.add(FsckTransactionChain, uuid, "transaction container chain contains a cycle")
+			complete = false
+			break
+		}
+		visited[uuid] = true
+		container, err := manager.Get(uuid)
+		if err != nil {
+			result.add(FsckTransactionChain, uuid, "transaction container cannot be read (%s)", err)
+			complete = false
+			break
+		}
+		if container.UUID != uuid {
+			result.add(FsckTransactionChain, uuid, "transaction container has the UUID %s", container.UUID)
+		}
+		containers = append(containers, container)
+		uuid = container.PreviousUUID
+	}
+
+	transactions := []*Transaction{}
+	for i := len(containers) - 1; i >= 0; i-- {
+		transactions = append(transactions, containers[i].Transactions...)
+	}
+	for i, transaction := range transactions {
+		var previousID int64
+		if i > 0 {
+			previousID = transactions[i-1].ID
+		} else if !complete {
+			// the beginning of the chain is unknown
+			continue
+		}
+		if transaction.PreviousID != previousID {
+			result.add(FsckTransactionChain, transaction.IDString(),
+				"transaction refers to previous transaction %d instead of %d",
+				transaction.PreviousID, previousID)
+		}
+	}
+	result.Transactions = len(transactions)
+	return transactions, nil
+}
+
+// checkNIBs verifies the NIBs of the given transactions and returns them.
+// NIBs which fail verification are returned nonetheless if they can be
+// parsed, so that the objects they reference are checked as well.
+func (r *Repository) checkNIBs(transactions []*Transaction, result *FsckResult) ([]*nib.NIB, error) {
+	nibIDs := make(map[string]bool)
+	for _, transaction := range transactions {
+		for _, nibID := range transaction.NIBIDs {
+			nibIDs[nibID] = true
+		}
+	}
+
+	nibs := []*nib.NIB{}
+	for _, nibID := range sortedIDs(nibIDs) {
+		data, err := r.nibStore.GetBytes(nibID)
+		if err != nil {
+			result.add(FsckBadNIB, nibID, "NIB cannot be read (%s)", err)
+			continue
+		}
+		n, err := r.nibStore.VerifyAndParseBytes(data)
+		if err == ErrSignatureVerification {
+			result.add(FsckBadNIB, nibID, "NIB does not verify against the signing keys")
+			n, err = r.nibStore.getStored(nibID)
+			if err != nil {
+				continue
+			}
+		} else if err == ErrUnMarshalling {
+			result.add(FsckBadNIB, nibID, "NIB cannot be parsed")
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if n.ID != nibID {
+			result.add(FsckBadNIB, nibID, "NIB is stored under a different ID than %s", n.ID)
+		}
+		nibs = append(nibs, n)
+	}
+	result.NIBs = len(nibIDs)
+	return nibs, nil
+}
+
+// sortedIDs returns the keys of the given set in order.
+func sortedIDs(set map[string]bool) []string {
+	ids := []string{}
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Fsck checks the integrity of the client repository. In addition to the
+// checks of all repositories, every stored object has to decrypt and hash
+// to its ID and every tracked path has to match the NIB store.
+// Thin clients and sparse checkouts only have to store the objects which
+// they require.
+func (r *ClientRepository) Fsck() (*FsckResult, error) {
+	result, nibs, err := r.fsck(r.fsckRequiredObjectIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkObjects(result)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkTracker(nibs, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fsckRequiredObjectIDs returns the objects of the given NIB which have to
+// be stored locally. If the metadata which decides this is not available,
+// only the metadata objects are required.
+func (r *ClientRepository) fsckRequiredObjectIDs(n *nib.NIB) ([]string, error) {
+	objectIDs, err := r.RequiredObjectIDs(n)
+	if err != nil {
+		return metadataObjectIDs(n), nil
+	}
+	return objectIDs, nil
+}
+
+// checkObjects verifies every stored object.
+func (r *ClientRepository) checkObjects(result *FsckResult) error {
+	entries, err := r.objectStorage.List()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		objectID := entry.Name()
+		err = r.verifyStoredObject(objectID)
+		if err == ErrObjectVerification {
+			result.add(FsckBadObject, objectID, "object does not decrypt or hash to its ID")
+		} else if err != nil {
+			result.add(FsckBadObject, objectID, "object cannot be read (%s)", err)
+		}
+	}
+	result.Objects = len(entries)
+	return nil
+}
+
+// verifyStoredObject verifies the locally stored object with the given ID.
+func (r *ClientRepository) verifyStoredObject(objectID string) error {
+	reader, err := r.objectStorage.Get(objectID)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	enc, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return r.verifyObject(objectID, enc)
+}
+
+// checkTracker verifies that every tracked path refers to a stored NIB
+// whose item is located at that path and that every item in the working
+// directory is tracked as its NIB. Paths of deleted items are kept in the
+// tracker, so they are not compared; items which have not been checked
+// out are not tracked yet. nibs are the NIBs of the repository.
+func (r *ClientRepository) checkTracker(nibs []*nib.NIB, result *FsckResult) error {
+	nibTracker, err := r.NIBTracker()
+	if err != nil {
+		return err
+	}
+	entries, err := nibTracker.List()
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]string)
+	for _, entry := range entries {
+		tracked[entry.Path] = entry.NIBID
+		if !r.nibStore.Exists(entry.NIBID) {
+			result.add(FsckTracker, entry.Path, "path is tracked as NIB %s which is not stored", entry.NIBID)
+			continue
+		}
+		n, err := r.nibStore.getStored(entry.NIBID)
+		if err != nil {
+			// reported by the NIB check
+			continue
+		}
+		metadata, ok := r.latestMetadata(n)
+		if ok && metadata.RepoRelativePath != entry.Path {
+			result.add(FsckTracker, entry.Path, "path is tracked as NIB %s which belongs to %s",
+				entry.NIBID, metadata.RepoRelativePath)
+		}
+	}
+
+	for _, n := range nibs {
+		metadata, ok := r.latestMetadata(n)
+		if !ok {
+			continue
+		}
+		relPath := metadata.RepoRelativePath
+		_, err := os.Lstat(filepath.Join(r.Path, relPath))
+		if err != nil {
+			// not checked out
+			continue
+		}
+		nibID, ok := tracked[relPath]
+		if !ok {
+			result.add(FsckTracker, relPath, "item of NIB %s is not tracked", n.ID)
+		} else if nibID != n.ID {
+			result.add(FsckTracker, relPath, "item of NIB %s is tracked as NIB %s", n.ID, nibID)
+		}
+	}
+	return nil
+}
+
+// latestMetadata returns the metadata of the latest revision of the given
+// NIB; ok is false if the item is deleted or its metadata cannot be read,
+// which is reported by the other checks.
+func (r *ClientRepository) latestMetadata(n *nib.NIB) (*Metadata, bool) {
+	rev, err := n.LatestRevision()
+	if err != nil || rev.IsDeletion() {
+		return nil, false
+	}
+	metadata, err := r.metadataByID(rev.MetadataID)
+	if err != nil {
+		return nil, false
+	}
+	return metadata, true
+}
+
+// RepairObjects re-fetches the missing and bad objects of the given result
+// with the configured object fetcher and marks them as repaired. Fetched
+// objects are only stored if they verify; objects which cannot be
+// repaired are logged and skipped.
+func (r *ClientRepository) RepairObjects(result *FsckResult) error {
+	if r.objectFetcher == nil {
+		return ErrNoObjectFetcher
+	}
+	for _, problem := range result.Problems {
+		if problem.Kind != FsckMissingObject && problem.Kind != FsckBadObject {
+			continue
+		}
+		err := r.refetchObject(problem.ID)
+		if err != nil {
+			Log.Warn("unable to repair object", "objectID", problem.ID, "error", err)
+			continue
+		}
+		problem.Repaired = true
+	}
+	return nil
+}
+
+// refetchObject fetches the object with the given ID and replaces the
+// local copy if the fetched one verifies.
+func (r *ClientRepository) refetchObject(objectID string) error {
+	reader, err := r.objectFetcher.GetObject(objectID)
+	if err != nil {
+		return err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	return r.AddVerifiedObject(objectID, reader)
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/helpers/lock"
+)
+
+var _ = Suite(&FsckTests{})
+
+type FsckTests struct {
+	clientPairTests
+}
+
+// contentID returns the ID of the first content object of the latest
+// revision of the item at the given relative path.
+func (t *FsckTests) contentID(c *C, r *ClientRepository, relPath string) string {
+	n, err := r.nibForPath(filepath.Join(r.Path, relPath))
+	c.Assert(err, IsNil)
+	rev, err := n.LatestRevision()
+	c.Assert(err, IsNil)
+	return rev.ContentIDs[0]
+}
+
+// corruptObject overwrites the stored object with the given ID.
+func (t *FsckTests) corruptObject(c *C, r *ClientRepository, objectID string) {
+	path := filepath.Join(r.subPathFor(objectsDirName), objectID)
+	err := ioutil.WriteFile(path, []byte("corrupt"), defaultFilePerms)
+	c.Assert(err, IsNil)
+}
+
+// problems returns the kinds of the problems of the given result by ID.
+func (t *FsckTests) problems(result *FsckResult) map[string]FsckProblemKind {
+	res := map[string]FsckProblemKind{}
+	for _, problem := range result.Problems {
+		res[problem.ID] = problem.Kind
+	}
+	return res
+}
+
+func (t *FsckTests) TestClean(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	err := t.mine.Move(filepath.Join(t.mine.Path, "bar.txt"),
+		filepath.Join(t.mine.Path, "baz.txt"))
+	c.Assert(err, IsNil)
+	err = os.Remove(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	err = t.mine.DeleteItem(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 0)
+	c.Assert(result.Transactions > 0, Equals, true)
+	c.Assert(result.NIBs, Equals, 3)
+	c.Assert(result.Objects > 0, Equals, true)
+}
+
+func (t *FsckTests) TestCleanCheckout(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	result, err := t.theirs.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 0)
+}
+
+func (t *FsckTests) TestServerRepository(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	objectID := t.contentID(c, t.mine, "foo.txt")
+	t.corruptObject(c, t.mine, objectID)
+
+	// servers cannot decrypt objects, so only their existence is checked
+	result, err := t.mine.Repository.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 0)
+	c.Assert(result.NIBs, Equals, 1)
+	// the metadata and the content object
+	c.Assert(result.Objects, Equals, 2)
+
+	err = os.Remove(filepath.Join(t.mine.subPathFor(objectsDirName), objectID))
+	c.Assert(err, IsNil)
+	result, err = t.mine.Repository.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals,
+		map[string]FsckProblemKind{objectID: FsckMissingObject})
+	c.Assert(result.Objects, Equals, 1)
+}
+
+func (t *FsckTests) TestServerRepositoryWaitsForUploads(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	gcLockTimeout = 20 * time.Millisecond
+	defer func() { gcLockTimeout = time.Minute }()
+	// another process stores objects
+	upload := lock.NewFileManager().GetFileLock(t.mine.GetManagementDir(), storageLockRole)
+	upload.RLock()
+
+	_, err := t.mine.Repository.Fsck()
+	c.Assert(err, Equals, lock.ErrTimeout)
+
+	upload.RUnlock()
+	result, err := t.mine.Repository.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 0)
+}
+
+func (t *FsckTests) TestBadObject(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	objectID := t.contentID(c, t.mine, "foo.txt")
+	t.corruptObject(c, t.mine, objectID)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals,
+		map[string]FsckProblemKind{objectID: FsckBadObject})
+	c.Assert(result.Unrepaired(), Equals, 1)
+}
+
+func (t *FsckTests) TestObjectWithWrongID(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	fooID := t.contentID(c, t.mine, "foo.txt")
+	barID := t.contentID(c, t.mine, "bar.txt")
+	reader, err := t.mine.GetObjectData(barID)
+	c.Assert(err, IsNil)
+	err = t.mine.AddObject(fooID, reader)
+	reader.Close()
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals,
+		map[string]FsckProblemKind{fooID: FsckBadObject})
+}
+
+func (t *FsckTests) TestBadNIB(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	path := filepath.Join(t.mine.subPathFor(nibsDirName), nibID)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	data[len(data)-1] ^= 0xff
+	err = ioutil.WriteFile(path, data, defaultFilePerms)
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals,
+		map[string]FsckProblemKind{nibID: FsckBadNIB})
+}
+
+func (t *FsckTests) TestTransactionChain(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	manager := t.mine.transactionManager.manager
+	container, err := manager.CurrentTransactionContainer()
+	c.Assert(err, IsNil)
+	c.Assert(container.Transactions, HasLen, 2)
+	container.Transactions[1].PreviousID = 5
+	err = manager.Set(container)
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals, map[string]FsckProblemKind{
+		container.Transactions[1].IDString(): FsckTransactionChain,
+	})
+}
+
+func (t *FsckTests) TestTransactionContainerMissing(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	manager := t.mine.transactionManager.manager
+	container, err := manager.CurrentTransactionContainer()
+	c.Assert(err, IsNil)
+	container.PreviousUUID = "0123456789abcdef0123456789abcdef"
+	err = manager.Set(container)
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals, map[string]FsckProblemKind{
+		container.PreviousUUID: FsckTransactionChain,
+	})
+}
+
+func (t *FsckTests) TestTracker(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	nibTracker, err := t.mine.NIBTracker()
+	c.Assert(err, IsNil)
+	err = nibTracker.Remove("foo.txt")
+	c.Assert(err, IsNil)
+	err = nibTracker.Add("bar.txt", nibID)
+	c.Assert(err, IsNil)
+	err = nibTracker.Add("baz.txt", "unknown")
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals, map[string]FsckProblemKind{
+		"foo.txt": FsckTracker,
+		"bar.txt": FsckTracker,
+		"baz.txt": FsckTracker,
+	})
+}
+
+func (t *FsckTests) TestTrackerMissingEntry(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	nibTracker, err := t.mine.NIBTracker()
+	c.Assert(err, IsNil)
+	err = nibTracker.Remove("foo.txt")
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals, map[string]FsckProblemKind{
+		"foo.txt": FsckTracker,
+	})
+}
+
+func (t *FsckTests) TestRepair(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	objectID := t.contentID(c, t.mine, "foo.txt")
+	t.copyNIBData(c, t.mine, t.theirs, "foo.txt")
+	t.corruptObject(c, t.mine, objectID)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 1)
+	fetcher := &repositoryFetcher{r: t.theirs}
+	t.mine.SetObjectFetcher(fetcher)
+	err = t.mine.RepairObjects(result)
+	c.Assert(err, IsNil)
+	c.Assert(fetcher.fetched, DeepEquals, []string{objectID})
+	c.Assert(result.Problems[0].Repaired, Equals, true)
+	c.Assert(result.Unrepaired(), Equals, 0)
+
+	result, err = t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(result.Problems, HasLen, 0)
+}
+
+func (t *FsckTests) TestRepairRejectsBadCopy(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	objectID := t.contentID(c, t.mine, "foo.txt")
+	err := t.mine.objectStorage.Delete(objectID)
+	c.Assert(err, IsNil)
+	err = t.theirs.AddObject(objectID, bytes.NewBufferString("corrupt"))
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.Fsck()
+	c.Assert(err, IsNil)
+	c.Assert(t.problems(result), DeepEquals,
+		map[string]FsckProblemKind{objectID: FsckMissingObject})
+	t.mine.SetObjectFetcher(&repositoryFetcher{r: t.theirs})
+	err = t.mine.RepairObjects(result)
+	c.Assert(err, IsNil)
+	c.Assert(result.Unrepaired(), Equals, 1)
+	c.Assert(t.mine.HasObject(objectID), Equals, false)
+}
+
+func (t *FsckTests) TestRepairWithoutFetcher(c *C) {
+	err := t.mine.RepairObjects(&FsckResult{})
+	c.Assert(err, Equals, ErrNoObjectFetcher)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/hoffie/larasync/helpers/lock"
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// DefaultGCGracePeriod is the minimum age of unreferenced objects before
+// they are removed by the garbage collection. It keeps the objects of
+// uploads in progress, whose NIBs have not been stored yet, safe.
+const DefaultGCGracePeriod = 24 * time.Hour
+
+// storageLockRole names the lock file in the management directory which
+// protects the stored objects against the garbage collection.
+const storageLockRole = "storage"
+
+// gcLockTimeout is how long the garbage collection and server side
+// integrity checks wait for uploads and commits in progress to finish.
+var gcLockTimeout = time.Minute
+
+// StorageLock returns the lock which protects the objects of this
+// repository against the garbage collection, also across processes.
+// Storing objects and NIBs holds it shared; CollectGarbage takes it
+// exclusively.
+func (r *Repository) StorageLock() *lock.FileLock {
+	return lock.CurrentFileManager().GetFileLock(r.GetManagementDir(), storageLockRole)
+}
+
+// GCResult describes the outcome of a garbage collection run.
+type GCResult struct {
+	// Referenced is the number of objects referenced by NIBs.
+	Referenced int
+	// Unreferenced lists the IDs of all unreferenced objects which are
+	// older than the grace period; they are deleted unless it is a dry run.
+	Unreferenced []string
+	// Size is the total size of the unreferenced objects in bytes.
+	Size int64
+	// Recent is the number of unreferenced objects which are kept as they
+	// have been stored within the grace period.
+	Recent int
+}
+
+// CollectGarbage removes all objects which are not referenced by any
+// revision of a NIB and which have not been stored within the grace
+// period. Nothing is removed if dryRun is set.
+// It returns lock.ErrTimeout if uploads or commits hold the storage lock
+// for too long.
+func (r *Repository) CollectGarbage(gracePeriod time.Duration, dryRun bool) (*GCResult, error) {
+	l := r.StorageLock()
+	take, release := l.LockTimeout, l.Unlock
+	if dryRun {
+		take, release = l.RLockTimeout, l.RUnlock
+	}
+	err := take(gcLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	referenced, err := r.referencedObjectIDs()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.objectStorage.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GCResult{Unreferenced: []string{}}
+	threshold := time.Now().Add(-gracePeriod)
+	for _, entry := range entries {
+		id := entry.Name()
+		if referenced[id] {
+			result.Referenced++
+			continue
+		}
+		if entry.ModTime().After(threshold) {
+			result.Recent++
+			continue
+		}
+		result.Unreferenced = append(result.Unreferenced, id)
+		result.Size += entry.Size()
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	for _, id := range result.Unreferenced {
+		err = r.objectStorage.Delete(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// referencedObjectIDs returns the IDs of all objects which are referenced
+// by any revision of a stored NIB.
+func (r *Repository) referencedObjectIDs() (map[string]bool, error) {
+	transactions, err := r.transactionManager.All()
+	if err != nil {
+		return nil, err
+	}
+	nibs, err := r.storedNIBs(transactions)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, n := range nibs {
+		for _, objectID := range n.AllObjectIDs() {
+			referenced[objectID] = true
+		}
+	}
+	return referenced, nil
+}
+
+// storedNIBs returns the current state of all NIBs which are part of the
+// given transactions. In contrast to GetAllNibs, it fails if any of them
+// cannot be read.
+// Signatures are not verified again as the NIBs of revoked devices still
+// reference objects.
+func (r *Repository) storedNIBs(transactions []*Transaction) ([]*nib.NIB, error) {
+	nibIDs := []string{}
+	for nibID := range nibUUIDsFromTransactions(transactions) {
+		nibIDs = append(nibIDs, nibID)
+	}
+
+	nibs := []*nib.NIB{}
+	seen := make(map[string]bool)
+	for _, nibID := range nibIDs {
+		if seen[nibID] {
+			continue
+		}
+		seen[nibID] = true
+		n, err := r.nibStore.getStored(nibID)
+		if err != nil {
+			return nil, err
+		}
+		nibs = append(nibs, n)
+	}
+	return nibs, nil
+}
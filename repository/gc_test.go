@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/helpers/lock"
+)
+
+var _ = Suite(&GCTests{})
+
+type GCTests struct {
+	clientPairTests
+}
+
+// addOrphan stores an object which is not referenced by any NIB and
+// backdates it by the given age.
+func (t *GCTests) addOrphan(c *C, id string, age time.Duration) {
+	err := t.mine.AddObject(id, bytes.NewBufferString("orphan"))
+	c.Assert(err, IsNil)
+	modTime := time.Now().Add(-age)
+	path := filepath.Join(t.mine.subPathFor(objectsDirName), id)
+	err = os.Chtimes(path, modTime, modTime)
+	c.Assert(err, IsNil)
+}
+
+func (t *GCTests) TestCollectGarbage(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	t.addOrphan(c, "old", 2*time.Hour)
+	t.addOrphan(c, "recent", time.Minute)
+
+	result, err := t.mine.CollectGarbage(time.Hour, false)
+	c.Assert(err, IsNil)
+	c.Assert(result.Unreferenced, DeepEquals, []string{"old"})
+	c.Assert(result.Size, Equals, int64(len("orphan")))
+	c.Assert(result.Recent, Equals, 1)
+	c.Assert(t.mine.HasObject("old"), Equals, false)
+	c.Assert(t.mine.HasObject("recent"), Equals, true)
+
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	objectIDs := n.AllObjectIDs()
+	c.Assert(result.Referenced, Equals, len(objectIDs))
+	for _, id := range objectIDs {
+		c.Assert(t.mine.HasObject(id), Equals, true)
+	}
+}
+
+func (t *GCTests) TestCollectGarbageDryRun(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.addOrphan(c, "old", 2*time.Hour)
+
+	result, err := t.mine.CollectGarbage(time.Hour, true)
+	c.Assert(err, IsNil)
+	c.Assert(result.Unreferenced, DeepEquals, []string{"old"})
+	c.Assert(t.mine.HasObject("old"), Equals, true)
+}
+
+func (t *GCTests) TestCollectGarbageEmpty(c *C) {
+	t.addOrphan(c, "old", 2*time.Hour)
+
+	result, err := t.mine.CollectGarbage(time.Hour, false)
+	c.Assert(err, IsNil)
+	c.Assert(result.Referenced, Equals, 0)
+	c.Assert(result.Unreferenced, DeepEquals, []string{"old"})
+}
+
+func (t *GCTests) TestCollectGarbageWaitsForUploads(c *C) {
+	t.addOrphan(c, "old", 2*time.Hour)
+	gcLockTimeout = 20 * time.Millisecond
+	defer func() { gcLockTimeout = time.Minute }()
+	// another process stores objects
+	upload := lock.NewFileManager().GetFileLock(t.mine.GetManagementDir(), storageLockRole)
+	upload.RLock()
+
+	_, err := t.mine.CollectGarbage(time.Hour, false)
+	c.Assert(err, Equals, lock.ErrTimeout)
+	c.Assert(t.mine.HasObject("old"), Equals, true)
+	_, err = t.mine.CollectGarbage(time.Hour, true)
+	c.Assert(err, IsNil)
+
+	upload.RUnlock()
+	result, err := t.mine.CollectGarbage(time.Hour, false)
+	c.Assert(err, IsNil)
+	c.Assert(result.Unreferenced, DeepEquals, []string{"old"})
+}
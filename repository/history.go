@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hoffie/larasync/helpers/crypto"
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// RevisionInfo describes a single revision of a repository item.
+type RevisionInfo struct {
+	// Number identifies the revision; it starts with 1 for the first
+	// revision ever recorded for the item and includes pruned revisions.
+	Number       int64
+	UTCTimestamp int64
+	DeviceID     string
+	// Size is the size of the content in bytes or -1 if the content
+	// has been evicted from the local object store.
+	Size    int64
+	Deleted bool
+}
+
+// History returns information about all revisions of the item at the
+// given path, oldest first.
+func (r *ClientRepository) History(absPath string) ([]*RevisionInfo, error) {
+	n, err := r.nibForPath(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []*RevisionInfo{}
+	for i, rev := range n.Revisions {
+		info := &RevisionInfo{
+			Number:       n.HistoryOffset + int64(i) + 1,
+			UTCTimestamp: rev.UTCTimestamp,
+			DeviceID:     rev.DeviceID,
+			Deleted:      rev.IsDeletion(),
+		}
+		for _, contentID := range rev.ContentIDs {
+			size, err := r.plainObjectSize(contentID)
+			if os.IsNotExist(err) {
+				info.Size = -1
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			info.Size += size
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// plainObjectSize returns the unencrypted size of the object with the
+// given id without decrypting it. Evicted objects are not fetched.
+func (r *ClientRepository) plainObjectSize(id string) (int64, error) {
+	reader, err := r.objectStorage.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	size, err := io.Copy(ioutil.Discard, reader)
+	if err != nil {
+		return 0, err
+	}
+	return crypto.PlainTextSize(size), nil
+}
+
+// nibForPath returns the NIB of the item at the given path.
+func (r *ClientRepository) nibForPath(absPath string) (*nib.NIB, error) {
+	relPath, err := r.getRepoRelativePath(absPath)
+	if err != nil {
+		return nil, err
+	}
+	nibID, err := r.pathToNIBID(relPath)
+	if err != nil {
+		return nil, err
+	}
+	return r.nibStore.Get(nibID)
+}
+
+// RestoreRevision restores the revision with the given number of the item
+// at absPath. If targetPath is empty or equal to absPath, the item is
+// restored in place and the restore is recorded as a new revision.
+// Otherwise the content is written to targetPath, which is added to the
+// repository if it is part of the working directory.
+func (r *ClientRepository) RestoreRevision(absPath string, number int64, targetPath string) error {
+	n, err := r.nibForPath(absPath)
+	if err != nil {
+		return err
+	}
+	index := number - n.HistoryOffset - 1
+	if index < 0 || index >= int64(len(n.Revisions)) {
+		return ErrRevisionNotFound
+	}
+	return r.restoreRevision(n, n.Revisions[index], absPath, targetPath)
+}
+
+// RestoreAt restores the item at absPath to the state it had at the given
+// time. Directories are restored recursively; items which did not exist
+// at that time are removed.
+// targetPath is handled the same way as in RestoreRevision.
+func (r *ClientRepository) RestoreAt(absPath string, at time.Time, targetPath string) error {
+	if targetPath == "" {
+		targetPath = absPath
+	}
+	timestamp := at.UTC().Unix()
+	if absPath == r.Path {
+		return r.restoreDirectoryAt(absPath, timestamp, targetPath)
+	}
+
+	n, err := r.nibForPath(absPath)
+	if err == nil {
+		isDir, err := r.isDirectoryNIB(n)
+		if err != nil {
+			return err
+		}
+		if isDir {
+			return r.restoreDirectoryAt(absPath, timestamp, targetPath)
+		}
+		rev := revisionAt(n, timestamp)
+		if rev == nil {
+			return ErrRevisionNotFound
+		}
+		return r.restoreRevision(n, rev, absPath, targetPath)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	return r.restoreDirectoryAt(absPath, timestamp, targetPath)
+}
+
+// isDirectoryNIB returns whether the latest revision of the given NIB
+// describes a directory.
+func (r *ClientRepository) isDirectoryNIB(n *nib.NIB) (bool, error) {
+	latest, err := n.LatestRevision()
+	if err != nil {
+		return false, err
+	}
+	metadata, err := r.metadataByID(latest.MetadataID)
+	if err != nil {
+		return false, err
+	}
+	return metadata.Type == MetadataTypeDir, nil
+}
+
+// restoreDirectoryAt restores the directory at absPath and all items below
+// it to the state they had at the given timestamp.
+func (r *ClientRepository) restoreDirectoryAt(absPath string, timestamp int64, targetPath string) error {
+	prefix := ""
+	dirRelPath := ""
+	if absPath != r.Path {
+		relPath, err := r.getRepoRelativePath(absPath)
+		if err != nil {
+			return err
+		}
+		dirRelPath = relPath
+		prefix = relPath + string(filepath.Separator)
+	}
+
+	nibs, err := r.GetAllNibs()
+	if err != nil {
+		return err
+	}
+	found := false
+	for n := range nibs {
+		latest, err := n.LatestRevision()
+		if err != nil {
+			continue
+		}
+		metadata, err := r.metadataByID(latest.MetadataID)
+		if err != nil {
+			return err
+		}
+		relPath := metadata.RepoRelativePath
+		itemTarget := targetPath
+		if relPath != dirRelPath {
+			if !strings.HasPrefix(relPath, prefix) {
+				continue
+			}
+			itemTarget = filepath.Join(targetPath, strings.TrimPrefix(relPath, prefix))
+		}
+		found = true
+
+		itemPath := filepath.Join(r.Path, relPath)
+		rev := revisionAt(n, timestamp)
+		if rev == nil {
+			// the item did not exist yet; use the latest revision as
+			// template for its deletion.
+			rev = latest.Clone()
+			rev.ContentIDs = []string{}
+		}
+		err = r.restoreRevision(n, rev, itemPath, itemTarget)
+		if err != nil && err != ErrRevisionDeleted {
+			return err
+		}
+	}
+	if !found {
+		return ErrRevisionNotFound
+	}
+	return nil
+}
+
+// revisionAt returns the revision of the given NIB which was current at
+// the given timestamp or nil if there was none.
+func revisionAt(n *nib.NIB, timestamp int64) *nib.Revision {
+	var found *nib.Revision
+	for _, rev := range n.Revisions {
+		if rev.UTCTimestamp > timestamp {
+			break
+		}
+		found = rev
+	}
+	return found
+}
+
+// restoreRevision restores the given revision of the NIB belonging to
+// absPath to targetPath.
+func (r *ClientRepository) restoreRevision(n *nib.NIB, rev *nib.Revision, absPath, targetPath string) error {
+	if targetPath != "" && targetPath != absPath {
+		if rev.IsDeletion() {
+			return ErrRevisionDeleted
+		}
+		metadata, err := r.metadataByID(rev.MetadataID)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(targetPath), defaultDirPerms)
+		if err != nil {
+			return err
+		}
+		err = r.writeItem(targetPath, metadata, rev.ContentIDs)
+		if err != nil || !r.isInWorkDir(targetPath) {
+			return err
+		}
+		return r.addRestored(targetPath)
+	}
+
+	workDirContentIDs, err := r.workDirContentIDs(absPath)
+	if err != nil {
+		return err
+	}
+	if workDirContentIDs != nil {
+		_, err = n.LatestRevisionWithContent(workDirContentIDs)
+		if err != nil {
+			return ErrWorkDirConflict
+		}
+	}
+
+	if rev.IsDeletion() {
+		return r.restoreDeletion(n, absPath)
+	}
+	err = r.checkoutRevision(n, rev)
+	if err != nil {
+		return err
+	}
+	return r.addRestored(absPath)
+}
+
+// addRestored records the restored item at the given path; ignored items
+// are restored without being recorded.
+func (r *ClientRepository) addRestored(absPath string) error {
+	err := r.AddItem(absPath)
+	if err == ErrPathIgnored {
+		return nil
+	}
+	return err
+}
+
+// restoreDeletion removes the work dir file of the given NIB and records
+// its deletion.
+func (r *ClientRepository) restoreDeletion(n *nib.NIB, absPath string) error {
+	latest, err := n.LatestRevision()
+	if err != nil {
+		return err
+	}
+	if !latest.IsDeletion() {
+		err = r.deleteFile(absPath)
+		if err != nil {
+			return err
+		}
+	}
+	// deleteFile only removes files matching the latest revision.
+	return removeItem(absPath)
+}
+
+// isInWorkDir returns whether the given absolute path is part of this
+// repository's working directory.
+func (r *ClientRepository) isInWorkDir(absPath string) bool {
+	return strings.HasPrefix(absPath, r.Path+string(filepath.Separator))
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&HistoryTests{})
+
+type HistoryTests struct {
+	clientPairTests
+}
+
+func (t *HistoryTests) readFile(c *C, relPath string) string {
+	data, err := ioutil.ReadFile(filepath.Join(t.mine.Path, relPath))
+	c.Assert(err, IsNil)
+	return string(data)
+}
+
+func (t *HistoryTests) TestHistory(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second!"))
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	err := t.mine.DeleteItem(absPath)
+	c.Assert(err, IsNil)
+
+	history, err := t.mine.History(absPath)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Assert(history[0].Number, Equals, int64(1))
+	c.Assert(history[0].Size, Equals, int64(5))
+	c.Assert(history[0].Deleted, Equals, false)
+	c.Assert(history[1].Number, Equals, int64(2))
+	c.Assert(history[1].Size, Equals, int64(7))
+	c.Assert(history[2].Number, Equals, int64(3))
+	c.Assert(history[2].Size, Equals, int64(0))
+	c.Assert(history[2].Deleted, Equals, true)
+}
+
+func (t *HistoryTests) TestHistoryUnknownPath(c *C) {
+	_, err := t.mine.History(filepath.Join(t.mine.Path, "unknown.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *HistoryTests) TestRestoreRevision(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+
+	err := t.mine.RestoreRevision(absPath, 1, "")
+	c.Assert(err, IsNil)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "first")
+
+	history, err := t.mine.History(absPath)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+}
+
+func (t *HistoryTests) TestRestoreRevisionToOtherPath(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	target := filepath.Join(t.mine.Path, "old", "foo.txt")
+
+	err := t.mine.RestoreRevision(absPath, 1, target)
+	c.Assert(err, IsNil)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "second")
+	c.Assert(t.readFile(c, filepath.Join("old", "foo.txt")), Equals, "first")
+
+	_, err = t.mine.History(target)
+	c.Assert(err, IsNil)
+}
+
+func (t *HistoryTests) TestRestoreRevisionOutsideWorkDir(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	target := filepath.Join(c.MkDir(), "foo.txt")
+
+	err := t.mine.RestoreRevision(filepath.Join(t.mine.Path, "foo.txt"), 1, target)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadFile(target)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "first")
+}
+
+func (t *HistoryTests) TestRestoreRevisionNotFound(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	err := t.mine.RestoreRevision(absPath, 2, "")
+	c.Assert(err, Equals, ErrRevisionNotFound)
+	err = t.mine.RestoreRevision(absPath, 0, "")
+	c.Assert(err, Equals, ErrRevisionNotFound)
+}
+
+func (t *HistoryTests) TestRestoreRevisionWorkDirConflict(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	err := ioutil.WriteFile(absPath, []byte("unsaved"), 0600)
+	c.Assert(err, IsNil)
+
+	err = t.mine.RestoreRevision(absPath, 1, "")
+	c.Assert(err, Equals, ErrWorkDirConflict)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "unsaved")
+}
+
+func (t *HistoryTests) TestRestoreDeletedFile(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	err := t.mine.DeleteItem(absPath)
+	c.Assert(err, IsNil)
+
+	err = t.mine.RestoreRevision(absPath, 1, "")
+	c.Assert(err, IsNil)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "first")
+}
+
+func (t *HistoryTests) TestRestoreAt(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	t.setTimestamps(c, "foo.txt", 1000, 2000)
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+
+	err := t.mine.RestoreAt(absPath, time.Unix(1500, 0), "")
+	c.Assert(err, IsNil)
+	c.Assert(t.readFile(c, "foo.txt"), Equals, "first")
+
+	err = t.mine.RestoreAt(absPath, time.Unix(500, 0), "")
+	c.Assert(err, Equals, ErrRevisionNotFound)
+}
+
+func (t *HistoryTests) TestRestoreDirectoryAt(c *C) {
+	err := os.Mkdir(filepath.Join(t.mine.Path, "dir"), 0700)
+	c.Assert(err, IsNil)
+	changed := filepath.Join("dir", "changed.txt")
+	created := filepath.Join("dir", "created.txt")
+	removed := filepath.Join("dir", "removed.txt")
+	t.writeAndAdd(c, t.mine, changed, []byte("old"))
+	t.writeAndAdd(c, t.mine, changed, []byte("new"))
+	t.writeAndAdd(c, t.mine, created, []byte("created"))
+	t.writeAndAdd(c, t.mine, removed, []byte("removed"))
+	t.writeAndAdd(c, t.mine, "outside.txt", []byte("outside"))
+	t.writeAndAdd(c, t.mine, "outside.txt", []byte("outside changed"))
+	err = t.mine.DeleteItem(filepath.Join(t.mine.Path, removed))
+	c.Assert(err, IsNil)
+	t.setTimestamps(c, changed, 1000, 2000)
+	t.setTimestamps(c, created, 2000)
+	t.setTimestamps(c, removed, 1000, 2000)
+	t.setTimestamps(c, "outside.txt", 1000, 2000)
+
+	err = t.mine.RestoreAt(filepath.Join(t.mine.Path, "dir"), time.Unix(1500, 0), "")
+	c.Assert(err, IsNil)
+	c.Assert(t.readFile(c, changed), Equals, "old")
+	c.Assert(t.readFile(c, removed), Equals, "removed")
+	_, err = os.Stat(filepath.Join(t.mine.Path, created))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	c.Assert(t.readFile(c, "outside.txt"), Equals, "outside changed")
+
+	history, err := t.mine.History(filepath.Join(t.mine.Path, created))
+	c.Assert(err, IsNil)
+	c.Assert(history[len(history)-1].Deleted, Equals, true)
+}
+
+func (t *HistoryTests) TestRestoreDirectoryAtToOtherPath(c *C) {
+	err := os.Mkdir(filepath.Join(t.mine.Path, "dir"), 0700)
+	c.Assert(err, IsNil)
+	changed := filepath.Join("dir", "changed.txt")
+	t.writeAndAdd(c, t.mine, changed, []byte("old"))
+	t.writeAndAdd(c, t.mine, changed, []byte("new"))
+	t.setTimestamps(c, changed, 1000, 2000)
+	target := c.MkDir()
+
+	err = t.mine.RestoreAt(filepath.Join(t.mine.Path, "dir"), time.Unix(1500, 0), target)
+	c.Assert(err, IsNil)
+	c.Assert(t.readFile(c, changed), Equals, "new")
+	data, err := ioutil.ReadFile(filepath.Join(target, "changed.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "old")
+}
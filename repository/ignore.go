@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ignoreFileName is the name of the files in the work dir which list
+	// patterns of items excluded from the repository.
+	ignoreFileName = ".laraignore"
+	// localExcludeFileName is the name of the file in the management
+	// directory which lists patterns of items excluded on this client.
+	localExcludeFileName = "exclude"
+)
+
+// ignoreRule is a single pattern of an ignore file. Patterns follow the
+// gitignore syntax: a leading "!" re-includes items, a trailing "/"
+// only matches directories, patterns containing a "/" are relative to
+// the directory of the ignore file and "**" matches any number of
+// directories.
+type ignoreRule struct {
+	// base is the slash-separated repository relative directory of the
+	// ignore file; it is empty for the repository root.
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// parseIgnoreRule parses the given line of an ignore file in the given
+// base directory. It returns nil for empty lines, comments and invalid
+// patterns.
+func parseIgnoreRule(line string, base string) *ignoreRule {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	rule := &ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+	rule.segments = strings.Split(line, "/")
+	for _, segment := range rule.segments {
+		_, err := path.Match(segment, "")
+		if err != nil {
+			Log.Warn("ignoring invalid pattern", "pattern", line, "err", err)
+			return nil
+		}
+	}
+	return rule
+}
+
+// matches returns whether the rule applies to the given slash-separated
+// repository relative path.
+func (rule *ignoreRule) matches(slashPath string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	if rule.base != "" {
+		if !strings.HasPrefix(slashPath, rule.base+"/") {
+			return false
+		}
+		slashPath = slashPath[len(rule.base)+1:]
+	}
+	if !rule.anchored {
+		matched, _ := path.Match(rule.segments[0], path.Base(slashPath))
+		return matched
+	}
+	return matchSegments(rule.segments, strings.Split(slashPath, "/"))
+}
+
+// matchSegments matches the path segments against the pattern segments;
+// a "**" pattern segment matches any number of path segments.
+func matchSegments(patterns []string, segments []string) bool {
+	if len(patterns) == 0 {
+		return len(segments) == 0
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(patterns[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(patterns[0], segments[0])
+	return matched && matchSegments(patterns[1:], segments[1:])
+}
+
+// ignoreMatcher decides whether work dir items are excluded from the
+// repository. It caches the parsed ignore files and is meant to be used
+// for a single operation only.
+type ignoreMatcher struct {
+	r     *ClientRepository
+	local []*ignoreRule
+	// dirs maps slash-separated directories to the rules of their ignore
+	// file.
+	dirs map[string][]*ignoreRule
+}
+
+// newIgnoreMatcher returns a matcher for the current ignore files.
+func (r *ClientRepository) newIgnoreMatcher() (*ignoreMatcher, error) {
+	local, err := readIgnoreFile(r.subPathFor(localExcludeFileName), "")
+	if err != nil {
+		return nil, err
+	}
+	return &ignoreMatcher{
+		r:     r,
+		local: local,
+		dirs:  map[string][]*ignoreRule{},
+	}, nil
+}
+
+// readIgnoreFile parses the ignore file at the given path; a missing file
+// does not contain any rules.
+func readIgnoreFile(absPath string, base string) ([]*ignoreRule, error) {
+	file, err := os.Open(absPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := []*ignoreRule{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		rule := parseIgnoreRule(scanner.Text(), base)
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// dirRules returns the rules of the ignore file in the given
+// slash-separated directory.
+func (m *ignoreMatcher) dirRules(dir string) ([]*ignoreRule, error) {
+	rules, ok := m.dirs[dir]
+	if ok {
+		return rules, nil
+	}
+	absPath := filepath.Join(m.r.Path, filepath.FromSlash(dir), ignoreFileName)
+	rules, err := readIgnoreFile(absPath, dir)
+	if err != nil {
+		return nil, err
+	}
+	m.dirs[dir] = rules
+	return rules, nil
+}
+
+// isIgnored returns whether the item with the given repository relative
+// path is ignored. Items below ignored directories are always ignored.
+func (m *ignoreMatcher) isIgnored(relPath string, isDir bool) (bool, error) {
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	rules := m.local
+	for i := range segments {
+		dir := strings.Join(segments[:i], "/")
+		dirRules, err := m.dirRules(dir)
+		if err != nil {
+			return false, err
+		}
+		rules = append(rules[:len(rules):len(rules)], dirRules...)
+
+		itemPath := strings.Join(segments[:i+1], "/")
+		if matchRules(rules, itemPath, isDir || i < len(segments)-1) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchRules returns whether the given slash-separated path is ignored by
+// the rules; later rules take precedence.
+func matchRules(rules []*ignoreRule, slashPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.matches(slashPath, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// IsIgnored returns whether the item at the given path is excluded from
+// the repository by an ignore file.
+func (r *ClientRepository) IsIgnored(absPath string) (bool, error) {
+	relPath, err := r.getRepoRelativePath(absPath)
+	if err != nil {
+		return false, err
+	}
+	m, err := r.newIgnoreMatcher()
+	if err != nil {
+		return false, err
+	}
+	stat, err := os.Lstat(absPath)
+	isDir := err == nil && stat.IsDir()
+	return m.isIgnored(relPath, isDir)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&IgnoreTests{})
+
+type IgnoreTests struct {
+	clientPairTests
+}
+
+// writeFile writes the given content to the relative path in mine,
+// creating parent directories as required.
+func (t *IgnoreTests) writeFile(c *C, relPath string, content string) {
+	absPath := filepath.Join(t.mine.Path, relPath)
+	err := os.MkdirAll(filepath.Dir(absPath), 0700)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(absPath, []byte(content), 0600)
+	c.Assert(err, IsNil)
+}
+
+// isTracked returns whether the item at the relative path has a NIB.
+func (t *IgnoreTests) isTracked(c *C, relPath string) bool {
+	_, err := t.mine.nibForPath(filepath.Join(t.mine.Path, relPath))
+	if os.IsNotExist(err) {
+		return false
+	}
+	c.Assert(err, IsNil)
+	return true
+}
+
+func (t *IgnoreTests) TestRuleMatches(c *C) {
+	tests := []struct {
+		line    string
+		base    string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"*.o", "", "foo.o", false, true},
+		{"*.o", "", "src/foo.o", false, true},
+		{"*.o", "", "foo.c", false, false},
+		{"build/", "", "build", true, true},
+		{"build/", "", "build", false, false},
+		{"/foo.txt", "", "foo.txt", false, true},
+		{"/foo.txt", "", "sub/foo.txt", false, false},
+		{"doc/*.txt", "", "doc/a.txt", false, true},
+		{"doc/*.txt", "", "doc/sub/a.txt", false, false},
+		{"**/tmp", "", "a/b/tmp", true, true},
+		{"a/**/b", "", "a/b", false, true},
+		{"a/**/b", "", "a/x/y/b", false, true},
+		{"*.log", "sub", "sub/x.log", false, true},
+		{"*.log", "sub", "x.log", false, false},
+		{"/x.log", "sub", "sub/x.log", false, true},
+	}
+	for _, test := range tests {
+		rule := parseIgnoreRule(test.line, test.base)
+		c.Assert(rule, NotNil)
+		c.Assert(rule.matches(test.path, test.isDir), Equals, test.matches,
+			Commentf("%s in %q for %s", test.line, test.base, test.path))
+	}
+}
+
+func (t *IgnoreTests) TestParseSkipsCommentsAndInvalid(c *C) {
+	for _, line := range []string{"", "   ", "# comment", "[", "/"} {
+		c.Assert(parseIgnoreRule(line, ""), IsNil, Commentf("%q", line))
+	}
+	rule := parseIgnoreRule(`\#foo`, "")
+	c.Assert(rule, NotNil)
+	c.Assert(rule.matches("#foo", false), Equals, true)
+}
+
+func (t *IgnoreTests) TestAddSkipsIgnored(c *C) {
+	t.writeFile(c, ignoreFileName, "*.swp\nbuild/\n!keep.swp\n")
+	t.writeFile(c, "foo.txt", "foo")
+	t.writeFile(c, "foo.txt.swp", "swap")
+	t.writeFile(c, "keep.swp", "keep")
+	t.writeFile(c, filepath.Join("build", "out.bin"), "out")
+	t.writeFile(c, filepath.Join("sub", ignoreFileName), "*.txt\n")
+	t.writeFile(c, filepath.Join("sub", "bar.txt"), "bar")
+	t.writeFile(c, filepath.Join("sub", "bar.dat"), "bar")
+
+	err := t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.isTracked(c, ignoreFileName), Equals, true)
+	c.Assert(t.isTracked(c, "foo.txt"), Equals, true)
+	c.Assert(t.isTracked(c, "foo.txt.swp"), Equals, false)
+	c.Assert(t.isTracked(c, "keep.swp"), Equals, true)
+	c.Assert(t.isTracked(c, filepath.Join("build", "out.bin")), Equals, false)
+	c.Assert(t.isTracked(c, filepath.Join("sub", "bar.txt")), Equals, false)
+	c.Assert(t.isTracked(c, filepath.Join("sub", "bar.dat")), Equals, true)
+}
+
+func (t *IgnoreTests) TestAddIgnoredItem(c *C) {
+	t.writeFile(c, ignoreFileName, "*.swp\n")
+	t.writeFile(c, "foo.swp", "swap")
+	err := t.mine.AddItem(filepath.Join(t.mine.Path, "foo.swp"))
+	c.Assert(err, Equals, ErrPathIgnored)
+}
+
+func (t *IgnoreTests) TestLocalExclude(c *C) {
+	err := ioutil.WriteFile(t.mine.subPathFor(localExcludeFileName), []byte(".DS_Store\n"), 0600)
+	c.Assert(err, IsNil)
+	t.writeFile(c, ".DS_Store", "junk")
+
+	ignored, err := t.mine.IsIgnored(filepath.Join(t.mine.Path, ".DS_Store"))
+	c.Assert(err, IsNil)
+	c.Assert(ignored, Equals, true)
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+	c.Assert(t.isTracked(c, ".DS_Store"), Equals, false)
+}
+
+func (t *IgnoreTests) TestStatusReportsTrackedIgnored(c *C) {
+	t.writeFile(c, "foo.log", "log")
+	t.writeFile(c, "bar.log", "log")
+	t.writeFile(c, "new.log", "log")
+	err := t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+	t.writeFile(c, ignoreFileName, "*.log\n")
+	t.writeFile(c, "untracked.log", "log")
+	err = os.Remove(filepath.Join(t.mine.Path, "bar.log"))
+	c.Assert(err, IsNil)
+
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	states := map[string]FileState{}
+	for _, file := range status.Files {
+		states[file.Path] = file.State
+	}
+	c.Assert(states, DeepEquals, map[string]FileState{
+		ignoreFileName: FileNew,
+		"foo.log":      FileIgnored,
+		"new.log":      FileIgnored,
+		"bar.log":      FileDeleted,
+	})
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+
+	"github.com/hoffie/larasync/helpers/crypto"
+	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// DeviceAuthorization is an Authorization which has been encrypted for a
+// specific device.
+type DeviceAuthorization struct {
+	Device *Device
+	Data   []byte
+}
+
+// RevokeDevice removes the device with the given ID from the device
+// registry and replaces the repository encryption and signing keys, which
+// are known to the device, by new ones.
+// All objects referenced by NIBs are re-wrapped with the new encryption
+// key and all NIBs are signed again by this device. Objects which are not
+// stored locally are fetched first, so that none of them remains wrapped
+// with the old key. The IDs of the re-wrapped objects are returned as they
+// have to be uploaded again.
+func (r *ClientRepository) RevokeDevice(deviceID string) ([]string, error) {
+	ownID, err := r.DeviceID()
+	if err != nil {
+		return nil, err
+	}
+	if deviceID == ownID {
+		return nil, ErrRevokingOwnDevice
+	}
+	registry, err := r.devices.Get()
+	if err != nil {
+		return nil, err
+	}
+	if !registry.Remove(deviceID) {
+		return nil, ErrDeviceNotFound
+	}
+	err = r.fetchMissingObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := r.NewAuthorization()
+	if err != nil {
+		return nil, err
+	}
+	_, err = rand.Read(auth.EncryptionKey[:])
+	if err != nil {
+		return nil, err
+	}
+	_, signingKey, err := edhelpers.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	auth.SigningKey = *signingKey
+
+	return r.rotateKeys(auth, func() error {
+		return r.devices.Set(registry)
+	})
+}
+
+// fetchMissingObjects stores all objects referenced by NIBs which are not
+// available locally, such as the objects evicted by thin clients.
+func (r *ClientRepository) fetchMissingObjects() error {
+	nibs, err := r.GetAllNibs()
+	if err != nil {
+		return err
+	}
+	missing := []string{}
+	seen := make(map[string]bool)
+	for n := range nibs {
+		for _, objectID := range n.AllObjectIDs() {
+			if seen[objectID] || r.HasObject(objectID) {
+				continue
+			}
+			seen[objectID] = true
+			missing = append(missing, objectID)
+		}
+	}
+	if len(missing) > 0 && r.objectFetcher == nil {
+		return ErrNoObjectFetcher
+	}
+	for _, objectID := range missing {
+		err = r.refetchObject(objectID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportKeyRotation replaces the repository keys by the ones passed in
+// the authorization, which has been handed to this device after another
+// device has been revoked. The device registry is replaced by the given
+// one, which has to be signed by the new signing key.
+func (r *ClientRepository) ImportKeyRotation(auth *Authorization, registryData []byte) error {
+	pubKey := edhelpers.GetPublicKeyFromPrivate(auth.SigningKey)
+	_, err := r.devices.verifyAndParseBytesWith(pubKey, registryData)
+	if err != nil {
+		return err
+	}
+	_, err = r.rotateKeys(auth, func() error {
+		return r.devices.SetBytes(registryData)
+	})
+	return err
+}
+
+// rotateKeys stores the keys of the given authorization and calls
+// storeRegistry to replace the device registry afterwards. It then
+// re-wraps all locally stored objects and signs all NIBs again.
+// It returns the IDs of the re-wrapped objects.
+func (r *ClientRepository) rotateKeys(auth *Authorization, storeRegistry func() error) ([]string, error) {
+	// NIBs have to be loaded before the keys change as they cannot be
+	// verified afterwards.
+	nibs := []*nib.NIB{}
+	nibChannel, err := r.GetAllNibs()
+	if err != nil {
+		return nil, err
+	}
+	for n := range nibChannel {
+		nibs = append(nibs, n)
+	}
+
+	oldKey, err := r.keys.EncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	// the previous key is kept to be able to read objects which have not
+	// been re-wrapped, e.g. as the rotation has been interrupted. Importing
+	// the same keys again must not replace it.
+	if oldKey != auth.EncryptionKey {
+		err = r.keys.SetPreviousEncryptionKey(oldKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = r.SetKeysFromAuth(auth)
+	if err != nil {
+		return nil, err
+	}
+	err = storeRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	objectIDs := []string{}
+	seen := make(map[string]bool)
+	for _, n := range nibs {
+		for _, objectID := range n.AllObjectIDs() {
+			if seen[objectID] || !r.HasObject(objectID) {
+				continue
+			}
+			seen[objectID] = true
+			err = r.rewrapObject(objectID, oldKey, auth.EncryptionKey)
+			if err != nil {
+				return nil, err
+			}
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+
+	for _, n := range nibs {
+		err = r.nibStore.resign(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objectIDs, nil
+}
+
+// rewrapObject re-encrypts the per-object key of the given object with the
+// new encryption key. Objects which have been re-wrapped already are left
+// untouched.
+func (r *ClientRepository) rewrapObject(id string, oldKey, newKey [EncryptionKeySize]byte) error {
+	reader, err := r.getObject(id)
+	if err != nil {
+		return err
+	}
+	enc, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	rewrapped, err := crypto.NewBox(oldKey).Rewrap(enc, newKey)
+	if err != nil {
+		// succeeds if the object is wrapped with the new key already
+		_, newErr := crypto.NewBox(newKey).Rewrap(enc, newKey)
+		if newErr == nil {
+			return nil
+		}
+		return err
+	}
+	return r.objectStorage.Set(id, bytes.NewReader(rewrapped))
+}
+
+// DeviceAuthorizations returns the current Authorization encrypted for
+// every registered device except this one. Devices which did not publish
+// an encryption key cannot be handled this way; they are returned
+// separately.
+func (r *ClientRepository) DeviceAuthorizations() ([]*DeviceAuthorization, []*Device, error) {
+	ownID, err := r.DeviceID()
+	if err != nil {
+		return nil, nil, err
+	}
+	registry, err := r.devices.Get()
+	if err != nil {
+		return nil, nil, err
+	}
+	auth, err := r.NewAuthorization()
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := &bytes.Buffer{}
+	_, err = auth.WriteTo(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authorizations := []*DeviceAuthorization{}
+	skipped := []*Device{}
+	for _, device := range registry.Devices {
+		if device.ID() == ownID {
+			continue
+		}
+		if !device.HasEncryptionKey() {
+			skipped = append(skipped, device)
+			continue
+		}
+		data, err := crypto.Seal(device.EncryptionPublicKey, buf.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+		authorizations = append(authorizations, &DeviceAuthorization{
+			Device: device,
+			Data:   data,
+		})
+	}
+	return authorizations, skipped, nil
+}
+
+// OpenDeviceAuthorization decrypts an Authorization which has been
+// encrypted for this device.
+func (r *ClientRepository) OpenDeviceAuthorization(data []byte) (*Authorization, error) {
+	key, err := r.keys.DeviceEncryptionPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := crypto.OpenSealed(key, data)
+	if err != nil {
+		return nil, err
+	}
+	auth := &Authorization{}
+	_, err = auth.ReadFrom(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
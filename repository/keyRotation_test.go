@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/helpers/crypto"
+	edhelpers "github.com/hoffie/larasync/helpers/ed25519"
+)
+
+var _ = Suite(&KeyRotationTests{})
+
+type KeyRotationTests struct {
+	clientPairTests
+	lost *Device
+}
+
+// SetUpTest additionally registers a third device which gets revoked by
+// the tests.
+func (t *KeyRotationTests) SetUpTest(c *C) {
+	t.clientPairTests.SetUpTest(c)
+	pubKey, _, err := edhelpers.GenerateKey()
+	c.Assert(err, IsNil)
+	boxPubKey, _, err := crypto.GenerateBoxKey()
+	c.Assert(err, IsNil)
+	t.lost = &Device{
+		PublicKey:           *pubKey,
+		EncryptionPublicKey: *boxPubKey,
+		Name:                "lost laptop",
+	}
+	registry, err := t.mine.devices.Get()
+	c.Assert(err, IsNil)
+	registry.Add(t.lost)
+	err = t.mine.devices.Set(registry)
+	c.Assert(err, IsNil)
+	t.shareDeviceRegistries(c)
+}
+
+func (t *KeyRotationTests) deviceID(c *C, r *ClientRepository) string {
+	id, err := r.DeviceID()
+	c.Assert(err, IsNil)
+	return id
+}
+
+func (t *KeyRotationTests) TestRevokeDevice(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	oldAuth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+
+	objectIDs, err := t.mine.RevokeDevice(t.lost.ID())
+	c.Assert(err, IsNil)
+	c.Assert(objectIDs, Not(HasLen), 0)
+
+	newAuth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	c.Assert(newAuth.EncryptionKey, Not(Equals), oldAuth.EncryptionKey)
+	c.Assert(newAuth.SigningKey, Not(Equals), oldAuth.SigningKey)
+	c.Assert(newAuth.HashingKey, Equals, oldAuth.HashingKey)
+
+	registry, err := t.mine.DeviceRegistry()
+	c.Assert(err, IsNil)
+	c.Assert(registry.Get(t.lost.ID()), IsNil)
+	c.Assert(registry.Devices, HasLen, 2)
+	keys, err := t.mine.AcceptedSigningPublicKeys()
+	c.Assert(err, IsNil)
+	for _, key := range keys {
+		c.Assert(key, Not(Equals), t.lost.PublicKey)
+		c.Assert(key, Not(Equals), edhelpers.GetPublicKeyFromPrivate(oldAuth.SigningKey))
+	}
+
+	for _, objectID := range objectIDs {
+		reader, err := t.mine.objectStorage.Get(objectID)
+		c.Assert(err, IsNil)
+		enc, err := ioutil.ReadAll(reader)
+		reader.Close()
+		c.Assert(err, IsNil)
+		_, err = crypto.NewBox(oldAuth.EncryptionKey).DecryptContent(enc)
+		c.Assert(err, NotNil)
+		_, err = t.mine.readEncryptedObject(objectID)
+		c.Assert(err, IsNil)
+	}
+
+	history, err := t.mine.History(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+}
+
+// evictObject removes the content object of foo.txt from mine after
+// storing it in theirs and returns its ID.
+func (t *KeyRotationTests) evictObject(c *C) string {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.copyNIBData(c, t.mine, t.theirs, "foo.txt")
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	objectID := n.Revisions[0].ContentIDs[0]
+	err = t.mine.objectStorage.Delete(objectID)
+	c.Assert(err, IsNil)
+	return objectID
+}
+
+func (t *KeyRotationTests) TestRevokeFetchesMissingObjects(c *C) {
+	objectID := t.evictObject(c)
+	oldAuth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	t.mine.SetObjectFetcher(&repositoryFetcher{r: t.theirs})
+
+	objectIDs, err := t.mine.RevokeDevice(t.lost.ID())
+	c.Assert(err, IsNil)
+	rewrapped := false
+	for _, id := range objectIDs {
+		rewrapped = rewrapped || id == objectID
+	}
+	c.Assert(rewrapped, Equals, true)
+	reader, err := t.mine.objectStorage.Get(objectID)
+	c.Assert(err, IsNil)
+	enc, err := ioutil.ReadAll(reader)
+	reader.Close()
+	c.Assert(err, IsNil)
+	_, err = crypto.NewBox(oldAuth.EncryptionKey).DecryptContent(enc)
+	c.Assert(err, NotNil)
+	_, err = t.mine.readEncryptedObject(objectID)
+	c.Assert(err, IsNil)
+}
+
+func (t *KeyRotationTests) TestRevokeWithMissingObjectsWithoutFetcher(c *C) {
+	t.evictObject(c)
+	oldAuth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+
+	_, err = t.mine.RevokeDevice(t.lost.ID())
+	c.Assert(err, Equals, ErrNoObjectFetcher)
+	auth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	c.Assert(auth.EncryptionKey, Equals, oldAuth.EncryptionKey)
+	registry, err := t.mine.DeviceRegistry()
+	c.Assert(err, IsNil)
+	c.Assert(registry.Get(t.lost.ID()), NotNil)
+}
+
+func (t *KeyRotationTests) TestRevokeOwnDevice(c *C) {
+	_, err := t.mine.RevokeDevice(t.deviceID(c, t.mine))
+	c.Assert(err, Equals, ErrRevokingOwnDevice)
+}
+
+func (t *KeyRotationTests) TestRevokeUnknownDevice(c *C) {
+	_, err := t.mine.RevokeDevice("unknown")
+	c.Assert(err, Equals, ErrDeviceNotFound)
+}
+
+func (t *KeyRotationTests) TestDeviceAuthorizations(c *C) {
+	authorizations, skipped, err := t.mine.DeviceAuthorizations()
+	c.Assert(err, IsNil)
+	c.Assert(skipped, HasLen, 0)
+	c.Assert(authorizations, HasLen, 2)
+
+	var theirs *DeviceAuthorization
+	for _, authorization := range authorizations {
+		if authorization.Device.ID() == t.deviceID(c, t.theirs) {
+			theirs = authorization
+		}
+	}
+	c.Assert(theirs, NotNil)
+
+	auth, err := t.theirs.OpenDeviceAuthorization(theirs.Data)
+	c.Assert(err, IsNil)
+	expected, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	c.Assert(auth.EncryptionKey, Equals, expected.EncryptionKey)
+
+	_, err = t.mine.OpenDeviceAuthorization(theirs.Data)
+	c.Assert(err, NotNil)
+}
+
+func (t *KeyRotationTests) TestImportKeyRotation(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.theirs, "bar.txt", []byte("bar"))
+
+	_, err = t.mine.RevokeDevice(t.lost.ID())
+	c.Assert(err, IsNil)
+	authorizations, _, err := t.mine.DeviceAuthorizations()
+	c.Assert(err, IsNil)
+	c.Assert(authorizations, HasLen, 1)
+	auth, err := t.theirs.OpenDeviceAuthorization(authorizations[0].Data)
+	c.Assert(err, IsNil)
+	registryData, err := t.mine.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+
+	err = t.theirs.ImportKeyRotation(auth, registryData)
+	c.Assert(err, IsNil)
+
+	registry, err := t.theirs.DeviceRegistry()
+	c.Assert(err, IsNil)
+	c.Assert(registry.Get(t.lost.ID()), IsNil)
+
+	// both devices accept each other's NIBs and objects again
+	err = t.transferNIB(c, t.theirs, t.mine, "bar.txt")
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo changed"))
+	err = t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	err = t.mine.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+}
+
+func (t *KeyRotationTests) TestImportKeyRotationForeignRegistry(c *C) {
+	auth, err := t.mine.NewAuthorization()
+	c.Assert(err, IsNil)
+	_, signingKey, err := edhelpers.GenerateKey()
+	c.Assert(err, IsNil)
+	auth.SigningKey = *signingKey
+	registryData, err := t.mine.GetDeviceRegistryBytes()
+	c.Assert(err, IsNil)
+
+	err = t.theirs.ImportKeyRotation(auth, registryData)
+	c.Assert(err, Equals, ErrSignatureVerification)
+}
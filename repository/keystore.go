@@ -24,12 +24,18 @@ const (
 	// HashingKeySize represents the size of the key used for
 	// generating content hashes (HMAC).
 	HashingKeySize = crypto.HashingKeySize
+	// BoxKeySize represents the size of the keys used for encrypting
+	// data for a specific device.
+	BoxKeySize = crypto.BoxKeySize
 
 	// ids for our keys in the storage
-	encryptionKeyName     = "encryption.key"
-	hashingKeyName        = "hashing.key"
-	signingPrivateKeyName = "signing.priv"
-	signingPublicKeyName  = "signing.pub"
+	encryptionKeyName         = "encryption.key"
+	hashingKeyName            = "hashing.key"
+	signingPrivateKeyName     = "signing.priv"
+	signingPublicKeyName      = "signing.pub"
+	deviceKeyName             = "device.priv"
+	deviceBoxKeyName          = "device.box"
+	previousEncryptionKeyName = "encryption.key.previous"
 )
 
 // KeyStore is responsible for loading keys from the storage backend.
@@ -108,6 +114,70 @@ func (ks *KeyStore) signingPublicKeyFromStorage() ([PublicKeySize]byte, error) {
 	return arrKey, err
 }
 
+// SetDeviceSigningPrivateKey sets the signing private key of this device.
+func (ks *KeyStore) SetDeviceSigningPrivateKey(key [PrivateKeySize]byte) error {
+	return ks.storage.SetBytes(deviceKeyName, key[:])
+}
+
+// DeviceSigningPrivateKey returns the signing private key of this device.
+func (ks *KeyStore) DeviceSigningPrivateKey() ([PrivateKeySize]byte, error) {
+	key, err := ks.storage.GetBytes(deviceKeyName)
+	if err != nil {
+		return [PrivateKeySize]byte{}, err
+	}
+	if len(key) != PrivateKeySize {
+		return [PrivateKeySize]byte{}, fmt.Errorf(
+			"invalid key length (%d)", len(key))
+	}
+	var arrKey [PrivateKeySize]byte
+	copy(arrKey[:], key)
+	return arrKey, nil
+}
+
+// SetDeviceEncryptionPrivateKey sets the private key which is used to
+// decrypt data sent to this device.
+func (ks *KeyStore) SetDeviceEncryptionPrivateKey(key [BoxKeySize]byte) error {
+	return ks.storage.SetBytes(deviceBoxKeyName, key[:])
+}
+
+// DeviceEncryptionPrivateKey returns the private key which is used to
+// decrypt data sent to this device.
+func (ks *KeyStore) DeviceEncryptionPrivateKey() ([BoxKeySize]byte, error) {
+	key, err := ks.storage.GetBytes(deviceBoxKeyName)
+	if err != nil {
+		return [BoxKeySize]byte{}, err
+	}
+	if len(key) != BoxKeySize {
+		return [BoxKeySize]byte{}, fmt.Errorf(
+			"invalid key length (%d)", len(key))
+	}
+	var arrKey [BoxKeySize]byte
+	copy(arrKey[:], key)
+	return arrKey, nil
+}
+
+// SetPreviousEncryptionKey stores the encryption key which has been
+// replaced by the latest key rotation.
+func (ks *KeyStore) SetPreviousEncryptionKey(key [EncryptionKeySize]byte) error {
+	return ks.storage.SetBytes(previousEncryptionKeyName, key[:])
+}
+
+// PreviousEncryptionKey returns the encryption key which has been replaced
+// by the latest key rotation.
+func (ks *KeyStore) PreviousEncryptionKey() ([EncryptionKeySize]byte, error) {
+	key, err := ks.storage.GetBytes(previousEncryptionKeyName)
+	if err != nil {
+		return [EncryptionKeySize]byte{}, err
+	}
+	if len(key) != EncryptionKeySize {
+		return [EncryptionKeySize]byte{}, fmt.Errorf(
+			"invalid key length (%d)", len(key))
+	}
+	var arrKey [EncryptionKeySize]byte
+	copy(arrKey[:], key)
+	return arrKey, nil
+}
+
 // SetHashingKey sets the repository hashing key (content addressing)
 func (ks *KeyStore) SetHashingKey(key [HashingKeySize]byte) error {
 	return ks.storage.SetBytes(hashingKeyName, key[:])
@@ -149,6 +219,28 @@ func (ks *KeyStore) CreateSigningKey() error {
 	return err
 }
 
+// CreateDeviceSigningKey generates a random signing key for this device.
+func (ks *KeyStore) CreateDeviceSigningKey() error {
+	_, privKey, err := edhelpers.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if privKey == nil {
+		return errors.New("no private key generated")
+	}
+	return ks.SetDeviceSigningPrivateKey(*privKey)
+}
+
+// CreateDeviceEncryptionKey generates a random key pair which is used to
+// encrypt data for this device.
+func (ks *KeyStore) CreateDeviceEncryptionKey() error {
+	_, privKey, err := crypto.GenerateBoxKey()
+	if err != nil {
+		return err
+	}
+	return ks.SetDeviceEncryptionPrivateKey(*privKey)
+}
+
 // CreateHashingKey generates a random hashing key.
 func (ks *KeyStore) CreateHashingKey() error {
 	key := make([]byte, HashingKeySize)
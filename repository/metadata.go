@@ -3,6 +3,7 @@ package repository
 import (
 	"bytes"
 	"io"
+	"os"
 
 	"github.com/golang/protobuf/proto"
 
@@ -14,12 +15,25 @@ const (
 	MetadataTypeFile = iota
 	// MetadataTypeDir marks a Metadata.Type attribute as a dir
 	MetadataTypeDir
+	// MetadataTypeSymlink marks a Metadata.Type attribute as a symlink
+	MetadataTypeSymlink
 )
 
 // Metadata describes information about a node such as its type and name.
 type Metadata struct {
 	Type             int32
 	RepoRelativePath string
+	// Mode holds the permission bits; it is 0 if they are unknown.
+	Mode os.FileMode
+	// ModTime is the modification time in nanoseconds since the Unix
+	// epoch; it is 0 if it is unknown.
+	ModTime int64
+	// SymlinkTarget is the target of symlinks.
+	SymlinkTarget string
+	// MovedFrom is the previous repository relative path of an item
+	// which has been moved; it is only set in the revision recording
+	// the move.
+	MovedFrom string
 }
 
 // WriteTo encodes this Metadata object to the supplied Writer in binary
@@ -27,13 +41,28 @@ type Metadata struct {
 // Returns the number of bytes written and an error if applicable.
 func (m *Metadata) WriteTo(w io.Writer) (int64, error) {
 	t := odf.NodeType_File
-	if m.Type == MetadataTypeDir {
+	switch m.Type {
+	case MetadataTypeDir:
 		t = odf.NodeType_Dir
+	case MetadataTypeSymlink:
+		t = odf.NodeType_Symlink
 	}
 	pb := &odf.Metadata{
 		Type:             &t,
 		RepoRelativePath: &m.RepoRelativePath,
 	}
+	if m.Mode != 0 {
+		pb.Mode = proto.Uint32(uint32(m.Mode))
+	}
+	if m.ModTime != 0 {
+		pb.ModTime = proto.Int64(m.ModTime)
+	}
+	if m.SymlinkTarget != "" {
+		pb.SymlinkTarget = proto.String(m.SymlinkTarget)
+	}
+	if m.MovedFrom != "" {
+		pb.MovedFrom = proto.String(m.MovedFrom)
+	}
 	buf, err := proto.Marshal(pb)
 	if err != nil {
 		return 0, err
@@ -56,10 +85,17 @@ func (m *Metadata) ReadFrom(r io.Reader) (int64, error) {
 		return read, err
 	}
 	m.RepoRelativePath = pb.GetRepoRelativePath()
-	if pb.GetType() == odf.NodeType_Dir {
+	switch pb.GetType() {
+	case odf.NodeType_Dir:
 		m.Type = MetadataTypeDir
-	} else {
+	case odf.NodeType_Symlink:
+		m.Type = MetadataTypeSymlink
+	default:
 		m.Type = MetadataTypeFile
 	}
+	m.Mode = os.FileMode(pb.GetMode())
+	m.ModTime = pb.GetModTime()
+	m.SymlinkTarget = pb.GetSymlinkTarget()
+	m.MovedFrom = pb.GetMovedFrom()
 	return read, nil
 }
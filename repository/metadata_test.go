@@ -45,3 +45,22 @@ func (t *MetadataTests) TestSerializeDir(c *C) {
 
 	c.Assert(m1, DeepEquals, m2)
 }
+
+func (t *MetadataTests) TestSerializeAttributes(c *C) {
+	m1 := Metadata{
+		Type:             MetadataTypeSymlink,
+		RepoRelativePath: "foo.txt",
+		Mode:             0755,
+		ModTime:          1234567890123456789,
+		SymlinkTarget:    "bar.txt",
+		MovedFrom:        "baz.txt",
+	}
+	buf := &bytes.Buffer{}
+	_, err := m1.WriteTo(buf)
+	c.Assert(err, IsNil)
+
+	m2 := Metadata{}
+	_, err = m2.ReadFrom(buf)
+	c.Assert(err, IsNil)
+	c.Assert(m1, DeepEquals, m2)
+}
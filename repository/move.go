@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hoffie/larasync/helpers"
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// moveSource is a tracked item which vanished from the work dir and may
+// have been moved to a new path.
+type moveSource struct {
+	relPath string
+	nib     *nib.NIB
+	// live is set if the deletion of the item has not been recorded yet.
+	live bool
+}
+
+// moveDetector finds the previous location of new work dir items by
+// matching their content with the content of vanished items.
+type moveDetector struct {
+	r *ClientRepository
+	// vanished maps the joined content ids of vanished items to the
+	// items; it is loaded on first use.
+	vanished map[string][]*moveSource
+}
+
+// newMoveDetector returns a moveDetector for this repository.
+func (r *ClientRepository) newMoveDetector() *moveDetector {
+	return &moveDetector{r: r}
+}
+
+// contentKey returns the key under which items with the given content ids
+// are indexed.
+func contentKey(contentIDs []string) string {
+	return strings.Join(contentIDs, ",")
+}
+
+// isNewNIB returns whether the given NIB does not describe an existing
+// item yet, i.e. whether it has no revisions or marks the item as deleted.
+func isNewNIB(n *nib.NIB) bool {
+	latest, err := n.LatestRevision()
+	return err != nil || latest.IsDeletion()
+}
+
+// lastContentRevision returns the latest revision of the given NIB which
+// does not mark the item as deleted or nil if there is none.
+func lastContentRevision(n *nib.NIB) *nib.Revision {
+	for i := len(n.Revisions) - 1; i >= 0; i-- {
+		if !n.Revisions[i].IsDeletion() {
+			return n.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// load indexes all selected files and symlinks whose path does not exist
+// in the work dir anymore. Directories are not considered as they all
+// share the same content.
+func (d *moveDetector) load() error {
+	d.vanished = map[string][]*moveSource{}
+	sparse, err := d.r.SparseConfig()
+	if err != nil {
+		return err
+	}
+	nibs, err := d.r.GetAllNibs()
+	if err != nil {
+		return err
+	}
+	for n := range nibs {
+		rev := lastContentRevision(n)
+		if rev == nil {
+			continue
+		}
+		metadata, err := d.r.metadataByID(rev.MetadataID)
+		if err != nil {
+			return err
+		}
+		relPath := metadata.RepoRelativePath
+		if metadata.Type == MetadataTypeDir || !sparse.Selects(relPath) {
+			continue
+		}
+		_, err = os.Lstat(filepath.Join(d.r.Path, relPath))
+		if !os.IsNotExist(err) {
+			continue
+		}
+		latest, err := n.LatestRevision()
+		if err != nil {
+			return err
+		}
+		key := contentKey(rev.ContentIDs)
+		d.vanished[key] = append(d.vanished[key], &moveSource{
+			relPath: relPath,
+			nib:     n,
+			live:    !latest.IsDeletion(),
+		})
+	}
+	return nil
+}
+
+// find returns the vanished item which has most likely been moved to
+// relPath or nil if there is none. Items with the same name are
+// preferred. Each vanished item is returned at most once.
+func (d *moveDetector) find(relPath string, contentIDs []string) (*moveSource, error) {
+	if len(contentIDs) == 0 {
+		return nil, nil
+	}
+	if d.vanished == nil {
+		err := d.load()
+		if err != nil {
+			return nil, err
+		}
+	}
+	key := contentKey(contentIDs)
+	candidates := d.vanished[key]
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	found := 0
+	for i, candidate := range candidates {
+		if filepath.Base(candidate.relPath) == filepath.Base(relPath) {
+			found = i
+			break
+		}
+	}
+	source := candidates[found]
+	d.vanished[key] = append(candidates[:found], candidates[found+1:]...)
+	return source, nil
+}
+
+// recordMoves stores the NIBs of moved items together with the deletion
+// of their previous locations in a single transaction.
+func (r *ClientRepository) recordMoves(moved []*nib.NIB, previous []*nib.NIB) error {
+	nibs := append([]*nib.NIB{}, moved...)
+	for _, n := range previous {
+		latest, err := n.LatestRevision()
+		if err != nil {
+			return err
+		}
+		rev, err := r.deletionRevision(latest)
+		if err != nil {
+			return err
+		}
+		n.AppendRevision(rev)
+		nibs = append(nibs, n)
+	}
+	return r.nibStore.AddAll(nibs)
+}
+
+// Move renames the work dir item at srcPath to dstPath and records the
+// move of the item and of all tracked items below it in a single
+// transaction. Untracked items which are moved along are added.
+func (r *ClientRepository) Move(srcPath, dstPath string) error {
+	for _, absPath := range []string{srcPath, dstPath} {
+		if r.isBelowManagementDir(absPath) {
+			return ErrRefusingWorkOnDotLara
+		}
+	}
+	srcRelPath, err := r.getRepoRelativePath(srcPath)
+	if err != nil {
+		return err
+	}
+	dstRelPath, err := r.getRepoRelativePath(dstPath)
+	if err != nil {
+		return err
+	}
+	_, err = os.Lstat(dstPath)
+	if err == nil {
+		return ErrMoveTargetExists
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	tracker, err := r.NIBTracker()
+	if err != nil {
+		return err
+	}
+	found, err := tracker.SearchPrefix(srcRelPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(srcPath, dstPath)
+	if err != nil {
+		return err
+	}
+
+	moved := []*nib.NIB{}
+	previous := []*nib.NIB{}
+	for _, item := range found {
+		old, err := r.nibStore.Get(item.NIBID)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if isNewNIB(old) {
+			continue
+		}
+		newAbsPath := filepath.Join(r.Path,
+			dstRelPath+strings.TrimPrefix(item.Path, srcRelPath))
+		if _, err := os.Lstat(newAbsPath); err != nil {
+			// the item vanished before it was moved
+			continue
+		}
+		contentIDs, err := r.writeFileToChunks(newAbsPath)
+		if err != nil {
+			return err
+		}
+		newRelPath, err := r.getRepoRelativePath(newAbsPath)
+		if err != nil {
+			return err
+		}
+		n, err := r.nibForRelPath(newRelPath)
+		if err != nil {
+			return err
+		}
+		changed, err := r.appendNodeRevision(n, newAbsPath, contentIDs, item.Path)
+		if err != nil {
+			return err
+		}
+		if changed {
+			moved = append(moved, n)
+		}
+		previous = append(previous, old)
+	}
+	if len(previous) > 0 {
+		err = r.recordMoves(moved, previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.AddItem(dstPath)
+	if err == ErrPathIgnored {
+		return nil
+	}
+	return err
+}
+
+// onlyRecordsMove returns whether the metadata with the given id only
+// differs from the given metadata by recording a move. This keeps items
+// from getting a new revision when they are added after a move.
+func (r *ClientRepository) onlyRecordsMove(metadataID string, metadata *Metadata) (bool, error) {
+	recorded, err := r.metadataByID(metadataID)
+	if err != nil || recorded.MovedFrom == "" {
+		return false, err
+	}
+	recorded.MovedFrom = metadata.MovedFrom
+	return *recorded == *metadata, nil
+}
+
+// isBelowManagementDir returns whether absPath is part of the management
+// directory.
+func (r *ClientRepository) isBelowManagementDir(absPath string) bool {
+	return strings.HasPrefix(absPath+string(filepath.Separator),
+		filepath.Join(r.Path, managementDirName)+string(filepath.Separator))
+}
+
+// renameMovedItem renames the work dir item at the previous location of
+// a moved item to absPath if it still has the content of the given
+// revision, which avoids writing the content again. It returns whether
+// the item has been renamed.
+func (r *ClientRepository) renameMovedItem(absPath string, metadata *Metadata, rev *nib.Revision) (bool, error) {
+	if metadata.MovedFrom == "" || metadata.Type == MetadataTypeDir {
+		return false, nil
+	}
+	oldPath := filepath.Join(r.Path, metadata.MovedFrom)
+	if oldPath == absPath || r.isBelowManagementDir(oldPath) {
+		return false, nil
+	}
+	contentIDs, err := r.workDirContentIDs(oldPath)
+	if err != nil || !helpers.StringsEqual(contentIDs, rev.ContentIDs) {
+		return false, err
+	}
+	err = os.Rename(oldPath, absPath)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
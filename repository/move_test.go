@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&MoveTests{})
+
+type MoveTests struct {
+	clientPairTests
+}
+
+// latestMetadata returns the metadata of the latest revision of the item
+// at the given relative path.
+func (t *MoveTests) latestMetadata(c *C, relPath string) *Metadata {
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, relPath))
+	c.Assert(err, IsNil)
+	rev, err := n.LatestRevision()
+	c.Assert(err, IsNil)
+	metadata, err := t.mine.metadataByID(rev.MetadataID)
+	c.Assert(err, IsNil)
+	return metadata
+}
+
+func (t *MoveTests) isDeleted(c *C, relPath string) bool {
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, relPath))
+	c.Assert(err, IsNil)
+	rev, err := n.LatestRevision()
+	c.Assert(err, IsNil)
+	return rev.IsDeletion()
+}
+
+func (t *MoveTests) lastTransactionSize(c *C) int {
+	transaction, err := t.mine.transactionManager.CurrentTransaction()
+	c.Assert(err, IsNil)
+	return len(transaction.NIBIDs)
+}
+
+func (t *MoveTests) rename(c *C, from, to string) {
+	err := os.Rename(filepath.Join(t.mine.Path, from), filepath.Join(t.mine.Path, to))
+	c.Assert(err, IsNil)
+}
+
+func (t *MoveTests) TestDetectMove(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("content"))
+	t.rename(c, "a.txt", "b.txt")
+
+	err := t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.latestMetadata(c, "b.txt").MovedFrom, Equals, "a.txt")
+	c.Assert(t.isDeleted(c, "a.txt"), Equals, true)
+	c.Assert(t.lastTransactionSize(c), Equals, 2)
+}
+
+func (t *MoveTests) TestModifiedIsNoMove(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("content"))
+	t.rename(c, "a.txt", "b.txt")
+	t.writeAndAdd(c, t.mine, "b.txt", []byte("changed"))
+
+	c.Assert(t.latestMetadata(c, "b.txt").MovedFrom, Equals, "")
+	c.Assert(t.isDeleted(c, "a.txt"), Equals, false)
+}
+
+func (t *MoveTests) TestDetectDirectoryRename(c *C) {
+	err := os.Mkdir(filepath.Join(t.mine.Path, "dir"), 0700)
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "x.txt"), []byte("x"))
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "y.txt"), []byte("y"))
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+	t.rename(c, "dir", "renamed")
+
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+
+	for _, name := range []string{"x.txt", "y.txt"} {
+		metadata := t.latestMetadata(c, filepath.Join("renamed", name))
+		c.Assert(metadata.MovedFrom, Equals, filepath.Join("dir", name))
+		c.Assert(t.isDeleted(c, filepath.Join("dir", name)), Equals, true)
+	}
+}
+
+func (t *MoveTests) TestReceiverRenames(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("content"))
+	err := t.transferNIB(c, t.mine, t.theirs, "a.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	before, err := os.Stat(filepath.Join(t.theirs.Path, "a.txt"))
+	c.Assert(err, IsNil)
+
+	t.rename(c, "a.txt", "b.txt")
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+	for _, relPath := range []string{"a.txt", "b.txt"} {
+		err = t.transferNIB(c, t.mine, t.theirs, relPath)
+		c.Assert(err, IsNil)
+	}
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	after, err := os.Stat(filepath.Join(t.theirs.Path, "b.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(os.SameFile(before, after), Equals, true)
+	_, err = os.Stat(filepath.Join(t.theirs.Path, "a.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *MoveTests) TestMove(c *C) {
+	err := os.Mkdir(filepath.Join(t.mine.Path, "dir"), 0700)
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.mine, filepath.Join("dir", "x.txt"), []byte("x"))
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+
+	err = t.mine.Move(filepath.Join(t.mine.Path, "dir"),
+		filepath.Join(t.mine.Path, "moved"))
+	c.Assert(err, IsNil)
+
+	c.Assert(t.lastTransactionSize(c), Equals, 4)
+	c.Assert(t.latestMetadata(c, "moved").MovedFrom, Equals, "dir")
+	c.Assert(t.latestMetadata(c, filepath.Join("moved", "x.txt")).MovedFrom,
+		Equals, filepath.Join("dir", "x.txt"))
+	c.Assert(t.isDeleted(c, "dir"), Equals, true)
+	c.Assert(t.isDeleted(c, filepath.Join("dir", "x.txt")), Equals, true)
+	data, err := ioutil.ReadFile(filepath.Join(t.mine.Path, "moved", "x.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "x")
+	_, err = os.Stat(filepath.Join(t.mine.Path, "dir"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *MoveTests) TestMoveTargetExists(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("a"))
+	t.writeAndAdd(c, t.mine, "b.txt", []byte("b"))
+
+	err := t.mine.Move(filepath.Join(t.mine.Path, "a.txt"),
+		filepath.Join(t.mine.Path, "b.txt"))
+	c.Assert(err, Equals, ErrMoveTargetExists)
+}
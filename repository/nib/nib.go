@@ -18,6 +18,10 @@ type NIB struct {
 	ID            string
 	Revisions     []*Revision
 	HistoryOffset int64
+	// PrunedRevisionHashes contains the hashes of the revisions which
+	// have been pruned from the beginning of the history, so that
+	// ancestry can still be proven.
+	PrunedRevisionHashes [][]byte
 }
 
 // ReadFrom fills this NIB's data with the contents supplied by
@@ -35,6 +39,7 @@ func (n *NIB) ReadFrom(r io.Reader) (int64, error) {
 	}
 	n.ID = pb.GetID()
 	n.HistoryOffset = pb.GetHistoryOffset()
+	n.PrunedRevisionHashes = pb.GetPrunedRevisionHashes()
 	if pb.Revisions != nil {
 		for _, pbRev := range pb.Revisions {
 			n.AppendRevision(newRevisionFromPb(pbRev))
@@ -47,9 +52,10 @@ func (n *NIB) ReadFrom(r io.Reader) (int64, error) {
 // Returns the number of bytes written and an error if applicable.
 func (n *NIB) WriteTo(w io.Writer) (int64, error) {
 	pb := &odf.NIB{
-		ID:            &n.ID,
-		HistoryOffset: &n.HistoryOffset,
-		Revisions:     make([]*odf.Revision, 0),
+		ID:                   &n.ID,
+		HistoryOffset:        &n.HistoryOffset,
+		PrunedRevisionHashes: n.PrunedRevisionHashes,
+		Revisions:            make([]*odf.Revision, 0),
 	}
 	for _, r := range n.Revisions {
 		pb.Revisions = append(pb.Revisions, r.toPb())
@@ -122,7 +128,9 @@ func (n *NIB) AllObjectIDs() []string {
 // This is used when checking whether it is ok to overwrite an old
 // nib with a newer version.
 // The check compares the overall history length and ensures
-// that a common head can be found.
+// that a common head can be found. If our latest revision has been
+// pruned from the other NIB, the hashes of the pruned revisions have
+// to prove that our history is the beginning of the other's.
 func (n *NIB) IsParentOf(other *NIB) bool {
 	if n.RevisionsTotal() > other.RevisionsTotal() {
 		// if our history is longer than the other's, there is no
@@ -145,6 +153,101 @@ func (n *NIB) IsParentOf(other *NIB) bool {
 			return true
 		}
 	}
-	// no common ancestory => no way to merge this on the NIB level
-	return false
+	return n.isHistoryPrefixOf(other)
+}
+
+// isHistoryPrefixOf returns whether every revision of this NIB's history
+// matches the revision at the same position of the other NIB's history.
+// Histories whose pruned revisions are not known cannot be compared.
+func (n *NIB) isHistoryPrefixOf(other *NIB) bool {
+	for position := int64(0); position < n.RevisionsTotal(); position++ {
+		mine, ok := n.revisionHash(position)
+		if !ok {
+			return false
+		}
+		theirs, ok := other.revisionHash(position)
+		if !ok || !bytes.Equal(mine, theirs) {
+			return false
+		}
+	}
+	return true
+}
+
+// revisionHash returns the hash of the revision at the given position
+// of the history, including pruned revisions.
+func (n *NIB) revisionHash(position int64) ([]byte, bool) {
+	if int64(len(n.PrunedRevisionHashes)) != n.HistoryOffset {
+		return nil, false
+	}
+	if position < n.HistoryOffset {
+		return n.PrunedRevisionHashes[position], true
+	}
+	index := position - n.HistoryOffset
+	if index >= int64(len(n.Revisions)) {
+		return nil, false
+	}
+	return n.Revisions[index].Hash(), true
+}
+
+// Prune removes the given number of revisions from the beginning of the
+// history; the latest revision is always kept. The hashes of the removed
+// revisions are recorded and HistoryOffset is increased accordingly, so
+// RevisionsTotal does not change.
+// It returns the number of removed revisions.
+func (n *NIB) Prune(count int) int {
+	if count > len(n.Revisions)-1 {
+		count = len(n.Revisions) - 1
+	}
+	if count <= 0 {
+		return 0
+	}
+	for _, rev := range n.Revisions[:count] {
+		n.PrunedRevisionHashes = append(n.PrunedRevisionHashes, rev.Hash())
+	}
+	n.Revisions = append([]*Revision{}, n.Revisions[count:]...)
+	n.HistoryOffset += int64(count)
+	return count
+}
+
+// CommonAncestor returns the most recent revision of this NIB which
+// is also part of the history of the other NIB. Revisions are matched by
+// their position in the history and their identity, so that an item which
+// returned to an earlier content does not match its earlier revision.
+// ErrNoRevision is returned if the histories differ from the beginning
+// or if the common revision has been pruned from this NIB.
+//
+// This is the base revision when merging two diverged NIBs.
+func (n *NIB) CommonAncestor(other *NIB) (*Revision, error) {
+	var base *Revision
+	for position := int64(0); position < n.RevisionsTotal(); position++ {
+		mine, ok := n.revisionHash(position)
+		if !ok {
+			break
+		}
+		theirs, ok := other.revisionHash(position)
+		if !ok || !bytes.Equal(mine, theirs) {
+			break
+		}
+		if position >= n.HistoryOffset {
+			base = n.Revisions[position-n.HistoryOffset]
+		}
+	}
+	if base == nil {
+		return nil, ErrNoRevision
+	}
+	return base, nil
+}
+
+// RevisionsAfter returns all revisions which have been appended after
+// the given revision. If rev is nil, all revisions are returned.
+func (n *NIB) RevisionsAfter(rev *Revision) []*Revision {
+	if rev == nil {
+		return append([]*Revision{}, n.Revisions...)
+	}
+	for i, r := range n.Revisions {
+		if r == rev {
+			return append([]*Revision{}, n.Revisions[i+1:]...)
+		}
+	}
+	return []*Revision{}
 }
@@ -2,6 +2,7 @@ package nib
 
 import (
 	"bytes"
+	"fmt"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -160,3 +161,185 @@ func (t *NIBTests) TestIsParentOfZeroLengthParent(c *C) {
 
 	c.Assert(oldNIB.IsParentOf(newNIB), Equals, true)
 }
+
+func (t *NIBTests) TestCommonAncestor(c *C) {
+	base := &Revision{MetadataID: "meta1", ContentIDs: []string{"content1"}}
+	mine := &NIB{}
+	mine.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"content0"}})
+	mine.AppendRevision(base)
+	mine.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"content2"}})
+
+	theirs := &NIB{}
+	theirs.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"content0"}})
+	theirs.AppendRevision(base.Clone())
+	theirs.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"content3"}})
+
+	rev, err := mine.CommonAncestor(theirs)
+	c.Assert(err, IsNil)
+	c.Assert(rev, Equals, base)
+}
+
+func (t *NIBTests) TestCommonAncestorNone(c *C) {
+	mine := &NIB{}
+	mine.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"content1"}})
+	theirs := &NIB{}
+	theirs.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"content2"}})
+
+	rev, err := mine.CommonAncestor(theirs)
+	c.Assert(err, Equals, ErrNoRevision)
+	c.Assert(rev, IsNil)
+}
+
+func (t *NIBTests) TestCommonAncestorRevertedContent(c *C) {
+	// A -> B is shared; mine reverts to A, theirs changes to C
+	mine := &NIB{}
+	mine.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"A"}, UTCTimestamp: 1})
+	mine.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"B"}, UTCTimestamp: 2})
+	theirs := &NIB{}
+	for _, rev := range mine.Revisions {
+		theirs.AppendRevision(rev.Clone())
+	}
+	revert := &Revision{MetadataID: "meta1", ContentIDs: []string{"A"}, UTCTimestamp: 3}
+	mine.AppendRevision(revert)
+	theirs.AppendRevision(&Revision{MetadataID: "meta1", ContentIDs: []string{"C"}, UTCTimestamp: 3})
+
+	rev, err := mine.CommonAncestor(theirs)
+	c.Assert(err, IsNil)
+	c.Assert(rev, Equals, mine.Revisions[1])
+	c.Assert(mine.RevisionsAfter(rev), DeepEquals, []*Revision{revert})
+
+	rev, err = theirs.CommonAncestor(mine)
+	c.Assert(err, IsNil)
+	c.Assert(rev, Equals, theirs.Revisions[1])
+}
+
+func (t *NIBTests) TestCommonAncestorPruned(c *C) {
+	mine := prunableNIB(1, 2, 3, 4)
+	theirs := prunableNIB(1, 2, 3, 5)
+	theirs.Revisions[3].ContentIDs = []string{"diverged"}
+	theirs.Prune(2)
+
+	rev, err := mine.CommonAncestor(theirs)
+	c.Assert(err, IsNil)
+	c.Assert(rev, Equals, mine.Revisions[2])
+	rev, err = theirs.CommonAncestor(mine)
+	c.Assert(err, IsNil)
+	c.Assert(rev, Equals, theirs.Revisions[0])
+
+	// the common revision has been pruned from mine
+	mine.Prune(3)
+	_, err = mine.CommonAncestor(theirs)
+	c.Assert(err, Equals, ErrNoRevision)
+}
+
+func (t *NIBTests) TestRevisionsAfter(c *C) {
+	n := &NIB{}
+	first := &Revision{MetadataID: "meta1", ContentIDs: []string{"content1"}}
+	second := &Revision{MetadataID: "meta1", ContentIDs: []string{"content2"}}
+	n.AppendRevision(first)
+	n.AppendRevision(second)
+
+	c.Assert(n.RevisionsAfter(first), DeepEquals, []*Revision{second})
+	c.Assert(n.RevisionsAfter(second), DeepEquals, []*Revision{})
+	c.Assert(n.RevisionsAfter(nil), DeepEquals, []*Revision{first, second})
+}
+
+// prunableNIB returns a NIB with revisions for the given timestamps.
+func prunableNIB(timestamps ...int64) *NIB {
+	n := &NIB{}
+	for i, timestamp := range timestamps {
+		n.AppendRevision(&Revision{
+			MetadataID:   "meta1",
+			ContentIDs:   []string{fmt.Sprintf("content%d", i)},
+			UTCTimestamp: timestamp,
+		})
+	}
+	return n
+}
+
+func (t *NIBTests) TestPrune(c *C) {
+	n := prunableNIB(1, 2, 3, 4)
+	first := n.Revisions[0]
+	latest := n.Revisions[3]
+	removed := n.Prune(1)
+	c.Assert(removed, Equals, 1)
+	c.Assert(n.HistoryOffset, Equals, int64(1))
+	c.Assert(n.PrunedRevisionHashes, DeepEquals, [][]byte{first.Hash()})
+	c.Assert(n.RevisionsTotal(), Equals, int64(4))
+	c.Assert(n.Revisions, HasLen, 3)
+	c.Assert(n.Revisions[0].UTCTimestamp, Equals, int64(2))
+	c.Assert(n.Revisions[2], Equals, latest)
+}
+
+func (t *NIBTests) TestPruneKeepsLatest(c *C) {
+	n := prunableNIB(1, 2)
+	removed := n.Prune(5)
+	c.Assert(removed, Equals, 1)
+	c.Assert(n.Revisions, HasLen, 1)
+	c.Assert(n.Revisions[0].UTCTimestamp, Equals, int64(2))
+	c.Assert(n.PrunedRevisionHashes, HasLen, 1)
+}
+
+func (t *NIBTests) TestPruneEnDecode(c *C) {
+	n := prunableNIB(1, 2, 3)
+	n.ID = "test"
+	n.Prune(2)
+	buf := &bytes.Buffer{}
+	_, err := n.WriteTo(buf)
+	c.Assert(err, IsNil)
+	decoded := &NIB{}
+	_, err = decoded.ReadFrom(buf)
+	c.Assert(err, IsNil)
+	c.Assert(decoded.HistoryOffset, Equals, int64(2))
+	c.Assert(decoded.PrunedRevisionHashes, DeepEquals, n.PrunedRevisionHashes)
+}
+
+func (t *NIBTests) TestRevisionHash(c *C) {
+	n := prunableNIB(1, 1)
+	c.Assert(n.Revisions[0].Hash(), DeepEquals, n.Revisions[0].Clone().Hash())
+	c.Assert(n.Revisions[0].Hash(), Not(DeepEquals), n.Revisions[1].Hash())
+	other := n.Revisions[0].Clone()
+	other.DeviceID = "other"
+	c.Assert(n.Revisions[0].Hash(), Not(DeepEquals), other.Hash())
+}
+
+func (t *NIBTests) TestIsParentOfPrunedOther(c *C) {
+	mine := prunableNIB(1, 2, 3)
+	theirs := prunableNIB(1, 2, 3, 4, 5)
+	theirs.Prune(4)
+	c.Assert(mine.IsParentOf(theirs), Equals, true)
+	c.Assert(theirs.IsParentOf(mine), Equals, false)
+}
+
+func (t *NIBTests) TestIsParentOfPrunedDifferently(c *C) {
+	mine := prunableNIB(1, 2, 3, 4)
+	mine.Prune(2)
+	theirs := prunableNIB(1, 2, 3, 4, 5)
+	theirs.Prune(4)
+	c.Assert(mine.IsParentOf(theirs), Equals, true)
+	c.Assert(theirs.IsParentOf(mine), Equals, false)
+}
+
+func (t *NIBTests) TestIsParentOfPrunedAfterDivergence(c *C) {
+	mine := prunableNIB(1, 2, 3)
+	theirs := prunableNIB(1, 2, 3, 4)
+	theirs.Revisions[2].ContentIDs = []string{"diverged"}
+	theirs.Prune(1)
+	c.Assert(mine.IsParentOf(theirs), Equals, false)
+}
+
+func (t *NIBTests) TestIsParentOfPrunedAfterLaterDivergence(c *C) {
+	mine := prunableNIB(1, 2, 3)
+	theirs := prunableNIB(1, 2, 4, 5)
+	theirs.Revisions[2].ContentIDs = []string{"diverged"}
+	theirs.Prune(3)
+	c.Assert(mine.IsParentOf(theirs), Equals, false)
+}
+
+func (t *NIBTests) TestIsParentOfPrunedWithoutHashes(c *C) {
+	mine := prunableNIB(1, 2, 3)
+	theirs := prunableNIB(1, 2, 3, 4)
+	theirs.Prune(3)
+	theirs.PrunedRevisionHashes = nil
+	c.Assert(mine.IsParentOf(theirs), Equals, false)
+}
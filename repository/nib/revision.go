@@ -1,6 +1,9 @@
 package nib
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
 	"reflect"
 
 	"github.com/hoffie/larasync/repository/odf"
@@ -57,6 +60,24 @@ func (r *Revision) HasSameContent(other *Revision) bool {
 	return true
 }
 
+// Hash returns a hash which identifies this revision including its
+// timestamp and device. It is recorded in place of pruned revisions.
+func (r *Revision) Hash() []byte {
+	h := sha256.New()
+	writeString := func(s string) {
+		binary.Write(h, binary.BigEndian, int64(len(s)))
+		io.WriteString(h, s)
+	}
+	writeString(r.MetadataID)
+	binary.Write(h, binary.BigEndian, int64(len(r.ContentIDs)))
+	for _, contentID := range r.ContentIDs {
+		writeString(contentID)
+	}
+	binary.Write(h, binary.BigEndian, r.UTCTimestamp)
+	writeString(r.DeviceID)
+	return h.Sum(nil)
+}
+
 // IsDeletion returns if the revision marks the item as being deleted.
 func (r *Revision) IsDeletion() bool {
 	return len(r.ContentIDs) == 0
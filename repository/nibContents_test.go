@@ -0,0 +1,88 @@
+package repository
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&NIBContentsTests{})
+
+// NIBContentsTests covers adding several NIBs at once, as done by the
+// server for commits.
+type NIBContentsTests struct {
+	clientPairTests
+}
+
+func (t *NIBContentsTests) transactionCount(c *C, r *ClientRepository) int {
+	transactions, err := r.transactionManager.All()
+	c.Assert(err, IsNil)
+	return len(transactions)
+}
+
+func (t *NIBContentsTests) TestSingleTransaction(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("a"))
+	t.writeAndAdd(c, t.mine, "b.txt", []byte("b"))
+	nibData := [][]byte{
+		t.copyNIBData(c, t.mine, t.theirs, "a.txt"),
+		t.copyNIBData(c, t.mine, t.theirs, "b.txt"),
+	}
+	before := t.transactionCount(c, t.theirs)
+
+	err := t.theirs.Repository.AddNIBContents(nibData)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.transactionCount(c, t.theirs), Equals, before+1)
+	transaction, err := t.theirs.CurrentTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(transaction.NIBIDs, HasLen, 2)
+}
+
+func (t *NIBContentsTests) TestConflictStoresNothing(c *C) {
+	t.diverge(c, []byte("mine"), []byte("theirs"))
+	t.writeAndAdd(c, t.theirs, "new.txt", []byte("new"))
+	newData := t.copyNIBData(c, t.theirs, t.mine, "new.txt")
+	conflicting := t.copyNIBData(c, t.theirs, t.mine, "foo.txt")
+	newID, err := t.theirs.pathToNIBID("new.txt")
+	c.Assert(err, IsNil)
+	before := t.transactionCount(c, t.mine)
+
+	err = t.mine.Repository.AddNIBContents([][]byte{newData, conflicting})
+	c.Assert(err, Equals, ErrNIBConflict)
+
+	c.Assert(t.mine.HasNIB(newID), Equals, false)
+	c.Assert(t.transactionCount(c, t.mine), Equals, before)
+}
+
+func (t *NIBContentsTests) TestMissingObjectsStoresNothing(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("a"))
+	t.writeAndAdd(c, t.mine, "b.txt", []byte("b"))
+	complete := t.copyNIBData(c, t.mine, t.theirs, "a.txt")
+	nibID, err := t.mine.pathToNIBID("b.txt")
+	c.Assert(err, IsNil)
+	incomplete, err := t.mine.nibStore.GetBytes(nibID)
+	c.Assert(err, IsNil)
+	n, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+
+	err = t.theirs.Repository.AddNIBContents([][]byte{complete, incomplete})
+	c.Assert(IsNIBContentMissing(err), Equals, true)
+	missing := err.(*ErrNIBContentMissing).MissingContentIDs()
+	c.Assert(missing, DeepEquals, n.AllObjectIDs())
+	aID, err := t.mine.pathToNIBID("a.txt")
+	c.Assert(err, IsNil)
+	c.Assert(t.theirs.HasNIB(aID), Equals, false)
+}
+
+func (t *NIBContentsTests) TestDuplicate(c *C) {
+	t.writeAndAdd(c, t.mine, "a.txt", []byte("a"))
+	data := t.copyNIBData(c, t.mine, t.theirs, "a.txt")
+
+	err := t.theirs.Repository.AddNIBContents([][]byte{data, data})
+	c.Assert(err, Equals, ErrDuplicateNIB)
+}
+
+func (t *NIBContentsTests) TestEmpty(c *C) {
+	before := t.transactionCount(c, t.theirs)
+	err := t.theirs.Repository.AddNIBContents([][]byte{})
+	c.Assert(err, IsNil)
+	c.Assert(t.transactionCount(c, t.theirs), Equals, before)
+}
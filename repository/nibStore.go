@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"os"
 
 	"github.com/hoffie/larasync/helpers/crypto"
 	"github.com/hoffie/larasync/repository/content"
@@ -16,54 +17,53 @@ import (
 type NIBStore struct {
 	storage            content.Storage
 	keys               *KeyStore
+	devices            *DeviceStore
 	transactionManager *TransactionManager
 }
 
 // newNibStore generates the NIBStore with the passed backend, repository,
-// and transactionManager.
+// device registry and transactionManager.
 func newNIBStore(storage content.Storage,
 	keys *KeyStore,
+	devices *DeviceStore,
 	transactionManager *TransactionManager,
 ) *NIBStore {
 	return &NIBStore{
 		storage:            storage,
 		keys:               keys,
+		devices:            devices,
 		transactionManager: transactionManager,
 	}
 }
 
 // Get returns the NIB of the given id.
 func (s *NIBStore) Get(id string) (*nib.NIB, error) {
-	pubKey, err := s.keys.SigningPublicKey()
+	data, err := s.GetBytes(id)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.VerifyAndParseBytes(data)
+}
+
+// getStored returns the stored NIB of the given id without verifying its
+// signature again. NIBs are verified before they are stored, but their
+// signer may have been revoked since.
+func (s *NIBStore) getStored(id string) (*nib.NIB, error) {
 	data, err := s.GetBytes(id)
 	if err != nil {
 		return nil, err
 	}
-
-	buffer := bytes.NewReader(data)
-	signatureReader, err := crypto.NewVerifyingReader(
-		pubKey,
-		buffer,
-	)
+	signed, err := crypto.StripSignature(data)
 	if err != nil {
 		return nil, err
 	}
-
-	n := nib.NIB{}
-	_, err = n.ReadFrom(signatureReader)
+	n := &nib.NIB{}
+	_, err = n.ReadFrom(bytes.NewReader(signed))
 	if err != nil {
-		return nil, err
-	}
-
-	if !signatureReader.VerifyAfterRead() {
-		return nil, ErrSignatureVerification
+		return nil, ErrUnMarshalling
 	}
-
-	return &n, nil
+	return n, nil
 }
 
 // GetBytes returns the Byte representation of the
@@ -175,27 +175,85 @@ func (s *NIBStore) Add(nib *nib.NIB) error {
 	return s.writeBytes(nib.ID, buf.Bytes())
 }
 
+// AddAll signs and stores the given NIBs and records them in a single
+// transaction. If this fails, the previously stored NIBs are restored.
+func (s *NIBStore) AddAll(nibs []*nib.NIB) error {
+	ids := make([]string, len(nibs))
+	contents := make([][]byte, len(nibs))
+	for i, n := range nibs {
+		if n.ID == "" {
+			return errors.New("empty nib ID")
+		}
+		buf := &bytes.Buffer{}
+		_, err := n.WriteTo(buf)
+		if err != nil {
+			return err
+		}
+		signed, err := s.sign(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		ids[i] = n.ID
+		contents[i] = signed.Bytes()
+	}
+	return s.AddContents(ids, contents)
+}
+
 // writeBytes signs and adds the bytes for the given NIB ID.
 func (s *NIBStore) writeBytes(id string, data []byte) error {
-	key, err := s.keys.SigningPrivateKey()
-
+	buf, err := s.sign(data)
 	if err != nil {
 		return err
 	}
 
+	return s.AddContent(id, buf)
+}
+
+// sign returns the given data signed with this device's key.
+func (s *NIBStore) sign(data []byte) (*bytes.Buffer, error) {
+	key, err := s.signingPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
 	buf := &bytes.Buffer{}
 
 	sw := crypto.NewSigningWriter(key, buf)
 	_, err = sw.Write(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = sw.Finalize()
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// resign signs the given NIB again with the current key and replaces the
+// stored copy without recording a transaction, as its content does not
+// change.
+func (s *NIBStore) resign(n *nib.NIB) error {
+	data := &bytes.Buffer{}
+	_, err := n.WriteTo(data)
+	if err != nil {
+		return err
+	}
+	buf, err := s.sign(data.Bytes())
 	if err != nil {
 		return err
 	}
+	return s.storage.Set(n.ID, buf)
+}
 
-	return s.AddContent(id, buf)
+// signingPrivateKey returns the key which is used to sign NIBs; this is
+// the device key if this device has one and the repository key otherwise.
+func (s *NIBStore) signingPrivateKey() ([PrivateKeySize]byte, error) {
+	key, err := s.keys.DeviceSigningPrivateKey()
+	if os.IsNotExist(err) {
+		return s.keys.SigningPrivateKey()
+	}
+	return key, err
 }
 
 // Creates a transaction with the given ID as NIB and Transaction id.
@@ -217,6 +275,54 @@ func (s *NIBStore) AddContent(id string, reader io.Reader) error {
 	return s.transactionManager.Add(transaction)
 }
 
+// AddContents adds the signed byte data of several NIBs with the passed
+// IDs and records them in a single transaction. If this fails, the
+// previously stored data is restored.
+// All writes of several NIBs at once, including AddAll, go through here.
+func (s *NIBStore) AddContents(ids []string, contents [][]byte) error {
+	previous := map[string][]byte{}
+	written := []string{}
+	var err error
+	for i, id := range ids {
+		if s.storage.Exists(id) {
+			previous[id], err = s.GetBytes(id)
+			if err != nil {
+				break
+			}
+		}
+		err = s.storage.Set(id, bytes.NewReader(contents[i]))
+		if err != nil {
+			break
+		}
+		written = append(written, id)
+	}
+	if err == nil {
+		err = s.transactionManager.Add(&Transaction{NIBIDs: ids})
+		if err == nil {
+			return nil
+		}
+	}
+	s.restore(written, previous)
+	return err
+}
+
+// restore resets the NIBs with the given ids to the given previous
+// contents; NIBs without previous content are removed.
+func (s *NIBStore) restore(ids []string, previous map[string][]byte) {
+	for _, id := range ids {
+		data, existed := previous[id]
+		var err error
+		if existed {
+			err = s.storage.Set(id, bytes.NewReader(data))
+		} else {
+			err = s.storage.Delete(id)
+		}
+		if err != nil {
+			Log.Error("unable to restore NIB", "id", id, "error", err)
+		}
+	}
+}
+
 // Exists returns if there is a NIB with
 // the given ID in the store.
 func (s *NIBStore) Exists(id string) bool {
@@ -226,29 +332,15 @@ func (s *NIBStore) Exists(id string) bool {
 // VerifyAndParseBytes verifies the correctness of the given
 // data in the reader and returns the parsed nib.
 func (s *NIBStore) VerifyAndParseBytes(data []byte) (*nib.NIB, error) {
-	pubKey, err := s.keys.SigningPublicKey()
-	if err != nil {
-		return nil, err
-	}
-
-	signatureReader, err := crypto.NewVerifyingReader(
-		pubKey,
-		bytes.NewReader(data),
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	// reading into a temporary buffer first requires memory,
-	// but this way we avoid parsing NIBs before actually verifying
-	// their signature; do not change this without further
-	// consideration.
-	buf, err := ioutil.ReadAll(signatureReader)
+	pubKeys, err := s.devices.AcceptedPublicKeys()
 	if err != nil {
 		return nil, err
 	}
 
-	if !signatureReader.VerifyAfterRead() {
+	// we avoid parsing NIBs before actually verifying their
+	// signature; do not change this without further consideration.
+	buf, ok := crypto.VerifySignedData(pubKeys, data)
+	if !ok {
 		return nil, ErrSignatureVerification
 	}
 
@@ -55,6 +55,7 @@ func (t *NIBStoreTest) SetUpTest(c *C) {
 	t.nibStore = newNIBStore(
 		t.storage,
 		t.repository.keys,
+		t.repository.devices,
 		t.transactionManager,
 	)
 }
@@ -88,6 +89,45 @@ func (t *NIBStoreTest) TestTransactionAddition(c *C) {
 	c.Assert(transaction.NIBIDs, DeepEquals, []string{testNib.ID})
 }
 
+func (t *NIBStoreTest) TestAddAll(c *C) {
+	first := t.getTestNIB()
+	second := t.getTestNIB()
+	second.ID = "second"
+	err := t.nibStore.AddAll([]*nib.NIB{first, second})
+	c.Assert(err, IsNil)
+	transaction, err := t.transactionManager.CurrentTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(transaction.NIBIDs, DeepEquals, []string{"test", "second"})
+	n, err := t.nibStore.Get("second")
+	c.Assert(err, IsNil)
+	c.Assert(n.Revisions, HasLen, 2)
+}
+
+func (t *NIBStoreTest) TestAddAllRestoresOnFailure(c *C) {
+	testNib := t.addTestNIB(c)
+	before, err := t.nibStore.GetBytes(testNib.ID)
+	c.Assert(err, IsNil)
+	transactionID, err := t.transactionManager.CurrentTransactionID()
+	c.Assert(err, IsNil)
+
+	changed := t.getTestNIB()
+	changed.AppendRevision(&nib.Revision{})
+	added := t.getTestNIB()
+	added.ID = "added"
+	invalid := t.getTestNIB()
+	invalid.ID = ".."
+	err = t.nibStore.AddAll([]*nib.NIB{changed, added, invalid})
+	c.Assert(err, NotNil)
+
+	after, err := t.nibStore.GetBytes(testNib.ID)
+	c.Assert(err, IsNil)
+	c.Assert(after, DeepEquals, before)
+	c.Assert(t.nibStore.Exists("added"), Equals, false)
+	currentID, err := t.transactionManager.CurrentTransactionID()
+	c.Assert(err, IsNil)
+	c.Assert(currentID, Equals, transactionID)
+}
+
 func (t *NIBStoreTest) TestNibGet(c *C) {
 	testNib := t.addTestNIB(c)
 	n, err := t.nibStore.Get(testNib.ID)
@@ -14,7 +14,10 @@ It has these top-level messages:
 	NIB
 	Revision
 	Metadata
+	ChunkerConfig
 	Authorization
+	Device
+	DeviceRegistry
 */
 package odf
 
@@ -28,17 +31,20 @@ var _ = math.Inf
 type NodeType int32
 
 const (
-	NodeType_Dir  NodeType = 0
-	NodeType_File NodeType = 1
+	NodeType_Dir     NodeType = 0
+	NodeType_File    NodeType = 1
+	NodeType_Symlink NodeType = 2
 )
 
 var NodeType_name = map[int32]string{
 	0: "Dir",
 	1: "File",
+	2: "Symlink",
 }
 var NodeType_value = map[string]int32{
-	"Dir":  0,
-	"File": 1,
+	"Dir":     0,
+	"File":    1,
+	"Symlink": 2,
 }
 
 func (x NodeType) Enum() *NodeType {
@@ -123,10 +129,11 @@ func (m *Transaction) GetPreviousID() int64 {
 }
 
 type NIB struct {
-	ID               *string     `protobuf:"bytes,1,req" json:"ID,omitempty"`
-	Revisions        []*Revision `protobuf:"bytes,2,rep" json:"Revisions,omitempty"`
-	HistoryOffset    *int64      `protobuf:"varint,3,opt,name=historyOffset" json:"historyOffset,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	ID                   *string     `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	Revisions            []*Revision `protobuf:"bytes,2,rep" json:"Revisions,omitempty"`
+	HistoryOffset        *int64      `protobuf:"varint,3,opt,name=historyOffset" json:"historyOffset,omitempty"`
+	PrunedRevisionHashes [][]byte    `protobuf:"bytes,4,rep,name=prunedRevisionHashes" json:"prunedRevisionHashes,omitempty"`
+	XXX_unrecognized     []byte      `json:"-"`
 }
 
 func (m *NIB) Reset()         { *m = NIB{} }
@@ -154,6 +161,13 @@ func (m *NIB) GetHistoryOffset() int64 {
 	return 0
 }
 
+func (m *NIB) GetPrunedRevisionHashes() [][]byte {
+	if m != nil {
+		return m.PrunedRevisionHashes
+	}
+	return nil
+}
+
 type Revision struct {
 	MetadataID       *string  `protobuf:"bytes,1,req" json:"MetadataID,omitempty"`
 	ContentIDs       []string `protobuf:"bytes,2,rep" json:"ContentIDs,omitempty"`
@@ -197,6 +211,10 @@ func (m *Revision) GetDeviceID() string {
 type Metadata struct {
 	Type             *NodeType `protobuf:"varint,1,req,enum=odf.NodeType" json:"Type,omitempty"`
 	RepoRelativePath *string   `protobuf:"bytes,2,req" json:"RepoRelativePath,omitempty"`
+	Mode             *uint32   `protobuf:"varint,3,opt" json:"Mode,omitempty"`
+	ModTime          *int64    `protobuf:"varint,4,opt" json:"ModTime,omitempty"`
+	SymlinkTarget    *string   `protobuf:"bytes,5,opt" json:"SymlinkTarget,omitempty"`
+	MovedFrom        *string   `protobuf:"bytes,6,opt" json:"MovedFrom,omitempty"`
 	XXX_unrecognized []byte    `json:"-"`
 }
 
@@ -218,11 +236,80 @@ func (m *Metadata) GetRepoRelativePath() string {
 	return ""
 }
 
+func (m *Metadata) GetMode() uint32 {
+	if m != nil && m.Mode != nil {
+		return *m.Mode
+	}
+	return 0
+}
+
+func (m *Metadata) GetModTime() int64 {
+	if m != nil && m.ModTime != nil {
+		return *m.ModTime
+	}
+	return 0
+}
+
+func (m *Metadata) GetSymlinkTarget() string {
+	if m != nil && m.SymlinkTarget != nil {
+		return *m.SymlinkTarget
+	}
+	return ""
+}
+
+func (m *Metadata) GetMovedFrom() string {
+	if m != nil && m.MovedFrom != nil {
+		return *m.MovedFrom
+	}
+	return ""
+}
+
+type ChunkerConfig struct {
+	Algorithm        *string `protobuf:"bytes,1,req" json:"Algorithm,omitempty"`
+	MinSize          *uint64 `protobuf:"varint,2,opt" json:"MinSize,omitempty"`
+	AvgSize          *uint64 `protobuf:"varint,3,req" json:"AvgSize,omitempty"`
+	MaxSize          *uint64 `protobuf:"varint,4,opt" json:"MaxSize,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ChunkerConfig) Reset()         { *m = ChunkerConfig{} }
+func (m *ChunkerConfig) String() string { return proto.CompactTextString(m) }
+func (*ChunkerConfig) ProtoMessage()    {}
+
+func (m *ChunkerConfig) GetAlgorithm() string {
+	if m != nil && m.Algorithm != nil {
+		return *m.Algorithm
+	}
+	return ""
+}
+
+func (m *ChunkerConfig) GetMinSize() uint64 {
+	if m != nil && m.MinSize != nil {
+		return *m.MinSize
+	}
+	return 0
+}
+
+func (m *ChunkerConfig) GetAvgSize() uint64 {
+	if m != nil && m.AvgSize != nil {
+		return *m.AvgSize
+	}
+	return 0
+}
+
+func (m *ChunkerConfig) GetMaxSize() uint64 {
+	if m != nil && m.MaxSize != nil {
+		return *m.MaxSize
+	}
+	return 0
+}
+
 type Authorization struct {
-	SigningKey       []byte `protobuf:"bytes,1,req" json:"SigningKey,omitempty"`
-	EncryptionKey    []byte `protobuf:"bytes,2,req" json:"EncryptionKey,omitempty"`
-	HashingKey       []byte `protobuf:"bytes,3,req" json:"HashingKey,omitempty"`
-	XXX_unrecognized []byte `json:"-"`
+	SigningKey       []byte         `protobuf:"bytes,1,req" json:"SigningKey,omitempty"`
+	EncryptionKey    []byte         `protobuf:"bytes,2,req" json:"EncryptionKey,omitempty"`
+	HashingKey       []byte         `protobuf:"bytes,3,req" json:"HashingKey,omitempty"`
+	Chunker          *ChunkerConfig `protobuf:"bytes,4,opt" json:"Chunker,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
 }
 
 func (m *Authorization) Reset()         { *m = Authorization{} }
@@ -250,6 +337,69 @@ func (m *Authorization) GetHashingKey() []byte {
 	return nil
 }
 
+func (m *Authorization) GetChunker() *ChunkerConfig {
+	if m != nil {
+		return m.Chunker
+	}
+	return nil
+}
+
+type Device struct {
+	PublicKey           []byte  `protobuf:"bytes,1,req" json:"PublicKey,omitempty"`
+	Name                *string `protobuf:"bytes,2,opt" json:"Name,omitempty"`
+	UTCTimestamp        *int64  `protobuf:"varint,3,opt" json:"UTCTimestamp,omitempty"`
+	EncryptionPublicKey []byte  `protobuf:"bytes,4,opt" json:"EncryptionPublicKey,omitempty"`
+	XXX_unrecognized    []byte  `json:"-"`
+}
+
+func (m *Device) Reset()         { *m = Device{} }
+func (m *Device) String() string { return proto.CompactTextString(m) }
+func (*Device) ProtoMessage()    {}
+
+func (m *Device) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *Device) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *Device) GetUTCTimestamp() int64 {
+	if m != nil && m.UTCTimestamp != nil {
+		return *m.UTCTimestamp
+	}
+	return 0
+}
+
+func (m *Device) GetEncryptionPublicKey() []byte {
+	if m != nil {
+		return m.EncryptionPublicKey
+	}
+	return nil
+}
+
+type DeviceRegistry struct {
+	Devices          []*Device `protobuf:"bytes,1,rep" json:"Devices,omitempty"`
+	XXX_unrecognized []byte    `json:"-"`
+}
+
+func (m *DeviceRegistry) Reset()         { *m = DeviceRegistry{} }
+func (m *DeviceRegistry) String() string { return proto.CompactTextString(m) }
+func (*DeviceRegistry) ProtoMessage()    {}
+
+func (m *DeviceRegistry) GetDevices() []*Device {
+	if m != nil {
+		return m.Devices
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("odf.NodeType", NodeType_name, NodeType_value)
 }
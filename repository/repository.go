@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/hoffie/larasync/repository/content"
 	"github.com/hoffie/larasync/repository/nib"
@@ -20,12 +21,14 @@ const (
 	authorizationsDirName = "authorizations"
 	keysDirName           = "keys"
 	stateConfigFileName   = "state.json"
+	repoConfigFileName    = "config.json"
 
 	// default permissions
 	defaultFilePerms = 0600
 	defaultDirPerms  = 0700
 
-	// chunk splitting size
+	// chunk splitting size of repositories which do not have a
+	// chunking configuration
 	chunkSize = 1 * 1024 * 1024
 )
 
@@ -34,8 +37,9 @@ const (
 type Repository struct {
 	Path                 string
 	keys                 *KeyStore
-	objectStorage        content.Storage
+	objectStorage        content.ListableStorage
 	nibStore             *NIBStore
+	devices              *DeviceStore
 	transactionManager   *TransactionManager
 	authorizationManager *AuthorizationManager
 	managementDir        *managementDirectory
@@ -58,9 +62,14 @@ func New(path string) *Repository {
 	)
 
 	r.keys = NewKeyStore(content.NewFileStorage(r.subPathFor(keysDirName)))
+	r.devices = newDeviceStore(
+		content.NewFileStorage(r.subPathFor(keysDirName)),
+		r.keys,
+	)
 	r.nibStore = newNIBStore(
 		content.NewFileStorage(r.subPathFor(nibsDirName)),
 		r.keys,
+		r.devices,
 		r.transactionManager,
 	)
 
@@ -117,34 +126,95 @@ func (r *Repository) VerifyAndParseNIBBytes(data []byte) (*nib.NIB, error) {
 
 // AddNIBContent adds NIBData to the repository after verifying it.
 func (r *Repository) AddNIBContent(nibReader io.Reader) error {
+	data, err := ioutil.ReadAll(nibReader)
+	if err != nil {
+		return err
+	}
+	return r.addNIBContent(data, func(n *nib.NIB) ([]string, error) {
+		return n.AllObjectIDs(), nil
+	})
+}
+
+// addNIBContent adds the given NIB data to the repository after verifying
+// it. The objects returned by requiredObjectIDs have to be stored already.
+func (r *Repository) addNIBContent(data []byte, requiredObjectIDs func(*nib.NIB) ([]string, error)) error {
 	nibStore := r.nibStore
 
-	data, err := ioutil.ReadAll(nibReader)
+	nib, missingObjectIDs, err := r.verifyNIBImport(data, requiredObjectIDs)
 	if err != nil {
 		return err
 	}
+	if len(missingObjectIDs) > 0 {
+		return NewErrNIBContentMissing(missingObjectIDs)
+	}
+	return nibStore.AddContent(nib.ID, bytes.NewReader(data))
+}
+
+// AddNIBContents adds the given NIBs to the repository after verifying
+// all of them. Either all NIBs are stored in a single transaction or none
+// of them is.
+func (r *Repository) AddNIBContents(nibData [][]byte) error {
+	allObjectIDs := func(n *nib.NIB) ([]string, error) {
+		return n.AllObjectIDs(), nil
+	}
+	ids := []string{}
+	seen := map[string]bool{}
+	seenObjects := map[string]bool{}
+	missingObjectIDs := []string{}
+	for _, data := range nibData {
+		n, missing, err := r.verifyNIBImport(data, allObjectIDs)
+		if err != nil {
+			return err
+		}
+		if seen[n.ID] {
+			return ErrDuplicateNIB
+		}
+		seen[n.ID] = true
+		ids = append(ids, n.ID)
+		for _, objectID := range missing {
+			if !seenObjects[objectID] {
+				seenObjects[objectID] = true
+				missingObjectIDs = append(missingObjectIDs, objectID)
+			}
+		}
+	}
+	if len(missingObjectIDs) > 0 {
+		return NewErrNIBContentMissing(missingObjectIDs)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.nibStore.AddContents(ids, nibData)
+}
 
+// verifyNIBImport verifies the given NIB data and makes sure that it can
+// be imported without conflicts. It returns the parsed NIB and the
+// objects returned by requiredObjectIDs which are not stored yet.
+func (r *Repository) verifyNIBImport(data []byte, requiredObjectIDs func(*nib.NIB) ([]string, error)) (*nib.NIB, []string, error) {
 	nib, err := r.VerifyAndParseNIBBytes(data)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
+	objectIDs, err := requiredObjectIDs(nib)
+	if err != nil {
+		return nil, nil, err
+	}
 	missingObjectIDs := []string{}
-	for _, objectID := range nib.AllObjectIDs() {
+	for _, objectID := range objectIDs {
 		if !r.HasObject(objectID) {
 			missingObjectIDs = append(missingObjectIDs, objectID)
 		}
 	}
-
 	if len(missingObjectIDs) > 0 {
-		return NewErrNIBContentMissing(missingObjectIDs)
+		return nib, missingObjectIDs, nil
 	}
 
 	err = r.ensureConflictFreeNIBImport(nib)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	return nibStore.AddContent(nib.ID, bytes.NewReader(data))
+	return nib, nil, nil
 }
 
 // ensureConflictFreeNIBImport returns an error if we cannot import
@@ -153,7 +223,7 @@ func (r *Repository) ensureConflictFreeNIBImport(otherNIB *nib.NIB) error {
 	if !r.HasNIB(otherNIB.ID) {
 		return nil
 	}
-	myNIB, err := r.GetNIB(otherNIB.ID)
+	myNIB, err := r.nibStore.getStored(otherNIB.ID)
 	if err != nil {
 		return err
 	}
@@ -207,6 +277,13 @@ func (r *Repository) CurrentTransaction() (*Transaction, error) {
 	return r.transactionManager.CurrentTransaction()
 }
 
+// WaitForTransactionAfter blocks until a transaction newer than the
+// given one has been recorded or until the timeout passes. Returns true
+// if a newer transaction exists.
+func (r *Repository) WaitForTransactionAfter(transactionID int64, timeout time.Duration) (bool, error) {
+	return r.transactionManager.WaitForTransactionAfter(transactionID, timeout)
+}
+
 // GetAuthorizationReader returns the authorization configuration for the
 // passed PublicKey.
 func (r *Repository) GetAuthorizationReader(publicKey [PublicKeySize]byte) (io.ReadCloser, error) {
@@ -252,6 +329,45 @@ func (r *Repository) GetSigningPublicKey() ([PublicKeySize]byte, error) {
 	return r.keys.SigningPublicKey()
 }
 
+// AcceptedSigningPublicKeys returns the public keys whose signatures are
+// accepted for this repository, i.e. the repository signing key and the
+// keys of all registered devices.
+func (r *Repository) AcceptedSigningPublicKeys() ([][PublicKeySize]byte, error) {
+	return r.devices.AcceptedPublicKeys()
+}
+
+// DeviceRegistry returns the verified device registry of this repository.
+func (r *Repository) DeviceRegistry() (*DeviceRegistry, error) {
+	return r.devices.Get()
+}
+
+// GetDeviceRegistryBytes returns the signed representation of the device
+// registry.
+func (r *Repository) GetDeviceRegistryBytes() ([]byte, error) {
+	return r.devices.GetBytes()
+}
+
+// SetDeviceRegistryBytes verifies and stores the given signed device
+// registry.
+func (r *Repository) SetDeviceRegistryBytes(data []byte) error {
+	return r.devices.SetBytes(data)
+}
+
+// ReplaceSigningPublicKey replaces the signing public key and the device
+// registry of a server repository after the repository keys have been
+// rotated. The registry has to be signed by the new key.
+func (r *Repository) ReplaceSigningPublicKey(pubKey [PublicKeySize]byte, registryData []byte) error {
+	_, err := r.devices.verifyAndParseBytesWith(pubKey, registryData)
+	if err != nil {
+		return err
+	}
+	err = r.keys.SetSigningPublicKey(pubKey[:])
+	if err != nil {
+		return err
+	}
+	return r.devices.SetBytes(registryData)
+}
+
 // SetKeysFromAuth takes the keys passed through the authorization and puts
 // them into the keystore.
 func (r *Repository) SetKeysFromAuth(auth *Authorization) error {
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/hoffie/larasync/repository/chunker"
+)
+
+// RepositoryConfig stores settings which have to be identical on all
+// clients of a repository, such as the way files are split into chunks.
+type RepositoryConfig struct {
+	Path      string           `json:"-"`
+	Chunking  *chunker.Config  `json:"chunking"`
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+}
+
+// NewRepositoryConfig creates a new RepositoryConfig instance for the
+// given path.
+func NewRepositoryConfig(path string) *RepositoryConfig {
+	return &RepositoryConfig{
+		Path: path,
+	}
+}
+
+// Load attempts to load the repository config from disk.
+func (rc *RepositoryConfig) Load() error {
+	data, err := ioutil.ReadFile(rc.Path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, rc)
+}
+
+// Save serializes the current RepositoryConfig to disk.
+func (rc *RepositoryConfig) Save() error {
+	data, err := json.MarshalIndent(rc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(rc.Path, data, defaultFilePerms)
+}
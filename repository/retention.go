@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// day is the duration of the periods used by retention policies.
+const day = 24 * time.Hour
+
+// RetentionPolicy describes which revisions are kept when the history of
+// a repository is pruned. The latest revision of every item is always
+// kept; any older revision is kept if at least one of the rules selects
+// it. As histories can only be pruned from their beginning, all revisions
+// after the oldest selected one are kept as well. Rules with a zero value
+// select nothing.
+type RetentionPolicy struct {
+	// KeepLast keeps the given number of most recent revisions.
+	KeepLast int `json:"keep_last,omitempty"`
+	// KeepDays keeps all revisions of the given number of past days.
+	KeepDays int `json:"keep_days,omitempty"`
+	// KeepDaily keeps the latest revision of each of the given number
+	// of past days.
+	KeepDaily int `json:"keep_daily,omitempty"`
+	// KeepWeekly keeps the latest revision of each of the given number
+	// of past weeks.
+	KeepWeekly int `json:"keep_weekly,omitempty"`
+}
+
+// Validate checks whether the policy can be applied.
+func (p *RetentionPolicy) Validate() error {
+	if p.KeepLast < 0 || p.KeepDays < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 {
+		return ErrInvalidRetentionPolicy
+	}
+	return nil
+}
+
+// periodStart returns the unix timestamp of the beginning of the given
+// number of periods before now; ok is false if no periods are requested.
+func periodStart(now time.Time, periods int, period time.Duration) (start int64, ok bool) {
+	if periods <= 0 {
+		return 0, false
+	}
+	return now.Add(-time.Duration(periods) * period).Unix(), true
+}
+
+// prunable returns the number of leading revisions of the passed ones
+// which are older than any revision selected by this policy at the given
+// time.
+func (p *RetentionPolicy) prunable(revisions []*nib.Revision, now time.Time) int {
+	keep := make([]bool, len(revisions))
+	daysStart, keepDays := periodStart(now, p.KeepDays, day)
+	dailyStart, keepDaily := periodStart(now, p.KeepDaily, day)
+	weeklyStart, keepWeekly := periodStart(now, p.KeepWeekly, 7*day)
+	seenDays := make(map[string]bool)
+	seenWeeks := make(map[string]bool)
+
+	// iterate newest first, so that the first revision seen in a period
+	// is the latest one of that period.
+	for i := len(revisions) - 1; i >= 0; i-- {
+		rev := revisions[i]
+		if len(revisions)-i <= p.KeepLast {
+			keep[i] = true
+		}
+		if keepDays && rev.UTCTimestamp >= daysStart {
+			keep[i] = true
+		}
+		at := time.Unix(rev.UTCTimestamp, 0).UTC()
+		if keepDaily && rev.UTCTimestamp >= dailyStart {
+			key := at.Format("2006-01-02")
+			if !seenDays[key] {
+				seenDays[key] = true
+				keep[i] = true
+			}
+		}
+		if keepWeekly && rev.UTCTimestamp >= weeklyStart {
+			year, week := at.ISOWeek()
+			key := fmt.Sprintf("%d-%d", year, week)
+			if !seenWeeks[key] {
+				seenWeeks[key] = true
+				keep[i] = true
+			}
+		}
+	}
+	for i, selected := range keep {
+		if selected {
+			return i
+		}
+	}
+	// nothing is selected, so only the latest revision is kept
+	if len(revisions) == 0 {
+		return 0
+	}
+	return len(revisions) - 1
+}
+
+// PruneResult describes the outcome of pruning the history.
+type PruneResult struct {
+	// Items is the number of items whose history has been pruned.
+	Items int
+	// Revisions is the number of removed revisions.
+	Revisions int
+}
+
+// RetentionPolicy returns the configured retention policy or nil if
+// none has been configured.
+func (r *ClientRepository) RetentionPolicy() (*RetentionPolicy, error) {
+	rc, err := r.RepositoryConfig()
+	if err != nil {
+		return nil, err
+	}
+	return rc.Retention, nil
+}
+
+// SetRetentionPolicy stores the given retention policy in the repository
+// config.
+func (r *ClientRepository) SetRetentionPolicy(policy *RetentionPolicy) error {
+	err := policy.Validate()
+	if err != nil {
+		return err
+	}
+	rc, err := r.RepositoryConfig()
+	if err != nil {
+		return err
+	}
+	rc.Retention = policy
+	return rc.Save()
+}
+
+// Prune removes all revisions which are older than the revisions selected
+// by the given retention policy at the given time. Pruned NIBs are signed again and
+// recorded in a new transaction, so they are synchronized like any other
+// change.
+func (r *ClientRepository) Prune(policy *RetentionPolicy, now time.Time) (*PruneResult, error) {
+	err := policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+	nibs, err := r.GetAllNibs()
+	if err != nil {
+		return nil, err
+	}
+	// collect all NIBs first as storing them creates new transactions.
+	all := make(map[string]*nib.NIB)
+	for n := range nibs {
+		all[n.ID] = n
+	}
+
+	result := &PruneResult{}
+	for _, n := range all {
+		removed := n.Prune(policy.prunable(n.Revisions, now))
+		if removed == 0 {
+			continue
+		}
+		err = r.nibStore.Add(n)
+		if err != nil {
+			return nil, err
+		}
+		result.Items++
+		result.Revisions += removed
+	}
+	return result, nil
+}
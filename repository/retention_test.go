@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+var _ = Suite(&RetentionTests{})
+
+type RetentionTests struct {
+	clientPairTests
+	now time.Time
+}
+
+func (t *RetentionTests) SetUpTest(c *C) {
+	t.clientPairTests.SetUpTest(c)
+	t.now = time.Date(2015, 6, 10, 12, 0, 0, 0, time.UTC)
+}
+
+// ago returns the unix timestamp of the given duration before t.now.
+func (t *RetentionTests) ago(d time.Duration) int64 {
+	return t.now.Add(-d).Unix()
+}
+
+// prunable applies the policy to revisions with the given timestamps and
+// returns the number of leading revisions which may be pruned.
+func (t *RetentionTests) prunable(policy *RetentionPolicy, timestamps ...int64) int {
+	revisions := []*nib.Revision{}
+	for _, timestamp := range timestamps {
+		revisions = append(revisions, &nib.Revision{UTCTimestamp: timestamp})
+	}
+	return policy.prunable(revisions, t.now)
+}
+
+// addRevisions adds a revision of foo.txt for each of the given
+// timestamps to the given repository.
+func (t *RetentionTests) addRevisions(c *C, r *ClientRepository, timestamps ...int64) {
+	for i := range timestamps {
+		t.writeAndAdd(c, r, "foo.txt", []byte(fmt.Sprintf("revision %d", i)))
+	}
+	t.setTimestamps(c, "foo.txt", timestamps...)
+}
+
+func (t *RetentionTests) TestKeepLast(c *C) {
+	policy := &RetentionPolicy{KeepLast: 2}
+	c.Assert(t.prunable(policy, 1, 2, 3, 4), Equals, 2)
+}
+
+func (t *RetentionTests) TestKeepDays(c *C) {
+	policy := &RetentionPolicy{KeepDays: 2}
+	c.Assert(t.prunable(policy, t.ago(3*day), t.ago(36*time.Hour), t.ago(time.Hour)),
+		Equals, 1)
+}
+
+func (t *RetentionTests) TestKeepDaily(c *C) {
+	policy := &RetentionPolicy{KeepDaily: 3}
+	c.Assert(t.prunable(policy,
+		t.ago(5*day),
+		t.ago(day+2*time.Hour), t.ago(day+time.Hour),
+		t.ago(3*time.Hour), t.ago(2*time.Hour)),
+		Equals, 2)
+}
+
+func (t *RetentionTests) TestKeepWeekly(c *C) {
+	policy := &RetentionPolicy{KeepWeekly: 2}
+	c.Assert(t.prunable(policy,
+		t.ago(20*day),
+		t.ago(9*day), t.ago(8*day),
+		t.ago(day), t.ago(time.Hour)),
+		Equals, 2)
+}
+
+func (t *RetentionTests) TestEmptyPolicy(c *C) {
+	c.Assert(t.prunable(&RetentionPolicy{}, 1, 2), Equals, 1)
+}
+
+func (t *RetentionTests) TestValidate(c *C) {
+	c.Assert((&RetentionPolicy{KeepLast: 1}).Validate(), IsNil)
+	c.Assert((&RetentionPolicy{KeepDays: -1}).Validate(),
+		Equals, ErrInvalidRetentionPolicy)
+}
+
+func (t *RetentionTests) TestSetRetentionPolicy(c *C) {
+	policy := &RetentionPolicy{KeepLast: 3, KeepWeekly: 4}
+	err := t.mine.SetRetentionPolicy(policy)
+	c.Assert(err, IsNil)
+
+	r := NewClient(t.mine.Path)
+	stored, err := r.RetentionPolicy()
+	c.Assert(err, IsNil)
+	c.Assert(stored, DeepEquals, policy)
+}
+
+func (t *RetentionTests) TestPrune(c *C) {
+	t.addRevisions(c, t.mine, t.ago(3*day), t.ago(2*day), t.ago(day))
+
+	result, err := t.mine.Prune(&RetentionPolicy{KeepLast: 2}, t.now)
+	c.Assert(err, IsNil)
+	c.Assert(result.Items, Equals, 1)
+	c.Assert(result.Revisions, Equals, 1)
+
+	history, err := t.mine.History(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Assert(history[0].Number, Equals, int64(2))
+
+	result, err = t.mine.Prune(&RetentionPolicy{KeepLast: 2}, t.now)
+	c.Assert(err, IsNil)
+	c.Assert(result.Revisions, Equals, 0)
+}
+
+func (t *RetentionTests) TestPruneFastForward(c *C) {
+	t.addRevisions(c, t.mine, t.ago(3*day), t.ago(2*day), t.ago(day))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+
+	_, err = t.mine.Prune(&RetentionPolicy{}, t.now)
+	c.Assert(err, IsNil)
+	err = t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+}
+
+func (t *RetentionTests) TestPruneFastForwardPrunedDifferently(c *C) {
+	t.addRevisions(c, t.mine, t.ago(3*day), t.ago(2*day))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+
+	// the latest revision of theirs is pruned by mine
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("newest"))
+	_, err = t.mine.Prune(&RetentionPolicy{}, t.now)
+	c.Assert(err, IsNil)
+	err = t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+}
+
+func (t *RetentionTests) TestPruneAfterDivergenceConflicts(c *C) {
+	t.addRevisions(c, t.mine, t.ago(3*day), t.ago(2*day))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	t.writeAndAdd(c, t.theirs, "foo.txt", []byte("theirs"))
+
+	// the diverged revision of theirs is older than the pruned history
+	// of mine, but it is not part of it.
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("newest"))
+	_, err = t.mine.Prune(&RetentionPolicy{}, t.now)
+	c.Assert(err, IsNil)
+	data := t.copyNIBData(c, t.mine, t.theirs, "foo.txt")
+	err = t.theirs.Repository.AddNIBContent(bytes.NewReader(data))
+	c.Assert(err, Equals, ErrNIBConflict)
+
+	err = t.theirs.AddNIBContent(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	copies, err := filepath.Glob(filepath.Join(t.theirs.Path, "foo (conflict copy from *).txt"))
+	c.Assert(err, IsNil)
+	c.Assert(copies, HasLen, 1)
+}
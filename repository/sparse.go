@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// SparseConfig selects the items of a repository which are checked out.
+// Patterns are slash-separated repository relative paths which may
+// contain the wildcards supported by path.Match; a pattern which matches
+// a directory selects everything below it as well.
+type SparseConfig struct {
+	// Include lists the selected items; everything is selected if it
+	// is empty.
+	Include []string `json:"include,omitempty"`
+	// Exclude lists items which are not selected even if they are
+	// included.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Validate checks whether all patterns are well-formed.
+func (s *SparseConfig) Validate() error {
+	for _, pattern := range append(append([]string{}, s.Include...), s.Exclude...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Selects returns whether the item with the given repository relative
+// path is part of the selection. A nil config selects everything.
+func (s *SparseConfig) Selects(relPath string) bool {
+	if s == nil {
+		return true
+	}
+	slashPath := filepath.ToSlash(relPath)
+	if len(s.Include) > 0 && !matchesAny(s.Include, slashPath) {
+		return false
+	}
+	return !matchesAny(s.Exclude, slashPath)
+}
+
+// matchesAny returns whether the given slash-separated path or any of its
+// parent directories matches one of the patterns.
+func matchesAny(patterns []string, slashPath string) bool {
+	for p := slashPath; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			matched, _ := path.Match(strings.Trim(pattern, "/"), p)
+			if matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SparseConfig returns the selection of checked out items or nil if all
+// items are checked out.
+func (r *ClientRepository) SparseConfig() (*SparseConfig, error) {
+	sc, err := r.StateConfig()
+	if err != nil {
+		return nil, err
+	}
+	return sc.Sparse, nil
+}
+
+// SetSparseConfig stores the given selection of checked out items; nil
+// selects all items.
+func (r *ClientRepository) SetSparseConfig(config *SparseConfig) error {
+	if config != nil {
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	sc, err := r.StateConfig()
+	if err != nil {
+		return err
+	}
+	sc.Sparse = config
+	return sc.Save()
+}
+
+// isSelected returns whether the item with the given repository relative
+// path is checked out.
+func (r *ClientRepository) isSelected(relPath string) (bool, error) {
+	config, err := r.SparseConfig()
+	if err != nil {
+		return false, err
+	}
+	return config.Selects(relPath), nil
+}
+
+// nibSelected returns whether the item of the given NIB is checked out.
+// Its path is determined by the metadata of the latest revision.
+func (r *ClientRepository) nibSelected(n *nib.NIB) (bool, error) {
+	config, err := r.SparseConfig()
+	if err != nil || config == nil {
+		return true, err
+	}
+	rev, err := n.LatestRevision()
+	if err != nil {
+		return true, nil
+	}
+	metadata, err := r.metadataByID(rev.MetadataID)
+	if err != nil {
+		return false, err
+	}
+	return config.Selects(metadata.RepoRelativePath), nil
+}
+
+// metadataObjectIDs returns the metadata IDs of all revisions of the
+// given NIB.
+func metadataObjectIDs(n *nib.NIB) []string {
+	ids := []string{}
+	for _, rev := range n.Revisions {
+		ids = append(ids, rev.MetadataID)
+	}
+	return ids
+}
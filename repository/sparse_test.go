@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SparseTests{})
+
+type SparseTests struct {
+	clientPairTests
+}
+
+func (t *SparseTests) TestSelects(c *C) {
+	var none *SparseConfig
+	c.Assert(none.Selects("foo.txt"), Equals, true)
+
+	config := &SparseConfig{
+		Include: []string{"docs", "*.txt"},
+		Exclude: []string{"docs/private/"},
+	}
+	c.Assert(config.Selects("foo.txt"), Equals, true)
+	c.Assert(config.Selects("foo.jpg"), Equals, false)
+	c.Assert(config.Selects(filepath.Join("docs", "foo.jpg")), Equals, true)
+	c.Assert(config.Selects(filepath.Join("docs", "private", "foo.txt")), Equals, false)
+	c.Assert(config.Selects(filepath.Join("other", "foo.jpg")), Equals, false)
+
+	config = &SparseConfig{Exclude: []string{"videos"}}
+	c.Assert(config.Selects("foo.txt"), Equals, true)
+	c.Assert(config.Selects(filepath.Join("videos", "foo.mkv")), Equals, false)
+}
+
+func (t *SparseTests) TestSetSparseConfigInvalid(c *C) {
+	err := t.mine.SetSparseConfig(&SparseConfig{Include: []string{"["}})
+	c.Assert(err, NotNil)
+	config, err := t.mine.SparseConfig()
+	c.Assert(err, IsNil)
+	c.Assert(config, IsNil)
+}
+
+func (t *SparseTests) TestRequiredObjectIDs(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+
+	err = t.mine.SetSparseConfig(&SparseConfig{Exclude: []string{"foo.txt"}})
+	c.Assert(err, IsNil)
+	ids, err := t.mine.RequiredObjectIDs(n)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{n.Revisions[0].MetadataID})
+}
+
+func (t *SparseTests) TestCheckoutSkipsUnselected(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	err := t.theirs.SetSparseConfig(&SparseConfig{Include: []string{"bar.txt"}})
+	c.Assert(err, IsNil)
+	for _, relPath := range []string{"foo.txt", "bar.txt"} {
+		err = t.transferNIB(c, t.mine, t.theirs, relPath)
+		c.Assert(err, IsNil)
+	}
+
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+	_, err = os.Stat(filepath.Join(t.theirs.Path, "foo.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	data, err := ioutil.ReadFile(filepath.Join(t.theirs.Path, "bar.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "bar")
+}
+
+func (t *SparseTests) TestAddSkipsUnselected(c *C) {
+	err := t.mine.SetSparseConfig(&SparseConfig{Exclude: []string{"skip"}})
+	c.Assert(err, IsNil)
+	err = os.Mkdir(filepath.Join(t.mine.Path, "skip"), 0700)
+	c.Assert(err, IsNil)
+	for _, relPath := range []string{"foo.txt", filepath.Join("skip", "foo.txt")} {
+		err = ioutil.WriteFile(filepath.Join(t.mine.Path, relPath), []byte("foo"), 0600)
+		c.Assert(err, IsNil)
+	}
+
+	err = t.mine.AddItem(t.mine.Path)
+	c.Assert(err, IsNil)
+	_, err = t.mine.nibForPath(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	_, err = t.mine.nibForPath(filepath.Join(t.mine.Path, "skip", "foo.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (t *SparseTests) TestStatusIgnoresUnselected(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	err := os.Remove(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(t.mine.Path, "bar.txt"), []byte("changed"), 0600)
+	c.Assert(err, IsNil)
+
+	err = t.mine.SetSparseConfig(&SparseConfig{Exclude: []string{"*.txt"}})
+	c.Assert(err, IsNil)
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.Files, HasLen, 0)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"os"
+	"time"
+
+	"github.com/hoffie/larasync/repository/tracker"
+)
+
+// racyInterval is the time after its last modification during which the
+// stat data of a file is not cached; changes within the timestamp
+// granularity of the file system would go unnoticed otherwise.
+const racyInterval = 2 * time.Second
+
+// cachedContentIDs returns the content ids recorded for the work dir file
+// at absPath if its stat data did not change since; ok is false if the
+// file has to be hashed.
+func (r *ClientRepository) cachedContentIDs(absPath string) (contentIDs []string, ok bool) {
+	stat, err := os.Lstat(absPath)
+	if err != nil || !stat.Mode().IsRegular() {
+		return nil, false
+	}
+	relPath, err := r.getRepoRelativePath(absPath)
+	if err != nil {
+		return nil, false
+	}
+	nibTracker, err := r.NIBTracker()
+	if err != nil {
+		return nil, false
+	}
+	found, err := nibTracker.Get(relPath)
+	if err != nil || found.StatInfo == nil || !found.StatInfo.Matches(stat) {
+		return nil, false
+	}
+	return found.StatInfo.ContentIDs, true
+}
+
+// cacheContentIDs records the stat data of the work dir file at absPath
+// together with its content ids. Recently modified files and untracked
+// paths are skipped.
+func (r *ClientRepository) cacheContentIDs(absPath string, contentIDs []string) error {
+	stat, err := os.Lstat(absPath)
+	if err != nil {
+		return err
+	}
+	if !stat.Mode().IsRegular() || time.Since(stat.ModTime()) < racyInterval {
+		return nil
+	}
+	relPath, err := r.getRepoRelativePath(absPath)
+	if err != nil {
+		return err
+	}
+	nibTracker, err := r.NIBTracker()
+	if err != nil {
+		return err
+	}
+	return nibTracker.SetStatInfo(relPath, tracker.NewStatInfo(stat, contentIDs))
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&StatCacheTests{})
+
+type StatCacheTests struct {
+	clientPairTests
+	path string
+	past time.Time
+}
+
+func (t *StatCacheTests) SetUpTest(c *C) {
+	t.clientPairTests.SetUpTest(c)
+	t.path = filepath.Join(t.mine.Path, "foo.txt")
+	t.past = time.Now().Add(-time.Hour)
+}
+
+// writeOld writes the given content and backdates the file so that its
+// stat data may be cached.
+func (t *StatCacheTests) writeOld(c *C, content string) {
+	err := ioutil.WriteFile(t.path, []byte(content), 0600)
+	c.Assert(err, IsNil)
+	err = os.Chtimes(t.path, t.past, t.past)
+	c.Assert(err, IsNil)
+}
+
+func (t *StatCacheTests) TestAddCaches(c *C) {
+	t.writeOld(c, "foo")
+	err := t.mine.AddItem(t.path)
+	c.Assert(err, IsNil)
+
+	ids, ok := t.mine.cachedContentIDs(t.path)
+	c.Assert(ok, Equals, true)
+	n, err := t.mine.nibForPath(t.path)
+	c.Assert(err, IsNil)
+	rev, err := n.LatestRevision()
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, rev.ContentIDs)
+}
+
+func (t *StatCacheTests) TestRecentlyModifiedNotCached(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	_, ok := t.mine.cachedContentIDs(t.path)
+	c.Assert(ok, Equals, false)
+}
+
+func (t *StatCacheTests) TestModificationDetected(c *C) {
+	t.writeOld(c, "foo")
+	err := t.mine.AddItem(t.path)
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(t.path, []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+	_, ok := t.mine.cachedContentIDs(t.path)
+	c.Assert(ok, Equals, false)
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.Files, HasLen, 1)
+	c.Assert(status.Files[0].State, Equals, FileModified)
+}
+
+// TestUnchangedStatSkipsHashing verifies that files are not hashed again
+// if their stat data did not change, even if their content did.
+func (t *StatCacheTests) TestUnchangedStatSkipsHashing(c *C) {
+	t.writeOld(c, "foo")
+	err := t.mine.AddItem(t.path)
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(t.path, []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+	err = os.Chtimes(t.path, t.past, t.past)
+	c.Assert(err, IsNil)
+
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.Files, HasLen, 1)
+	c.Assert(status.Files[0].State, Equals, FileClean)
+}
+
+func (t *StatCacheTests) TestCheckoutCaches(c *C) {
+	t.writeOld(c, "foo")
+	err := t.mine.AddItem(t.path)
+	c.Assert(err, IsNil)
+	err = t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	_, ok := t.theirs.cachedContentIDs(filepath.Join(t.theirs.Path, "foo.txt"))
+	c.Assert(ok, Equals, true)
+}
@@ -10,6 +10,14 @@ import (
 type StateConfig struct {
 	Path          string             `json:"-"`
 	DefaultServer *ServerStateConfig `json:"default_server"`
+	// ThinClient is set if only the objects of the current checkout are
+	// kept locally.
+	ThinClient *ThinClientConfig `json:"thin_client,omitempty"`
+	// Sparse restricts the items which are checked out.
+	Sparse *SparseConfig `json:"sparse,omitempty"`
+	// Cloning is set while the initial download of a cloned repository
+	// has not completed yet.
+	Cloning bool `json:"cloning,omitempty"`
 }
 
 // ServerStateConfig is a substruct which stores the state
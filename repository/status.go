@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hoffie/larasync/helpers"
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// FileState describes how a work dir item relates to the repository state.
+type FileState int
+
+const (
+	// FileClean marks items whose content matches the latest revision.
+	FileClean FileState = iota
+	// FileNew marks items which are not part of the repository yet.
+	FileNew
+	// FileModified marks items whose content differs from the latest
+	// revision.
+	FileModified
+	// FileDeleted marks items which are part of the repository but
+	// are missing in the work dir.
+	FileDeleted
+	// FileConflicting marks items which have been changed locally and
+	// on the server.
+	FileConflicting
+	// FileIgnored marks items which are part of the repository but are
+	// excluded by an ignore file; their changes are not recorded.
+	FileIgnored
+)
+
+// String returns a human readable representation of the state.
+func (s FileState) String() string {
+	switch s {
+	case FileClean:
+		return "clean"
+	case FileNew:
+		return "new"
+	case FileModified:
+		return "modified"
+	case FileDeleted:
+		return "deleted"
+	case FileConflicting:
+		return "conflicting"
+	case FileIgnored:
+		return "ignored"
+	}
+	return "unknown"
+}
+
+// FileStatus is the state of a single work dir item.
+type FileStatus struct {
+	// Path is the repository relative path of the item.
+	Path  string
+	NIBID string
+	State FileState
+}
+
+// Status describes the differences between the work dir, the repository
+// and the server state.
+type Status struct {
+	// Files contains all work dir and repository items sorted by path.
+	Files []*FileStatus
+	// UnpushedTransactions is the number of local transactions which
+	// have not been uploaded to the default server yet.
+	UnpushedTransactions int
+	// RemoteChanges is the number of items which have been changed on
+	// the server and have not been downloaded yet.
+	RemoteChanges int
+}
+
+// Status compares the work dir with the repository state.
+//
+// remoteNIBs are the NIBs which changed on the server since the last
+// download; they are used to detect conflicting items and may be nil if
+// the server state is unknown.
+func (r *ClientRepository) Status(remoteNIBs []*nib.NIB) (*Status, error) {
+	sc, err := r.StateConfig()
+	if err != nil {
+		return nil, err
+	}
+	unpushed, err := r.TransactionsFrom(sc.DefaultServer.LocalTransactionID)
+	if err != nil && err != ErrTransactionNotExists {
+		return nil, err
+	}
+	unpushedNIBIDs := map[string]bool{}
+	for _, transaction := range unpushed {
+		for _, nibID := range transaction.NIBIDs {
+			unpushedNIBIDs[nibID] = true
+		}
+	}
+
+	ignores, err := r.newIgnoreMatcher()
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.workDirStatus(ignores)
+	if err != nil {
+		return nil, err
+	}
+	missing, err := r.missingStatus(files, ignores)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, missing...)
+
+	// NIBs which are already contained locally are no remote changes;
+	// they are usually the result of our own uploads.
+	remote := map[string]*nib.NIB{}
+	for _, remoteNIB := range remoteNIBs {
+		if r.nibIsParent(remoteNIB) {
+			continue
+		}
+		selected, err := r.remoteNIBSelected(remoteNIB)
+		if err != nil {
+			return nil, err
+		}
+		if selected {
+			remote[remoteNIB.ID] = remoteNIB
+		}
+	}
+	for _, file := range files {
+		changedLocally := file.State != FileClean || unpushedNIBIDs[file.NIBID]
+		if _, ok := remote[file.NIBID]; ok && changedLocally {
+			file.State = FileConflicting
+		}
+	}
+
+	sort.Sort(fileStatusByPath(files))
+	return &Status{
+		Files:                files,
+		UnpushedTransactions: len(unpushed),
+		RemoteChanges:        len(remote),
+	}, nil
+}
+
+// nibIsParent returns true if the local NIB with the same ID already
+// contains all revisions of the passed NIB.
+func (r *ClientRepository) nibIsParent(other *nib.NIB) bool {
+	local, err := r.GetNIB(other.ID)
+	if err != nil {
+		return false
+	}
+	return other.IsParentOf(local)
+}
+
+// remoteNIBSelected returns whether the item of the passed remote NIB is
+// selected for checkout. The path is taken from the local version of the
+// NIB as the metadata of remote revisions is usually not known yet; items
+// whose path is unknown are considered to be selected.
+func (r *ClientRepository) remoteNIBSelected(remote *nib.NIB) (bool, error) {
+	n, err := r.GetNIB(remote.ID)
+	if os.IsNotExist(err) {
+		n = remote
+	} else if err != nil {
+		return false, err
+	}
+	selected, err := r.nibSelected(n)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	return selected, err
+}
+
+// workDirStatus returns the status of all files in the work dir which
+// are selected for checkout and not ignored.
+func (r *ClientRepository) workDirStatus(ignores *ignoreMatcher) ([]*FileStatus, error) {
+	managementDir := r.GetManagementDir()
+	sparse, err := r.SparseConfig()
+	if err != nil {
+		return nil, err
+	}
+	files := []*FileStatus{}
+	err = filepath.Walk(r.Path, func(absPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if absPath == managementDir {
+			return filepath.SkipDir
+		}
+		if absPath == r.Path {
+			return nil
+		}
+		relPath, err := r.getRepoRelativePath(absPath)
+		if err != nil {
+			return err
+		}
+		ignored, err := ignores.isIgnored(relPath, info.IsDir())
+		if err != nil {
+			return err
+		}
+		if ignored && info.IsDir() {
+			return filepath.SkipDir
+		}
+		// directories are always tracked, even outside of the sparse
+		// selection.
+		if ignored || (!info.IsDir() && !sparse.Selects(relPath)) {
+			return nil
+		}
+		file, err := r.fileStatus(absPath, relPath)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+// fileStatus returns the status of the existing work dir file.
+func (r *ClientRepository) fileStatus(absPath string, relPath string) (*FileStatus, error) {
+	nibID, err := r.nibIDForPath(relPath)
+	if err != nil {
+		return nil, err
+	}
+	file := &FileStatus{Path: relPath, NIBID: nibID, State: FileNew}
+
+	n, err := r.GetNIB(nibID)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rev, err := n.LatestRevision()
+	if err == nib.ErrNoRevision {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rev.IsDeletion() {
+		return file, nil
+	}
+
+	contentIDs, err := r.getFileChunkIDs(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if helpers.StringsEqual(contentIDs, rev.ContentIDs) {
+		file.State = FileClean
+	} else {
+		file.State = FileModified
+	}
+	return file, nil
+}
+
+// nibIDForPath returns the NIB ID which is tracked for the given
+// repository relative path.
+func (r *ClientRepository) nibIDForPath(relPath string) (string, error) {
+	tracker, err := r.NIBTracker()
+	if err != nil {
+		return "", err
+	}
+	found, err := tracker.Get(relPath)
+	if err == nil {
+		return found.NIBID, nil
+	}
+	// not tracked yet; NIB IDs are derived from the path.
+	return r.pathToNIBID(relPath)
+}
+
+// missingStatus returns the status of all selected repository items which
+// are missing from existing, which has to contain the status of all work
+// dir files which are not ignored. Items which still exist in the work dir
+// are reported as ignored, all others as deleted.
+func (r *ClientRepository) missingStatus(existing []*FileStatus, ignores *ignoreMatcher) ([]*FileStatus, error) {
+	sparse, err := r.SparseConfig()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, file := range existing {
+		seen[file.NIBID] = true
+	}
+
+	nibs, err := r.GetAllNibs()
+	if err != nil {
+		return nil, err
+	}
+	missing := []*FileStatus{}
+	for n := range nibs {
+		if seen[n.ID] {
+			continue
+		}
+		rev, err := n.LatestRevision()
+		if err != nil || rev.IsDeletion() {
+			continue
+		}
+		metadata, err := r.metadataByID(rev.MetadataID)
+		if err != nil {
+			return nil, err
+		}
+		relPath := metadata.RepoRelativePath
+		if !sparse.Selects(relPath) {
+			continue
+		}
+		state := FileDeleted
+		ignored, err := ignores.isIgnored(relPath, false)
+		if err != nil {
+			return nil, err
+		}
+		if ignored {
+			_, err = os.Lstat(filepath.Join(r.Path, relPath))
+			if err == nil {
+				state = FileIgnored
+			}
+		}
+		missing = append(missing, &FileStatus{
+			Path:  relPath,
+			NIBID: n.ID,
+			State: state,
+		})
+	}
+	return missing, nil
+}
+
+// fileStatusByPath implements sort.Interface for FileStatus slices.
+type fileStatusByPath []*FileStatus
+
+func (s fileStatusByPath) Len() int           { return len(s) }
+func (s fileStatusByPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
+func (s fileStatusByPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
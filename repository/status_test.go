@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+var _ = Suite(&StatusTests{})
+
+type StatusTests struct {
+	clientPairTests
+}
+
+// states returns the state of each path in the status.
+func (t *StatusTests) states(c *C, remoteNIBs []*nib.NIB) map[string]FileState {
+	status, err := t.mine.Status(remoteNIBs)
+	c.Assert(err, IsNil)
+	states := map[string]FileState{}
+	for _, file := range status.Files {
+		states[file.Path] = file.State
+	}
+	return states
+}
+
+func (t *StatusTests) TestEmpty(c *C) {
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.Files, HasLen, 0)
+	c.Assert(status.UnpushedTransactions, Equals, 0)
+}
+
+func (t *StatusTests) TestStates(c *C) {
+	t.writeAndAdd(c, t.mine, "clean.txt", []byte("clean"))
+	t.writeAndAdd(c, t.mine, "modified.txt", []byte("before"))
+	t.writeAndAdd(c, t.mine, "deleted.txt", []byte("deleted"))
+
+	err := ioutil.WriteFile(filepath.Join(t.mine.Path, "modified.txt"), []byte("after"), 0600)
+	c.Assert(err, IsNil)
+	err = os.Remove(filepath.Join(t.mine.Path, "deleted.txt"))
+	c.Assert(err, IsNil)
+	err = os.Mkdir(filepath.Join(t.mine.Path, "dir"), 0700)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(t.mine.Path, "dir", "new.txt"), []byte("new"), 0600)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.states(c, nil), DeepEquals, map[string]FileState{
+		"clean.txt":                     FileClean,
+		"modified.txt":                  FileModified,
+		"deleted.txt":                   FileDeleted,
+		"dir":                           FileNew,
+		filepath.Join("dir", "new.txt"): FileNew,
+	})
+}
+
+func (t *StatusTests) TestUnpushedTransactions(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	t.writeAndAdd(c, t.mine, "bar.txt", []byte("bar"))
+	status, err := t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.UnpushedTransactions, Equals, 2)
+
+	sc, err := t.mine.StateConfig()
+	c.Assert(err, IsNil)
+	transaction, err := t.mine.CurrentTransaction()
+	c.Assert(err, IsNil)
+	sc.DefaultServer.LocalTransactionID = transaction.ID
+	status, err = t.mine.Status(nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.UnpushedTransactions, Equals, 0)
+}
+
+func (t *StatusTests) TestConflicting(c *C) {
+	t.diverge(c, []byte("mine"), []byte("theirs"))
+	nibID, err := t.theirs.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	theirs, err := t.theirs.GetNIB(nibID)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.states(c, []*nib.NIB{theirs})["foo.txt"], Equals, FileConflicting)
+	status, err := t.mine.Status([]*nib.NIB{theirs})
+	c.Assert(err, IsNil)
+	c.Assert(status.RemoteChanges, Equals, 1)
+}
+
+// A remote NIB which is already known locally does not conflict.
+func (t *StatusTests) TestRemoteKnown(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	nibID, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	mine, err := t.mine.GetNIB(nibID)
+	c.Assert(err, IsNil)
+
+	c.Assert(t.states(c, []*nib.NIB{mine})["foo.txt"], Equals, FileClean)
+	status, err := t.mine.Status([]*nib.NIB{mine})
+	c.Assert(err, IsNil)
+	c.Assert(status.RemoteChanges, Equals, 0)
+}
+
+// Checked out files are known to the tracker.
+func (t *StatusTests) TestCheckedOutIsTracked(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("foo"))
+	err := t.transferNIB(c, t.mine, t.theirs, "foo.txt")
+	c.Assert(err, IsNil)
+	err = t.theirs.CheckoutAllPaths()
+	c.Assert(err, IsNil)
+
+	tracker, err := t.theirs.NIBTracker()
+	c.Assert(err, IsNil)
+	found, err := tracker.Get("foo.txt")
+	c.Assert(err, IsNil)
+	nibID, err := t.theirs.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	c.Assert(found.NIBID, Equals, nibID)
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/hoffie/larasync/repository/nib"
+)
+
+// ObjectFetcher retrieves objects which are not stored locally, usually
+// from the server.
+type ObjectFetcher interface {
+	GetObject(objectID string) (io.Reader, error)
+}
+
+// ThinClientConfig configures a client repository which only keeps the
+// objects required for the current checkout locally.
+type ThinClientConfig struct {
+	// CacheSize is the number of bytes of other objects which are kept
+	// in addition to the required ones.
+	CacheSize int64 `json:"cache_size"`
+}
+
+// EvictionResult describes the outcome of evicting objects.
+type EvictionResult struct {
+	// Evicted is the number of removed objects.
+	Evicted int
+	// EvictedSize is the total size of the removed objects in bytes.
+	EvictedSize int64
+	// CachedSize is the total size of the objects which are kept in
+	// addition to the required ones.
+	CachedSize int64
+}
+
+// SetObjectFetcher configures the source of objects which are not stored
+// locally. Without a fetcher, such objects cannot be read.
+func (r *ClientRepository) SetObjectFetcher(fetcher ObjectFetcher) {
+	r.objectFetcher = fetcher
+}
+
+// ThinClientConfig returns the thin client configuration or nil if all
+// objects are kept locally.
+func (r *ClientRepository) ThinClientConfig() (*ThinClientConfig, error) {
+	sc, err := r.StateConfig()
+	if err != nil {
+		return nil, err
+	}
+	return sc.ThinClient, nil
+}
+
+// SetThinClientConfig enables the thin client mode with the given
+// configuration; nil disables it.
+func (r *ClientRepository) SetThinClientConfig(config *ThinClientConfig) error {
+	if config != nil && config.CacheSize < 0 {
+		return ErrInvalidCacheSize
+	}
+	sc, err := r.StateConfig()
+	if err != nil {
+		return err
+	}
+	sc.ThinClient = config
+	return sc.Save()
+}
+
+// RequiredObjectIDs returns the IDs of the objects of the given NIB which
+// have to be available locally. Thin clients only require the metadata
+// of all revisions and the content of the latest one; items which are not
+// selected for checkout only require their metadata.
+// The metadata of the latest revision is read to determine whether the
+// item is selected, so it is fetched if necessary.
+func (r *ClientRepository) RequiredObjectIDs(n *nib.NIB) ([]string, error) {
+	selected, err := r.nibSelected(n)
+	if err != nil {
+		return nil, err
+	}
+	if !selected {
+		return metadataObjectIDs(n), nil
+	}
+	config, err := r.ThinClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return n.AllObjectIDs(), nil
+	}
+	return thinObjectIDs(n), nil
+}
+
+// thinObjectIDs returns the metadata IDs of all revisions and the content
+// IDs of the latest revision of the given NIB.
+func thinObjectIDs(n *nib.NIB) []string {
+	ids := metadataObjectIDs(n)
+	latest, err := n.LatestRevision()
+	if err == nil {
+		ids = append(ids, latest.ContentIDs...)
+	}
+	return ids
+}
+
+// getObject returns a reader for the object with the given id. Objects
+// which are not stored locally are fetched, verified and stored if a
+// fetcher has been configured.
+func (r *ClientRepository) getObject(id string) (io.ReadCloser, error) {
+	reader, err := r.objectStorage.Get(id)
+	if !os.IsNotExist(err) || r.objectFetcher == nil {
+		return reader, err
+	}
+	Log.Debug("fetching object", "objectID", id)
+	data, err := r.objectFetcher.GetObject(id)
+	if err != nil {
+		return nil, err
+	}
+	err = r.AddVerifiedObject(id, data)
+	if err != nil {
+		return nil, err
+	}
+	return r.objectStorage.Get(id)
+}
+
+// pinnedObjectIDs returns the IDs of all objects which must not be
+// evicted: the objects required by thin clients and all objects of NIBs
+// which have not been pushed to the server yet.
+func (r *ClientRepository) pinnedObjectIDs() (map[string]bool, error) {
+	transactions, err := r.transactionManager.All()
+	if err != nil {
+		return nil, err
+	}
+	nibs, err := r.storedNIBs(transactions)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[string]bool)
+	for _, n := range nibs {
+		for _, id := range thinObjectIDs(n) {
+			pinned[id] = true
+		}
+	}
+
+	sc, err := r.StateConfig()
+	if err != nil {
+		return nil, err
+	}
+	unpushed := transactions
+	if sc.DefaultServer.LocalTransactionID != 0 {
+		unpushed, err = r.transactionManager.From(sc.DefaultServer.LocalTransactionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nibs, err = r.storedNIBs(unpushed)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nibs {
+		for _, id := range n.AllObjectIDs() {
+			pinned[id] = true
+		}
+	}
+	return pinned, nil
+}
+
+// byModTime sorts os.FileInfo entries by their modification time, newest
+// first.
+type byModTime []os.FileInfo
+
+func (s byModTime) Len() int           { return len(s) }
+func (s byModTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byModTime) Less(i, j int) bool { return s[i].ModTime().After(s[j].ModTime()) }
+
+// EvictObjects removes all local objects which are neither required for
+// the current checkout nor part of changes which have not been pushed
+// yet. The most recently stored of them are kept up to the configured
+// cache size.
+func (r *ClientRepository) EvictObjects() (*EvictionResult, error) {
+	config, err := r.ThinClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, ErrNotThinClient
+	}
+	pinned, err := r.pinnedObjectIDs()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.objectStorage.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(byModTime(entries))
+
+	result := &EvictionResult{}
+	for _, entry := range entries {
+		if pinned[entry.Name()] {
+			continue
+		}
+		if result.CachedSize+entry.Size() <= config.CacheSize {
+			result.CachedSize += entry.Size()
+			continue
+		}
+		err = r.objectStorage.Delete(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		result.Evicted++
+		result.EvictedSize += entry.Size()
+	}
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ThinClientTests{})
+
+type ThinClientTests struct {
+	clientPairTests
+}
+
+// repositoryFetcher serves objects from another repository, as a server
+// would do.
+type repositoryFetcher struct {
+	r       *ClientRepository
+	fetched []string
+}
+
+func (f *repositoryFetcher) GetObject(objectID string) (io.Reader, error) {
+	f.fetched = append(f.fetched, objectID)
+	return f.r.GetObjectData(objectID)
+}
+
+// markPushed records all local transactions as pushed to the server.
+func (t *ThinClientTests) markPushed(c *C, r *ClientRepository) {
+	transaction, err := r.CurrentTransaction()
+	c.Assert(err, IsNil)
+	sc, err := r.StateConfig()
+	c.Assert(err, IsNil)
+	sc.DefaultServer.LocalTransactionID = transaction.ID
+	err = sc.Save()
+	c.Assert(err, IsNil)
+}
+
+// twoRevisions adds two revisions of foo.txt to mine and copies all
+// objects to theirs.
+func (t *ThinClientTests) twoRevisions(c *C) {
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("first"))
+	t.writeAndAdd(c, t.mine, "foo.txt", []byte("second"))
+	t.copyNIBData(c, t.mine, t.theirs, "foo.txt")
+}
+
+// revisionContentIDs returns the content ids of both revisions of foo.txt.
+func (t *ThinClientTests) revisionContentIDs(c *C, r *ClientRepository) (old, latest []string) {
+	n, err := r.nibForPath(filepath.Join(r.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(n.Revisions, HasLen, 2)
+	return n.Revisions[0].ContentIDs, n.Revisions[1].ContentIDs
+}
+
+func (t *ThinClientTests) TestRequiredObjectIDs(c *C) {
+	t.twoRevisions(c)
+	n, err := t.mine.nibForPath(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+
+	ids, err := t.mine.RequiredObjectIDs(n)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, n.AllObjectIDs())
+
+	err = t.mine.SetThinClientConfig(&ThinClientConfig{})
+	c.Assert(err, IsNil)
+	ids, err = t.mine.RequiredObjectIDs(n)
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []string{n.Revisions[0].MetadataID,
+		n.Revisions[1].MetadataID, n.Revisions[1].ContentIDs[0]})
+}
+
+func (t *ThinClientTests) TestSetThinClientConfigInvalid(c *C) {
+	err := t.mine.SetThinClientConfig(&ThinClientConfig{CacheSize: -1})
+	c.Assert(err, Equals, ErrInvalidCacheSize)
+}
+
+func (t *ThinClientTests) TestEvictNotThin(c *C) {
+	_, err := t.mine.EvictObjects()
+	c.Assert(err, Equals, ErrNotThinClient)
+}
+
+func (t *ThinClientTests) TestEvictKeepsUnpushed(c *C) {
+	t.twoRevisions(c)
+	err := t.mine.SetThinClientConfig(&ThinClientConfig{})
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.EvictObjects()
+	c.Assert(err, IsNil)
+	c.Assert(result.Evicted, Equals, 0)
+}
+
+func (t *ThinClientTests) TestEvict(c *C) {
+	t.twoRevisions(c)
+	old, latest := t.revisionContentIDs(c, t.mine)
+	t.markPushed(c, t.mine)
+	err := t.mine.SetThinClientConfig(&ThinClientConfig{})
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.EvictObjects()
+	c.Assert(err, IsNil)
+	c.Assert(result.Evicted, Equals, 1)
+	c.Assert(t.mine.HasObject(old[0]), Equals, false)
+	c.Assert(t.mine.HasObject(latest[0]), Equals, true)
+
+	history, err := t.mine.History(filepath.Join(t.mine.Path, "foo.txt"))
+	c.Assert(err, IsNil)
+	c.Assert(history[0].Size, Equals, int64(-1))
+	c.Assert(history[1].Size, Equals, int64(len("second")))
+}
+
+func (t *ThinClientTests) TestEvictCache(c *C) {
+	t.twoRevisions(c)
+	old, _ := t.revisionContentIDs(c, t.mine)
+	t.markPushed(c, t.mine)
+	err := t.mine.SetThinClientConfig(&ThinClientConfig{CacheSize: 1024})
+	c.Assert(err, IsNil)
+
+	result, err := t.mine.EvictObjects()
+	c.Assert(err, IsNil)
+	c.Assert(result.Evicted, Equals, 0)
+	c.Assert(result.CachedSize, Not(Equals), int64(0))
+	c.Assert(t.mine.HasObject(old[0]), Equals, true)
+}
+
+func (t *ThinClientTests) TestFetchOnDemand(c *C) {
+	t.twoRevisions(c)
+	old, _ := t.revisionContentIDs(c, t.mine)
+	t.markPushed(c, t.mine)
+	err := t.mine.SetThinClientConfig(&ThinClientConfig{})
+	c.Assert(err, IsNil)
+	_, err = t.mine.EvictObjects()
+	c.Assert(err, IsNil)
+
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	err = t.mine.RestoreRevision(absPath, 1, "")
+	c.Assert(err, NotNil)
+
+	fetcher := &repositoryFetcher{r: t.theirs}
+	t.mine.SetObjectFetcher(fetcher)
+	err = t.mine.RestoreRevision(absPath, 1, "")
+	c.Assert(err, IsNil)
+	c.Assert(fetcher.fetched, DeepEquals, old)
+	c.Assert(t.mine.HasObject(old[0]), Equals, true)
+}
+
+func (t *ThinClientTests) TestFetchRejectsBadObject(c *C) {
+	t.twoRevisions(c)
+	old, _ := t.revisionContentIDs(c, t.mine)
+	t.markPushed(c, t.mine)
+	err := t.mine.SetThinClientConfig(&ThinClientConfig{})
+	c.Assert(err, IsNil)
+	_, err = t.mine.EvictObjects()
+	c.Assert(err, IsNil)
+	err = t.theirs.AddObject(old[0], bytes.NewBufferString("corrupt"))
+	c.Assert(err, IsNil)
+
+	t.mine.SetObjectFetcher(&repositoryFetcher{r: t.theirs})
+	absPath := filepath.Join(t.mine.Path, "foo.txt")
+	err = t.mine.RestoreRevision(absPath, 1, "")
+	c.Assert(err, Equals, ErrObjectVerification)
+	c.Assert(t.mine.HasObject(old[0]), Equals, false)
+}
+
+func (t *ThinClientTests) TestAddNIBContentThin(c *C) {
+	t.twoRevisions(c)
+	old, _ := t.revisionContentIDs(c, t.mine)
+	err := t.theirs.SetThinClientConfig(&ThinClientConfig{})
+	c.Assert(err, IsNil)
+	err = t.theirs.objectStorage.Delete(old[0])
+	c.Assert(err, IsNil)
+
+	data, err := t.mine.nibStore.GetBytes(t.nibID(c))
+	c.Assert(err, IsNil)
+	err = t.theirs.AddNIBContent(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+
+	err = t.theirs.SetThinClientConfig(nil)
+	c.Assert(err, IsNil)
+	err = t.theirs.AddNIBContent(bytes.NewReader(data))
+	c.Assert(IsNIBContentMissing(err), Equals, true)
+}
+
+func (t *ThinClientTests) nibID(c *C) string {
+	id, err := t.mine.pathToNIBID("foo.txt")
+	c.Assert(err, IsNil)
+	return id
+}
@@ -16,6 +16,12 @@ type NIBLookup struct {
 	ID    int64
 	NIBID string `sql:"size:256;unique" gorm:"column:nib_id"`
 	Path  string `sql:"size:4096;unique"`
+	// The stat data and content ids of the file at the time it has been
+	// hashed last; ModTime is 0 if they are unknown.
+	Size       int64
+	ModTime    int64
+	Inode      int64
+	ContentIDs string `sql:"type:text" gorm:"column:content_ids"`
 }
 
 // TableName returns the name of the SQLite NIB table.
@@ -36,6 +42,8 @@ func NewDatabaseNIBTracker(dbLocation string, repositoryPath string) (NIBTracker
 	nibTracker.db = &db
 	if err == nil && os.IsNotExist(statErr) {
 		err = nibTracker.createDb()
+	} else if err == nil {
+		err = nibTracker.migrateDb()
 	}
 
 	return nibTracker, err
@@ -55,6 +63,13 @@ func (d *DatabaseNIBTracker) createDb() error {
 	return db.Error
 }
 
+// migrateDb adds the columns which are missing in databases created by
+// older versions.
+func (d *DatabaseNIBTracker) migrateDb() error {
+	db := d.db.AutoMigrate(&NIBLookup{})
+	return db.Error
+}
+
 // Add registers the given nibID for the given path.
 func (d *DatabaseNIBTracker) Add(path string, nibID string) error {
 	if len(path) > MaxPathSize {
@@ -87,11 +102,20 @@ func (d *DatabaseNIBTracker) whereFor(path string, db *gorm.DB) *gorm.DB {
 
 // lookupToNIB converts the lookup nib to a search response.
 func (d *DatabaseNIBTracker) lookupToNIB(nibLookup *NIBLookup) *NIBSearchResponse {
-	return &NIBSearchResponse{
+	resp := &NIBSearchResponse{
 		NIBID:          nibLookup.NIBID,
 		Path:           nibLookup.Path,
 		repositoryPath: d.repositoryPath,
 	}
+	if nibLookup.ModTime != 0 {
+		resp.StatInfo = &StatInfo{
+			Size:       nibLookup.Size,
+			ModTime:    nibLookup.ModTime,
+			Inode:      uint64(nibLookup.Inode),
+			ContentIDs: splitContentIDs(nibLookup.ContentIDs),
+		}
+	}
+	return resp
 }
 
 // get returns the database object for the given path.
@@ -132,6 +156,32 @@ func (d *DatabaseNIBTracker) SearchPrefix(prefix string) ([]*NIBSearchResponse,
 	return searchResponse, db.Error
 }
 
+// List returns all tracked paths.
+func (d *DatabaseNIBTracker) List() ([]*NIBSearchResponse, error) {
+	var resp []NIBLookup
+	db := d.db.Find(&resp)
+
+	searchResponse := []*NIBSearchResponse{}
+	for _, item := range resp {
+		searchResponse = append(searchResponse, d.lookupToNIB(&item))
+	}
+
+	return searchResponse, db.Error
+}
+
+// SetStatInfo records the given stat data for the given path. Untracked
+// paths are ignored.
+func (d *DatabaseNIBTracker) SetStatInfo(path string, info *StatInfo) error {
+	db := d.whereFor(path, d.db.Model(&NIBLookup{})).UpdateColumns(
+		map[string]interface{}{
+			"size":        info.Size,
+			"mod_time":    info.ModTime,
+			"inode":       int64(info.Inode),
+			"content_ids": joinContentIDs(info.ContentIDs),
+		})
+	return db.Error
+}
+
 // Remove removes the given path from being tracked.
 func (d *DatabaseNIBTracker) Remove(path string) error {
 	tx := d.db.Begin()
@@ -123,6 +123,29 @@ func (t *DatabaseNIBTrackerTests) TestSearchPrefix(c *C) {
 	}
 }
 
+func (t *DatabaseNIBTrackerTests) TestList(c *C) {
+	tracker := t.getVerifiedTracker(c)
+	c.Assert(tracker.Add("test", "123"), IsNil)
+	c.Assert(tracker.Add("test2/sub", "456"), IsNil)
+
+	resp, err := tracker.List()
+	c.Assert(err, IsNil)
+	c.Assert(len(resp), Equals, 2)
+	paths := map[string]string{}
+	for _, entry := range resp {
+		paths[entry.Path] = entry.NIBID
+	}
+	c.Assert(paths["test"], Equals, "123")
+	c.Assert(paths["test2/sub"], Equals, "456")
+}
+
+func (t *DatabaseNIBTrackerTests) TestListEmpty(c *C) {
+	tracker := t.getVerifiedTracker(c)
+	resp, err := tracker.List()
+	c.Assert(err, IsNil)
+	c.Assert(len(resp), Equals, 0)
+}
+
 func (t *DatabaseNIBTrackerTests) TestRemove(c *C) {
 	tracker := t.getVerifiedTracker(c)
 	add := func(path string, nibID string) {
@@ -143,3 +166,49 @@ func (t *DatabaseNIBTrackerTests) TestRemoveNotExisting(c *C) {
 	err := tracker.Remove("/test")
 	c.Assert(err, NotNil)
 }
+
+func (t *DatabaseNIBTrackerTests) TestSetStatInfo(c *C) {
+	tracker := t.getVerifiedTracker(c)
+	err := tracker.Add("/test", "123")
+	c.Assert(err, IsNil)
+	resp, err := tracker.Get("/test")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatInfo, IsNil)
+
+	info := &StatInfo{
+		Size:       3,
+		ModTime:    1234567890123456789,
+		Inode:      42,
+		ContentIDs: []string{"abc", "def"},
+	}
+	err = tracker.SetStatInfo("/test", info)
+	c.Assert(err, IsNil)
+	resp, err = tracker.Get("/test")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatInfo, DeepEquals, info)
+
+	err = tracker.Add("/test", "456")
+	c.Assert(err, IsNil)
+	resp, err = tracker.Get("/test")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatInfo, DeepEquals, info)
+}
+
+func (t *DatabaseNIBTrackerTests) TestSetStatInfoUntracked(c *C) {
+	tracker := t.getVerifiedTracker(c)
+	err := tracker.SetStatInfo("/test", &StatInfo{ModTime: 1})
+	c.Assert(err, IsNil)
+	_, err = tracker.Get("/test")
+	c.Assert(err, NotNil)
+}
+
+func (t *DatabaseNIBTrackerTests) TestReopen(c *C) {
+	tracker := t.getVerifiedTracker(c)
+	err := tracker.Add("/test", "123")
+	c.Assert(err, IsNil)
+
+	tracker = t.getVerifiedTracker(c)
+	resp, err := tracker.Get("/test")
+	c.Assert(err, IsNil)
+	c.Assert(resp.NIBID, Equals, "123")
+}
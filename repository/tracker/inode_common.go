@@ -0,0 +1,17 @@
+// +build !windows
+
+package tracker
+
+import (
+	"os"
+	"syscall"
+)
+
+// inode returns the inode number of the file described by info.
+func inode(info os.FileInfo) uint64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint64(stat.Ino)
+}
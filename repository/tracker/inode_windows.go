@@ -0,0 +1,11 @@
+package tracker
+
+import (
+	"os"
+)
+
+// inode returns 0 as the file index is not part of the stat data on
+// windows.
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
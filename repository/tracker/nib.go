@@ -12,4 +12,9 @@ type NIBTracker interface {
 	// SearchPrefix returns all nibIDs with the given path.
 	// The map being returned has the paths
 	SearchPrefix(prefix string) ([]*NIBSearchResponse, error)
+	// List returns all tracked paths.
+	List() ([]*NIBSearchResponse, error)
+	// SetStatInfo records the given stat data for the given path.
+	// Untracked paths are ignored.
+	SetStatInfo(path string, info *StatInfo) error
 }
@@ -18,8 +18,11 @@ func NewNIBSearchResponse(NIBID string, path string, repositoryPath string) *NIB
 // NIBSearchResponse is being returned by NIBTracker implementations
 // to indicate that a NIB for the path exists.
 type NIBSearchResponse struct {
-	NIBID          string
-	Path           string
+	NIBID string
+	Path  string
+	// StatInfo is the stat data recorded for the path or nil if there
+	// is none.
+	StatInfo       *StatInfo
 	repositoryPath string
 }
 
@@ -0,0 +1,54 @@
+package tracker
+
+import (
+	"os"
+	"strings"
+)
+
+// StatInfo describes the state of a work dir file at the time its content
+// ids were determined. It allows to skip hashing files which did not
+// change since.
+type StatInfo struct {
+	Size int64
+	// ModTime is the modification time in nanoseconds since the Unix
+	// epoch.
+	ModTime int64
+	// Inode is 0 on platforms without inode numbers.
+	Inode      uint64
+	ContentIDs []string
+}
+
+// NewStatInfo returns the StatInfo for a file with the given stat data
+// and content ids.
+func NewStatInfo(info os.FileInfo, contentIDs []string) *StatInfo {
+	return &StatInfo{
+		Size:       info.Size(),
+		ModTime:    info.ModTime().UnixNano(),
+		Inode:      inode(info),
+		ContentIDs: contentIDs,
+	}
+}
+
+// Matches returns whether the given stat data is equal to the recorded
+// one, i.e. whether the file is considered unchanged.
+func (s *StatInfo) Matches(info os.FileInfo) bool {
+	return info.Mode().IsRegular() &&
+		s.Size == info.Size() &&
+		s.ModTime == info.ModTime().UnixNano() &&
+		s.Inode == inode(info)
+}
+
+// joinContentIDs returns the representation of the given content ids in
+// the database.
+func joinContentIDs(contentIDs []string) string {
+	return strings.Join(contentIDs, ",")
+}
+
+// splitContentIDs returns the content ids which are represented by the
+// given database value.
+func splitContentIDs(joined string) []string {
+	if joined == "" {
+		return []string{}
+	}
+	return strings.Split(joined, ",")
+}
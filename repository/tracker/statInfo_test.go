@@ -0,0 +1,67 @@
+package tracker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&StatInfoTests{})
+
+type StatInfoTests struct {
+	path string
+}
+
+func (t *StatInfoTests) SetUpTest(c *C) {
+	t.path = filepath.Join(c.MkDir(), "foo.txt")
+	err := ioutil.WriteFile(t.path, []byte("foo"), 0600)
+	c.Assert(err, IsNil)
+}
+
+func (t *StatInfoTests) stat(c *C) os.FileInfo {
+	stat, err := os.Lstat(t.path)
+	c.Assert(err, IsNil)
+	return stat
+}
+
+func (t *StatInfoTests) TestMatches(c *C) {
+	info := NewStatInfo(t.stat(c), []string{"abc"})
+	c.Assert(info.Matches(t.stat(c)), Equals, true)
+}
+
+func (t *StatInfoTests) TestModTimeChanged(c *C) {
+	info := NewStatInfo(t.stat(c), []string{"abc"})
+	past := time.Now().Add(-time.Hour)
+	err := os.Chtimes(t.path, past, past)
+	c.Assert(err, IsNil)
+	c.Assert(info.Matches(t.stat(c)), Equals, false)
+}
+
+func (t *StatInfoTests) TestReplaced(c *C) {
+	stat := t.stat(c)
+	if inode(stat) == 0 {
+		c.Skip("no inode numbers on this platform")
+	}
+	info := NewStatInfo(stat, []string{"abc"})
+	replacement := t.path + ".new"
+	err := ioutil.WriteFile(replacement, []byte("bar"), 0600)
+	c.Assert(err, IsNil)
+	err = os.Chtimes(replacement, stat.ModTime(), stat.ModTime())
+	c.Assert(err, IsNil)
+	err = os.Rename(replacement, t.path)
+	c.Assert(err, IsNil)
+	c.Assert(info.Matches(t.stat(c)), Equals, false)
+}
+
+func (t *StatInfoTests) TestDirectory(c *C) {
+	info := NewStatInfo(t.stat(c), []string{"abc"})
+	dirStat, err := os.Lstat(filepath.Dir(t.path))
+	c.Assert(err, IsNil)
+	info.Size = dirStat.Size()
+	info.ModTime = dirStat.ModTime().UnixNano()
+	info.Inode = inode(dirStat)
+	c.Assert(info.Matches(dirStat), Equals, false)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"sync"
+	"time"
 
 	"github.com/hoffie/larasync/helpers/lock"
 	"github.com/hoffie/larasync/repository/content"
@@ -21,6 +22,8 @@ func reverseTransactionSlice(slice []*Transaction) []*Transaction {
 type TransactionManager struct {
 	manager *TransactionContainerManager
 	lock    sync.Locker
+	// lockingPath identifies the repository for transaction waiters.
+	lockingPath string
 }
 
 // newTransactionManager initializes a new transaction manager
@@ -33,6 +36,7 @@ func newTransactionManager(storage content.Storage, lockingPath string) *Transac
 			lockingPath,
 			"transaction_manager",
 		),
+		lockingPath: lockingPath,
 	}
 }
 
@@ -102,9 +106,38 @@ func (tm *TransactionManager) Add(transaction *Transaction) error {
 		return manager.Set(transactionContainer)
 	}()
 	locker.Unlock()
+	if err == nil {
+		notifyTransactionWaiters(tm.lockingPath)
+	}
 	return err
 }
 
+// WaitForTransactionAfter blocks until a transaction newer than the
+// given transaction ID has been added or until the timeout passes.
+// Returns true if a newer transaction exists.
+func (tm *TransactionManager) WaitForTransactionAfter(transactionID int64, timeout time.Duration) (bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	notifier := acquireTransactionNotifier(tm.lockingPath)
+	defer releaseTransactionNotifier(tm.lockingPath, notifier)
+	for {
+		// fetch the channel before checking to not miss a notification
+		changed := notifier.wait()
+		currentID, err := tm.CurrentTransactionID()
+		if err != nil && err != ErrTransactionNotExists {
+			return false, err
+		}
+		if currentID > transactionID {
+			return true, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false, nil
+		}
+	}
+}
+
 // Get returns the transaction with the given UUID.
 func (tm *TransactionManager) Get(transactionID int64) (*Transaction, error) {
 	manager := tm.manager
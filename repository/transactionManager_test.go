@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/hoffie/larasync/repository/content"
 
 	. "gopkg.in/check.v1"
@@ -125,3 +127,41 @@ func (t *TransactionManagerTest) TestExistsNegative(c *C) {
 	queryID := int64(transactionsInContainer*2 + 1)
 	c.Assert(t.tm.Exists(queryID), Equals, false)
 }
+
+func (t *TransactionManagerTest) TestWaitForTransactionAfterExisting(c *C) {
+	err := t.tm.Add(&Transaction{NIBIDs: []string{"a"}})
+	c.Assert(err, IsNil)
+	found, err := t.tm.WaitForTransactionAfter(0, time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+}
+
+func (t *TransactionManagerTest) TestWaitForTransactionAfterTimeout(c *C) {
+	found, err := t.tm.WaitForTransactionAfter(0, 10*time.Millisecond)
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, false)
+}
+
+func (t *TransactionManagerTest) TestWaitForTransactionAfterNotified(c *C) {
+	err := t.tm.Add(&Transaction{NIBIDs: []string{"a"}})
+	c.Assert(err, IsNil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		// a separately opened manager has to wake up the waiter as well
+		other := newTransactionManager(content.NewFileStorage(t.dir), t.dir)
+		other.Add(&Transaction{NIBIDs: []string{"b"}})
+	}()
+	found, err := t.tm.WaitForTransactionAfter(1, time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+}
+
+func (t *TransactionManagerTest) TestWaitForTransactionAfterReleasesNotifier(c *C) {
+	_, err := t.tm.WaitForTransactionAfter(0, 10*time.Millisecond)
+	c.Assert(err, IsNil)
+	transactionNotifiersLock.Lock()
+	_, exists := transactionNotifiers[t.dir]
+	transactionNotifiersLock.Unlock()
+	c.Assert(exists, Equals, false)
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"sync"
+)
+
+var (
+	transactionNotifiersLock sync.Mutex
+	transactionNotifiers     = map[string]*transactionNotifier{}
+)
+
+// transactionNotifier wakes up everyone waiting for new transactions in
+// a repository. Repositories are opened for each request separately,
+// which is why notifiers are shared per path for the whole process.
+// A notifier only exists as long as someone is waiting.
+type transactionNotifier struct {
+	lock    sync.Mutex
+	changed chan struct{}
+	// waiters is guarded by transactionNotifiersLock.
+	waiters int
+}
+
+// acquireTransactionNotifier returns the notifier for the given path and
+// registers the caller as a waiter. Every call has to be followed by a
+// call of releaseTransactionNotifier.
+func acquireTransactionNotifier(path string) *transactionNotifier {
+	transactionNotifiersLock.Lock()
+	defer transactionNotifiersLock.Unlock()
+	notifier, ok := transactionNotifiers[path]
+	if !ok {
+		notifier = &transactionNotifier{changed: make(chan struct{})}
+		transactionNotifiers[path] = notifier
+	}
+	notifier.waiters++
+	return notifier
+}
+
+// releaseTransactionNotifier unregisters a waiter of the given notifier;
+// the notifier is removed when its last waiter leaves.
+func releaseTransactionNotifier(path string, notifier *transactionNotifier) {
+	transactionNotifiersLock.Lock()
+	defer transactionNotifiersLock.Unlock()
+	notifier.waiters--
+	if notifier.waiters == 0 && transactionNotifiers[path] == notifier {
+		delete(transactionNotifiers, path)
+	}
+}
+
+// notifyTransactionWaiters wakes up everyone waiting for new transactions
+// in the repository at the given path.
+func notifyTransactionWaiters(path string) {
+	transactionNotifiersLock.Lock()
+	notifier, ok := transactionNotifiers[path]
+	transactionNotifiersLock.Unlock()
+	if ok {
+		notifier.notify()
+	}
+}
+
+// wait returns a channel which is closed on the next notify call.
+func (n *transactionNotifier) wait() <-chan struct{} {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.changed
+}
+
+// notify wakes up all current waiters.
+func (n *transactionNotifier) notify() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	close(n.changed)
+	n.changed = make(chan struct{})
+}